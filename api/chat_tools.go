@@ -0,0 +1,303 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// maxToolRounds 单次对话中模型最多连续发起工具调用的轮数
+	maxToolRounds = 5
+	// maxToolResultRunes 回传给模型的工具结果最大字符数
+	maxToolResultRunes = 16000
+	// builtinWebSearch 内置联网搜索函数名
+	builtinWebSearch = "web_search"
+	// builtinFetch 内置网页抓取函数名
+	builtinFetch = "fetch_url"
+)
+
+// invalidFunctionChars 函数名只允许字母、数字、下划线和短横线
+var invalidFunctionChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// ToolCallEvent 模型发起工具调用时推送的SSE事件
+type ToolCallEvent struct {
+	ID        string `json:"id"`               // 工具调用ID
+	Name      string `json:"name"`             // 函数名
+	Server    string `json:"server,omitempty"` // 处理调用的MCP服务器，内置工具为空
+	Tool      string `json:"tool"`             // 工具名称
+	Arguments string `json:"arguments"`        // 调用参数（JSON字符串）
+}
+
+// ToolResultEvent 工具执行完成时推送的SSE事件
+type ToolResultEvent struct {
+	ID      string `json:"id"`                 // 工具调用ID
+	Name    string `json:"name"`               // 函数名
+	Content string `json:"content"`            // 回传给模型的结果
+	IsError bool   `json:"is_error,omitempty"` // 是否执行失败
+}
+
+// chatTool 函数名对应的工具
+type chatTool struct {
+	Server string // MCP服务器名称，内置工具为空
+	Tool   string // 工具名称
+}
+
+// chatToolSet 对话中向模型声明的工具集合
+type chatToolSet struct {
+	Tools  []openai.Tool
+	routes map[string]chatTool
+}
+
+// add 添加一个工具，函数名冲突时自动追加序号
+func (s *chatToolSet) add(tool chatTool, description string, parameters json.RawMessage) {
+	base := invalidFunctionChars.ReplaceAllString(tool.Tool, "_")
+	if strings.Trim(base, "_-") == "" {
+		base = "tool"
+	}
+	if len(base) > 60 {
+		base = base[:60]
+	}
+	name := base
+	for i := 2; ; i++ {
+		if _, exists := s.routes[name]; !exists {
+			break
+		}
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+
+	if len(parameters) == 0 || string(parameters) == "null" {
+		parameters = json.RawMessage(`{"type":"object","properties":{}}`)
+	}
+	s.routes[name] = tool
+	s.Tools = append(s.Tools, openai.Tool{
+		Type: openai.ToolTypeFunction,
+		Function: &openai.FunctionDefinition{
+			Name:        name,
+			Description: description,
+			Parameters:  parameters,
+		},
+	})
+}
+
+// buildChatTools 根据tools参数构造向模型声明的工具。spec为all时包含内置工具和全部已启用的服务器，
+// 否则为逗号分隔的服务器名称，web-search和fetch表示内置工具。
+// 对话中无法等待人工审批，MCP服务器上只声明AutoApprove列表中的工具
+func (h *Handlers) buildChatTools(ctx context.Context, spec string) (*chatToolSet, error) {
+	set := &chatToolSet{routes: make(map[string]chatTool)}
+
+	var names []string
+	if spec == "all" {
+		names = append([]string{"web-search", "fetch"}, h.MCPManager.Names()...)
+	} else {
+		for _, name := range strings.Split(spec, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+
+	var serverNames []string
+	for _, name := range names {
+		switch name {
+		case "web-search":
+			set.add(chatTool{Tool: builtinWebSearch}, "联网搜索，返回与查询相关的网页标题、链接和摘要",
+				json.RawMessage(`{"type":"object","properties":{"query":{"type":"string","description":"搜索关键词"},"max_results":{"type":"integer","description":"返回结果数量"}},"required":["query"]}`))
+		case "fetch":
+			set.add(chatTool{Tool: builtinFetch}, "抓取网页并返回标题和正文",
+				json.RawMessage(`{"type":"object","properties":{"url":{"type":"string","description":"网页地址"},"max_length":{"type":"integer","description":"正文最大长度"}},"required":["url"]}`))
+		default:
+			server, ok := h.MCPManager.Server(name)
+			if !ok {
+				return nil, fmt.Errorf("不支持的MCP服务器: %s", name)
+			}
+			if server.Disabled {
+				if spec == "all" {
+					continue
+				}
+				return nil, fmt.Errorf("MCP服务器已禁用: %s", name)
+			}
+			if len(server.AutoApprove) == 0 {
+				log.Printf("MCP服务器%s没有自动批准的工具，对话中不使用该服务器", name)
+				continue
+			}
+			serverNames = append(serverNames, name)
+		}
+	}
+
+	// 并发获取各服务器的工具列表，获取失败的服务器跳过
+	results := make([][]models.MCPTool, len(serverNames))
+	var wg sync.WaitGroup
+	for i, name := range serverNames {
+		wg.Add(1)
+		go func(i int, name string) {
+			defer wg.Done()
+			discoverCtx, cancel := context.WithTimeout(ctx, mcpDiscoveryTimeout)
+			defer cancel()
+			tools, err := h.MCPManager.Tools(discoverCtx, name)
+			if err != nil {
+				log.Printf("获取MCP服务器%s的工具列表失败，对话中不使用该服务器: %v", name, err)
+				return
+			}
+			results[i] = toModelTools(tools)
+		}(i, name)
+	}
+	wg.Wait()
+
+	for i, name := range serverNames {
+		server, _ := h.MCPManager.Server(name)
+		for _, tool := range results[i] {
+			if !server.IsAutoApproved(tool.Name) {
+				continue
+			}
+			set.add(chatTool{Server: name, Tool: tool.Name}, tool.Description, tool.InputSchema)
+		}
+	}
+	return set, nil
+}
+
+// describe 构造工具调用事件
+func (s *chatToolSet) describe(call openai.ToolCall) ToolCallEvent {
+	route := s.routes[call.Function.Name]
+	return ToolCallEvent{
+		ID:        call.ID,
+		Name:      call.Function.Name,
+		Server:    route.Server,
+		Tool:      route.Tool,
+		Arguments: call.Function.Arguments,
+	}
+}
+
+// executeChatTool 执行模型发起的工具调用，返回回传给模型的结果。
+// 声明工具后服务器配置可能被修改，执行前再次检查工具是否仍在AutoApprove列表中
+func (h *Handlers) executeChatTool(ctx context.Context, set *chatToolSet, call openai.ToolCall) ToolResultEvent {
+	event := ToolResultEvent{ID: call.ID, Name: call.Function.Name}
+	fail := func(format string, args ...interface{}) ToolResultEvent {
+		event.Content = fmt.Sprintf(format, args...)
+		event.IsError = true
+		return event
+	}
+
+	route, ok := set.routes[call.Function.Name]
+	if !ok {
+		return fail("未知的工具: %s", call.Function.Name)
+	}
+
+	arguments := map[string]interface{}{}
+	if strings.TrimSpace(call.Function.Arguments) != "" {
+		if err := json.Unmarshal([]byte(call.Function.Arguments), &arguments); err != nil {
+			return fail("工具参数不是有效的JSON: %v", err)
+		}
+	}
+
+	switch {
+	case route.Server == "" && route.Tool == builtinWebSearch:
+		query, _ := arguments["query"].(string)
+		if query == "" {
+			return fail("web_search需要query参数")
+		}
+		result, err := h.executeWebSearchTool(query, arguments)
+		if err != nil {
+			return fail("%v", err)
+		}
+		event.Content = marshalToolResult(result)
+
+	case route.Server == "" && route.Tool == builtinFetch:
+		url, _ := arguments["url"].(string)
+		if url == "" {
+			return fail("fetch_url需要url参数")
+		}
+		maxLength := 5000
+		if value, ok := arguments["max_length"].(float64); ok && value > 0 {
+			maxLength = int(value)
+		}
+		page, err := h.Fetcher.Fetch(ctx, url, maxLength)
+		if err != nil {
+			return fail("抓取网页失败: %v", err)
+		}
+		title, content := "", page.Text
+		if page.Article != nil {
+			title = page.Article.Title
+			if page.Article.Content != "" {
+				content = page.Article.Content
+			}
+		}
+		event.Content = fmt.Sprintf("标题: %s\n链接: %s\n\n%s", title, page.FinalURL, content)
+
+	default:
+		server, _ := h.MCPManager.Server(route.Server)
+		if !server.IsAutoApproved(route.Tool) {
+			return fail("工具%s需要人工审批，对话中不能调用。请告知用户该工具未加入MCP服务器%s的autoApprove列表，可以通过/mcp接口调用并审批", route.Tool, route.Server)
+		}
+
+		result, err := h.MCPManager.CallTool(ctx, route.Server, route.Tool, arguments)
+		if err != nil {
+			return fail("调用工具失败: %v", err)
+		}
+		event.Content = result.Text()
+		event.IsError = result.IsError
+	}
+
+	event.Content = truncateToolResult(event.Content)
+	return event
+}
+
+// marshalToolResult 将工具结果序列化为回传给模型的文本
+func marshalToolResult(result interface{}) string {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Sprintf("%v", result)
+	}
+	return string(data)
+}
+
+// truncateToolResult 截断过长的工具结果，避免超出模型上下文
+func truncateToolResult(content string) string {
+	runes := []rune(content)
+	if len(runes) <= maxToolResultRunes {
+		return content
+	}
+	return string(runes[:maxToolResultRunes]) + "\n...(结果过长，已截断)"
+}
+
+// mergeToolCallDeltas 将流式返回的工具调用增量按index合并为完整的工具调用
+func mergeToolCallDeltas(calls []openai.ToolCall, deltas []openai.ToolCall) []openai.ToolCall {
+	for _, delta := range deltas {
+		index := len(calls)
+		if delta.Index != nil {
+			index = *delta.Index
+		} else if delta.ID != "" {
+			// 部分服务商不返回index，按ID匹配
+			for i := range calls {
+				if calls[i].ID == delta.ID {
+					index = i
+					break
+				}
+			}
+		} else if len(calls) > 0 {
+			index = len(calls) - 1
+		}
+
+		for len(calls) <= index {
+			calls = append(calls, openai.ToolCall{Type: openai.ToolTypeFunction})
+		}
+		call := &calls[index]
+		if delta.ID != "" {
+			call.ID = delta.ID
+		}
+		if delta.Type != "" {
+			call.Type = delta.Type
+		}
+		call.Function.Name += delta.Function.Name
+		call.Function.Arguments += delta.Function.Arguments
+	}
+	return calls
+}
@@ -0,0 +1,219 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+	"github.com/aimmetal-tech/wistrans-backend/store"
+	"github.com/aimmetal-tech/wistrans-backend/translate"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ListGlossaries 获取全部术语表及其术语数
+func (h *Handlers) ListGlossaries(c *gin.Context) {
+	glossaries, err := h.Glossaries.ListGlossaries()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取术语表列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"glossaries": glossaries,
+	})
+}
+
+// DeleteGlossary 删除术语表中的全部术语
+func (h *Handlers) DeleteGlossary(c *gin.Context) {
+	glossary := c.Param("glossary")
+
+	deleted, err := h.Glossaries.DeleteGlossary(glossary)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "删除术语表失败: " + err.Error(),
+		})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "术语表不存在: " + glossary,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"glossary": glossary,
+		"deleted":  deleted,
+	})
+}
+
+// ListGlossaryTerms 获取术语表中的术语，支持source_lang、target_lang参数按语言过滤
+func (h *Handlers) ListGlossaryTerms(c *gin.Context) {
+	terms, err := h.Glossaries.ListTerms(c.Param("glossary"), normalizeLang(c.Query("source_lang")), normalizeLang(c.Query("target_lang")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取术语列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"glossary": c.Param("glossary"),
+		"terms":    terms,
+	})
+}
+
+// GetGlossaryTerm 获取术语详情
+func (h *Handlers) GetGlossaryTerm(c *gin.Context) {
+	term, err := h.Glossaries.GetTerm(c.Param("glossary"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "术语不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// CreateGlossaryTerm 向术语表中新增术语，术语表不存在时自动创建
+func (h *Handlers) CreateGlossaryTerm(c *gin.Context) {
+	var term models.GlossaryTerm
+	if err := c.ShouldBindJSON(&term); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	term.ID = uuid.New().String()
+	term.Glossary = c.Param("glossary")
+	term.CreatedAt = time.Now()
+	term.UpdatedAt = term.CreatedAt
+	term, err := normalizeGlossaryTerm(term)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.Glossaries.CreateTerm(term); err != nil {
+		c.JSON(glossaryErrorStatus(err), gin.H{
+			"error": "保存术语失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, term)
+}
+
+// UpdateGlossaryTerm 部分更新术语
+func (h *Handlers) UpdateGlossaryTerm(c *gin.Context) {
+	var patch models.GlossaryTermPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	current, err := h.Glossaries.GetTerm(c.Param("glossary"), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "术语不存在",
+		})
+		return
+	}
+
+	term := patch.Apply(*current)
+	term.UpdatedAt = time.Now()
+	term, err = normalizeGlossaryTerm(term)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.Glossaries.UpdateTerm(term)
+	if err != nil {
+		c.JSON(glossaryErrorStatus(err), gin.H{
+			"error": "保存术语失败: " + err.Error(),
+		})
+		return
+	}
+	if !updated {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "术语不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, term)
+}
+
+// DeleteGlossaryTerm 删除术语
+func (h *Handlers) DeleteGlossaryTerm(c *gin.Context) {
+	id := c.Param("id")
+
+	deleted, err := h.Glossaries.DeleteTerm(c.Param("glossary"), id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "删除术语失败: " + err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "术语不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id": id,
+	})
+}
+
+// normalizeGlossaryTerm 去掉术语首尾的空白，统一语言代码，并校验必填字段
+func normalizeGlossaryTerm(term models.GlossaryTerm) (models.GlossaryTerm, error) {
+	term.Glossary = strings.TrimSpace(term.Glossary)
+	term.SourceLang = normalizeLang(term.SourceLang)
+	term.TargetLang = normalizeLang(term.TargetLang)
+	term.SourceTerm = strings.TrimSpace(term.SourceTerm)
+	term.TargetTerm = strings.TrimSpace(term.TargetTerm)
+	term.Note = strings.TrimSpace(term.Note)
+
+	switch {
+	case term.Glossary == "":
+		return term, fmt.Errorf("术语表名称不能为空")
+	case term.TargetLang == "":
+		return term, fmt.Errorf("target_lang不能为空")
+	case term.SourceTerm == "" || term.TargetTerm == "":
+		return term, fmt.Errorf("source_term和target_term不能为空")
+	case term.SourceLang != "" && term.SourceLang == term.TargetLang:
+		return term, fmt.Errorf("source_lang和target_lang不能相同")
+	}
+	return term, nil
+}
+
+// normalizeLang 将语言名称或代码统一为翻译时查询术语使用的语言代码
+func normalizeLang(lang string) string {
+	return translate.CanonicalLang(lang)
+}
+
+// glossaryErrorStatus 源术语重复时返回409，其他错误返回500
+func glossaryErrorStatus(err error) int {
+	if errors.Is(err, store.ErrDuplicateTerm) {
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
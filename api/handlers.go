@@ -7,11 +7,16 @@ import (
 	"io"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/aimmetal-tech/wistrans-backend/fetcher"
 	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/mcp"
 	"github.com/aimmetal-tech/wistrans-backend/models"
 	"github.com/aimmetal-tech/wistrans-backend/store"
+	"github.com/aimmetal-tech/wistrans-backend/translate"
+	"github.com/aimmetal-tech/wistrans-backend/websearch"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -20,24 +25,66 @@ import (
 
 // Handlers API处理函数集合
 type Handlers struct {
-	Store     *store.SessionStore
-	LLMClient *llm.Client
+	Store      *store.SessionStore
+	Approvals  *store.ApprovalStore
+	MCPServers *store.MCPServerStore
+	Memory     *store.TranslationMemoryStore
+	Glossaries *store.GlossaryStore
+	Quality    *store.QualityStore
+	Jobs       *store.TranslationJobStore
+	LLMClient  *llm.Client
+	Fetcher    *fetcher.Fetcher
+	MCPManager *mcp.Manager
+	Translator *translate.Translator
+	JobQueue   *translate.JobQueue
+
+	serversMu     sync.Mutex      // 串行化MCP服务器配置的修改
+	customMu      sync.RWMutex    // 保护customServers
+	customServers map[string]bool // 数据库中保存了配置的服务器名称
 }
 
 // NewHandlers 创建新的处理函数实例
-func NewHandlers(store *store.SessionStore) (*Handlers, error) {
+func NewHandlers(store *store.SessionStore, approvals *store.ApprovalStore, servers *store.MCPServerStore, memory *store.TranslationMemoryStore, glossaries *store.GlossaryStore, quality *store.QualityStore, jobs *store.TranslationJobStore) (*Handlers, error) {
 	// 初始化大模型客户端
 	llmClient, err := llm.NewClient()
 	if err != nil {
 		return nil, err
 	}
 
+	// 合并内置和用户配置的MCP服务器
+	mcpServers, customServers, err := loadMCPServers(servers)
+	if err != nil {
+		return nil, err
+	}
+
+	// 启动异步翻译任务队列，租约过期的未完成任务会继续翻译
+	translator := translate.NewTranslator(llmClient, memory, glossaries, quality)
+	jobQueue := translate.NewJobQueue(translator, jobs)
+	jobQueue.Start()
+
 	return &Handlers{
-		Store:     store,
-		LLMClient: llmClient,
+		Store:         store,
+		Approvals:     approvals,
+		MCPServers:    servers,
+		Memory:        memory,
+		Glossaries:    glossaries,
+		Quality:       quality,
+		Jobs:          jobs,
+		LLMClient:     llmClient,
+		Fetcher:       fetcher.NewFetcher(),
+		MCPManager:    mcp.NewManager(mcpServers),
+		Translator:    translator,
+		JobQueue:      jobQueue,
+		customServers: customServers,
 	}, nil
 }
 
+// Close 释放处理函数持有的资源，例如已启动的MCP服务器进程和翻译任务的工作协程
+func (h *Handlers) Close() {
+	h.JobQueue.Close()
+	h.MCPManager.Close()
+}
+
 // HealthCheck 健康检查接口
 func (h *Handlers) HealthCheck(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -132,25 +179,6 @@ type Delta struct {
 	Content string `json:"content,omitempty"`
 }
 
-// TranslateSegment 翻译片段结构体
-type TranslateSegment struct {
-	ID   string `json:"id" binding:"required"`   // 片段ID，用于标识片段以便后续返回到前端相应位置
-	Text string `json:"text" binding:"required"` // 要翻译的文本
-}
-
-// TranslateRequest 翻译请求结构体
-type TranslateRequest struct {
-	Target    string             `json:"target" binding:"required"`   // 目标语言
-	Segments  []TranslateSegment `json:"segments" binding:"required"` // 要翻译的文本片段
-	ExtraArgs interface{}        `json:"extra_args,omitempty"`        // 额外参数，例如翻译的风格
-}
-
-// TranslateResponse 翻译响应结构体
-type TranslateResponse struct {
-	Target   string             `json:"target"`   // 目标语言
-	Segments []TranslateSegment `json:"segments"` // 翻译后的文本片段
-}
-
 // StreamConversation 流式对话接口
 func (h *Handlers) StreamConversation(c *gin.Context) {
 	// 从查询参数获取参数
@@ -183,7 +211,13 @@ func (h *Handlers) StreamConversation(c *gin.Context) {
 	}
 
 	// 解析模型参数
-	provider, model := h.LLMClient.ParseModel(modelStr)
+	provider, model, err := h.LLMClient.ParseModel(modelStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
 
 	// 获取会话历史消息
 	messages, err := h.Store.GetMessagesByConversationID(conversationID)
@@ -214,16 +248,31 @@ func (h *Handlers) StreamConversation(c *gin.Context) {
 		return
 	}
 
+	// 根据tools参数构造向模型声明的MCP工具
+	var toolSet *chatToolSet
+	if toolsParam := c.Query("tools"); toolsParam != "" {
+		toolSet, err = h.buildChatTools(c.Request.Context(), toolsParam)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "加载工具失败: " + err.Error(),
+			})
+			return
+		}
+	}
+	var tools []openai.Tool
+	if toolSet != nil {
+		tools = toolSet.Tools
+	}
+
 	// 调用大模型API并流式返回结果
 	ctx := context.Background()
-	stream, err := h.LLMClient.StreamChat(ctx, provider, model, chatMessages)
+	stream, served, err := h.LLMClient.StreamChatWithTools(ctx, provider, model, chatMessages, tools)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "调用大模型API失败: " + err.Error(),
 		})
 		return
 	}
-	defer stream.Close()
 
 	// 设置SSE响应头
 	c.Header("Content-Type", "text/event-stream")
@@ -234,96 +283,101 @@ func (h *Handlers) StreamConversation(c *gin.Context) {
 	// 生成响应ID
 	responseID := uuid.New().String()
 
+	// 以用户新消息结尾的上下文，用于生成标题
+	titleMessages := chatMessages
+
 	// 流式返回结果
 	c.Stream(func(w io.Writer) bool {
-		// 发送开始标记
-		c.SSEvent("start", gin.H{})
+		// 发送开始标记，包含实际处理请求的服务商
+		c.SSEvent("start", gin.H{
+			"provider": served.Provider,
+			"model":    served.Model,
+			"failover": served.Failover,
+		})
 		c.Writer.Flush()
 
-		// 流式读取并发送数据
 		responseContent := ""
-		for {
-			chunk, err := stream.Recv()
-			if err != nil {
-				// 流结束或出错
+		continueFailed := false
+		for round := 0; ; round++ {
+			// 流式读取并发送数据，同时收集模型发起的工具调用
+			var toolCalls []openai.ToolCall
+			responseContent, toolCalls = h.relayStream(c, stream, responseID, served.Model)
+			stream.Close()
+			if len(toolCalls) == 0 {
 				break
 			}
 
-			if len(chunk.Choices) > 0 {
-				delta := chunk.Choices[0].Delta
-				if delta.Content != "" {
-					responseContent += delta.Content
-
-					// 构造符合DeepSeek格式的响应
-					response := StreamResponse{
-						ID:      responseID,
-						Object:  "chat.completion.chunk",
-						Created: time.Now().Unix(),
-						Model:   model,
-					}
-
-					response.Choices = append(response.Choices, struct {
-						Index        int     `json:"index"`
-						Delta        Delta   `json:"delta"`
-						Logprobs     *string `json:"logprobs"`
-						FinishReason *string `json:"finish_reason"`
-					}{
-						Index: 0,
-						Delta: Delta{
-							Role:    delta.Role,
-							Content: delta.Content,
-						},
-						Logprobs:     nil,
-						FinishReason: nil,
-					})
-
-					// 发送数据
-					c.SSEvent("data", response)
-					c.Writer.Flush()
+			// 保存发起工具调用的助手消息
+			assistantMessage := openai.ChatCompletionMessage{
+				Role:      openai.ChatMessageRoleAssistant,
+				Content:   responseContent,
+				ToolCalls: toolCalls,
+			}
+			chatMessages = append(chatMessages, assistantMessage)
+			if err := h.Store.CreateMessage(models.FromChatMessage(conversationID, assistantMessage)); err != nil {
+				fmt.Printf("保存助手消息失败: %v\n", err)
+			}
+
+			// 依次执行工具并推送调用和结果事件
+			for _, call := range toolCalls {
+				c.SSEvent("tool_call", toolSet.describe(call))
+				c.Writer.Flush()
+
+				result := h.executeChatTool(c.Request.Context(), toolSet, call)
+				c.SSEvent("tool_result", result)
+				c.Writer.Flush()
+
+				toolMessage := openai.ChatCompletionMessage{
+					Role:       openai.ChatMessageRoleTool,
+					Content:    result.Content,
+					Name:       call.Function.Name,
+					ToolCallID: call.ID,
+				}
+				chatMessages = append(chatMessages, toolMessage)
+				if err := h.Store.CreateMessage(models.FromChatMessage(conversationID, toolMessage)); err != nil {
+					fmt.Printf("保存工具消息失败: %v\n", err)
 				}
 			}
+
+			// 将工具结果交给同一服务商继续生成，已经推送过内容，不再切换服务商。
+			// 达到轮数上限后不再允许调用工具
+			nextTools := tools
+			if round+1 >= maxToolRounds {
+				nextTools = nil
+			}
+			stream, _, err = h.LLMClient.ContinueStream(ctx, served.Provider, served.Model, chatMessages, nextTools)
+			if err != nil {
+				c.SSEvent("error", gin.H{"error": "调用大模型API失败: " + err.Error()})
+				c.Writer.Flush()
+				responseContent = ""
+				continueFailed = true
+				break
+			}
 		}
 
 		// 发送结束标记，包含finish_reason
 		finishReason := "stop"
-		response := StreamResponse{
-			ID:      responseID,
-			Object:  "chat.completion.chunk",
-			Created: time.Now().Unix(),
-			Model:   model,
-		}
-
-		response.Choices = append(response.Choices, struct {
-			Index        int     `json:"index"`
-			Delta        Delta   `json:"delta"`
-			Logprobs     *string `json:"logprobs"`
-			FinishReason *string `json:"finish_reason"`
-		}{
-			Index:        0,
-			Delta:        Delta{},
-			Logprobs:     nil,
-			FinishReason: &finishReason,
-		})
-
-		c.SSEvent("data", response)
+		c.SSEvent("data", newStreamChunk(responseID, served.Model, Delta{}, &finishReason))
 		c.Writer.Flush()
 
-		// 保存助手消息到数据库
-		assistantMessage := openai.ChatCompletionMessage{
-			Role:    openai.ChatMessageRoleAssistant,
-			Content: responseContent,
-		}
-		assistantMsg := models.FromChatMessage(conversationID, assistantMessage)
-		err = h.Store.CreateMessage(assistantMsg)
-		if err != nil {
-			// 记录错误但不中断流
-			fmt.Printf("保存助手消息失败: %v\n", err)
+		// 保存助手消息到数据库，工具调用后继续生成失败时没有回复，不保存空消息
+		if !continueFailed {
+			assistantMessage := openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleAssistant,
+				Content: responseContent,
+			}
+			assistantMsg := models.FromChatMessage(conversationID, assistantMessage)
+			err = h.Store.CreateMessage(assistantMsg)
+			if err != nil {
+				// 记录错误但不中断流
+				fmt.Printf("保存助手消息失败: %v\n", err)
+			}
 		}
 
 		// 如果这是第一条消息，自动生成对话标题
 		if len(messages) == 0 {
 			// 异步生成并更新标题
-			go h.generateAndSetConversationTitle(conversation, chatMessages, responseContent)
+			go h.generateAndSetConversationTitle(conversation, titleMessages, responseContent)
 		}
 
 		// 发送最终结束标记
@@ -334,6 +388,67 @@ func (h *Handlers) StreamConversation(c *gin.Context) {
 	})
 }
 
+// relayStream 将模型的增量内容以SSE推送给前端，返回完整的回复内容和工具调用
+func (h *Handlers) relayStream(c *gin.Context, stream *llm.Stream, responseID, model string) (string, []openai.ToolCall) {
+	responseContent := ""
+	var toolCalls []openai.ToolCall
+	for {
+		chunk, err := stream.Recv()
+		if err != nil {
+			// 流结束或出错
+			break
+		}
+		if len(chunk.Choices) == 0 {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta
+		if len(delta.ToolCalls) > 0 {
+			toolCalls = mergeToolCallDeltas(toolCalls, delta.ToolCalls)
+		}
+		if delta.Content != "" {
+			responseContent += delta.Content
+
+			// 构造符合DeepSeek格式的响应
+			c.SSEvent("data", newStreamChunk(responseID, model, Delta{
+				Role:    delta.Role,
+				Content: delta.Content,
+			}, nil))
+			c.Writer.Flush()
+		}
+	}
+
+	// 部分服务商不返回调用ID，补充生成以便关联工具结果
+	for i := range toolCalls {
+		if toolCalls[i].ID == "" {
+			toolCalls[i].ID = "call_" + uuid.New().String()
+		}
+	}
+	return responseContent, toolCalls
+}
+
+// newStreamChunk 构造一条流式响应
+func newStreamChunk(responseID, model string, delta Delta, finishReason *string) StreamResponse {
+	response := StreamResponse{
+		ID:      responseID,
+		Object:  "chat.completion.chunk",
+		Created: time.Now().Unix(),
+		Model:   model,
+	}
+	response.Choices = append(response.Choices, struct {
+		Index        int     `json:"index"`
+		Delta        Delta   `json:"delta"`
+		Logprobs     *string `json:"logprobs"`
+		FinishReason *string `json:"finish_reason"`
+	}{
+		Index:        0,
+		Delta:        delta,
+		Logprobs:     nil,
+		FinishReason: finishReason,
+	})
+	return response
+}
+
 // generateAndSetConversationTitle 生成并设置对话标题
 func (h *Handlers) generateAndSetConversationTitle(conversation *models.Conversation, chatMessages []openai.ChatCompletionMessage, responseContent string) {
 	// 构造生成标题的提示
@@ -346,22 +461,15 @@ func (h *Handlers) generateAndSetConversationTitle(conversation *models.Conversa
 	}
 
 	// 获取默认模型用于生成标题
-	provider, model := h.LLMClient.ParseModel("")
+	provider, model := h.LLMClient.DefaultModel()
 
 	// 调用大模型API生成标题
 	ctx := context.Background()
-	client, _, err := h.LLMClient.GetClient(provider)
-	if err != nil {
-		fmt.Printf("获取大模型客户端失败: %v\n", err)
-		return
-	}
-
 	req := openai.ChatCompletionRequest{
-		Model:    model,
 		Messages: titlePrompt,
 	}
 
-	resp, err := client.CreateChatCompletion(ctx, req)
+	resp, _, err := h.LLMClient.CreateChatCompletion(ctx, provider, model, req)
 	if err != nil {
 		fmt.Printf("调用大模型API生成标题失败: %v\n", err)
 		return
@@ -389,124 +497,6 @@ func (h *Handlers) generateAndSetConversationTitle(conversation *models.Conversa
 	}
 }
 
-// Translate 网页翻译接口
-// 该函数处理网页翻译请求，接收多个文本片段并翻译为目标语言
-func (h *Handlers) Translate(c *gin.Context) {
-	var req TranslateRequest
-
-	// 绑定请求参数
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "请求参数错误: " + err.Error(),
-		})
-		return
-	}
-
-	// 准备翻译消息
-	// 构造翻译提示词
-	prompt := fmt.Sprintf("请将以下内容翻译为%s语言:\n", req.Target)
-	for _, segment := range req.Segments {
-		prompt += fmt.Sprintf("片段ID %s: %s\n", segment.ID, segment.Text)
-	}
-
-	// 处理extra_args参数
-	if req.ExtraArgs != nil {
-		// 将extra_args转换为字符串
-		var extraArgsStr string
-		switch v := req.ExtraArgs.(type) {
-		case string:
-			// 如果是字符串，直接使用
-			extraArgsStr = v
-		case map[string]interface{}:
-			// 如果是对象，将其转换为易读的字符串
-			if style, ok := v["style"]; ok {
-				extraArgsStr = fmt.Sprintf("风格: %v", style)
-			} else {
-				// 处理其他可能的字段
-				for key, value := range v {
-					extraArgsStr = fmt.Sprintf("%s %s: %v", extraArgsStr, key, value)
-				}
-			}
-		default:
-			// 其他类型转换为字符串
-			extraArgsStr = fmt.Sprintf("%v", v)
-		}
-
-		if extraArgsStr != "" {
-			prompt += fmt.Sprintf("翻译要求: %s\n", extraArgsStr)
-		}
-	}
-
-	prompt += "请按照以下JSON格式返回结果，只返回JSON，不要包含其他内容:\n"
-	prompt += "{\n  \"target\": \"目标语言\",\n  \"segments\": [\n    {\"id\": \"片段ID\", \"text\": \"翻译后的文本\"}\n  ]\n}"
-
-	// 使用默认模型进行翻译（这里使用Qwen）
-	provider, model := h.LLMClient.ParseModel("")
-
-	// 构造聊天消息
-	messages := []openai.ChatCompletionMessage{
-		{
-			Role:    openai.ChatMessageRoleUser,
-			Content: prompt,
-		},
-	}
-
-	// 调用大模型API
-	ctx := context.Background()
-	client, _, err := h.LLMClient.GetClient(provider)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "获取大模型客户端失败: " + err.Error(),
-		})
-		return
-	}
-
-	// 创建聊天完成请求
-	reqBody := openai.ChatCompletionRequest{
-		Model:    model,
-		Messages: messages,
-	}
-
-	// 获取响应
-	resp, err := client.CreateChatCompletion(ctx, reqBody)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "调用大模型API失败: " + err.Error(),
-		})
-		return
-	}
-
-	// 解析响应中的JSON
-	var translateResp TranslateResponse
-	if len(resp.Choices) > 0 {
-		content := resp.Choices[0].Message.Content
-
-		// 尝试解析JSON
-		if err := json.Unmarshal([]byte(content), &translateResp); err != nil {
-			// 如果解析失败，尝试清理内容后再解析
-			// 移除可能的代码块标记
-			content = strings.TrimPrefix(content, "``json")
-			content = strings.TrimSuffix(content, "```")
-			content = strings.TrimSpace(content)
-
-			if err := json.Unmarshal([]byte(content), &translateResp); err != nil {
-				c.JSON(http.StatusInternalServerError, gin.H{
-					"error":       "解析翻译结果失败: " + err.Error(),
-					"raw_content": content,
-				})
-				return
-			}
-		}
-	} else {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "大模型未返回有效内容",
-		})
-		return
-	}
-
-	c.JSON(http.StatusOK, translateResp)
-}
-
 // GetConversationDetail 获取会话详情接口
 func (h *Handlers) GetConversationDetail(c *gin.Context) {
 	// 从查询参数获取会话ID
@@ -560,88 +550,10 @@ func (h *Handlers) GetConversationHistory(c *gin.Context) {
 	})
 }
 
-// MCP MCP服务接口
-func (h *Handlers) MCP(c *gin.Context) {
-	var req models.MCPRequest
-
-	// 绑定请求参数
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error": "请求参数错误: " + err.Error(),
-		})
-		return
-	}
-
-	// 获取默认的MCP服务器配置
-	defaultServers := models.GetDefaultMCPServers()
-
-	// 构建响应中的MCP服务器配置
-	responseServers := make(map[string]models.MCPServer)
-
-	// 遍历请求的MCP服务器名称
-	for _, serverName := range req.MCPServers {
-		// 检查是否为默认支持的服务器
-		if server, exists := defaultServers[serverName]; exists {
-			responseServers[serverName] = server
-		} else {
-			// 如果不是默认服务器，返回错误
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf("不支持的MCP服务器: %s，支持的服务器: %v",
-					serverName, getAvailableServerNames(defaultServers)),
-			})
-			return
-		}
-	}
-
-	// 构造响应
-	response := models.MCPResponse{
-		Model:      req.Model,
-		MCPServers: responseServers,
-		Timestamp:  time.Now(),
-		Query:      req.Query,
-		Tool:       req.Tool,
-	}
-
-	// 如果指定了工具调用，执行相应的工具
-	if req.Tool != "" && req.Query != "" {
-		toolResult, err := h.executeMCPTool(req.Tool, req.Query, req.Params)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "工具调用失败: " + err.Error(),
-			})
-			return
-		}
-		response.ToolResult = toolResult
-	}
-
-	c.JSON(http.StatusOK, response)
-}
-
-// getAvailableServerNames 获取可用的服务器名称列表
-func getAvailableServerNames(servers map[string]models.MCPServer) []string {
-	names := make([]string, 0, len(servers))
-	for name := range servers {
-		names = append(names, name)
-	}
-	return names
-}
-
-// executeMCPTool 执行MCP工具调用
-func (h *Handlers) executeMCPTool(tool, query string, params map[string]interface{}) (interface{}, error) {
-	switch tool {
-	case "web-search":
-		return h.executeWebSearchTool(query, params)
-	case "fetch":
-		return h.executeFetchTool(query, params)
-	default:
-		return nil, fmt.Errorf("不支持的工具: %s", tool)
-	}
-}
-
 // executeWebSearchTool 执行联网搜索工具
 func (h *Handlers) executeWebSearchTool(query string, params map[string]interface{}) (interface{}, error) {
 	// 创建联网搜索客户端
-	webSearchClient, err := models.NewWebSearchClient()
+	webSearchClient, err := websearch.NewClient(h.MCPManager)
 	if err != nil {
 		return nil, fmt.Errorf("创建联网搜索客户端失败: %v", err)
 	}
@@ -685,7 +597,7 @@ func (h *Handlers) executeWebSearchTool(query string, params map[string]interfac
 	}
 
 	// 执行搜索
-	response, err := webSearchClient.Search(searchReq)
+	response, err := webSearchClient.Search(context.Background(), searchReq)
 	if err != nil {
 		return nil, fmt.Errorf("执行联网搜索失败: %v", err)
 	}
@@ -694,7 +606,7 @@ func (h *Handlers) executeWebSearchTool(query string, params map[string]interfac
 }
 
 // executeFetchTool 执行网页抓取工具
-func (h *Handlers) executeFetchTool(query string, params map[string]interface{}) (interface{}, error) {
+func (h *Handlers) executeFetchTool(ctx context.Context, query string, params map[string]interface{}) (interface{}, error) {
 	// 从params中提取URL
 	url, ok := params["url"].(string)
 	if !ok {
@@ -744,30 +656,18 @@ func (h *Handlers) executeFetchTool(query string, params map[string]interface{})
 		fetchReq.ExtractFields = models.GetDefaultExtractFields(fetchReq.ContentType)
 	}
 
-	// 模拟调用Fetch MCP获取网页内容
-	webContent, err := h.fetchWebContent(fetchReq.URL, fetchReq.MaxLength)
+	// 抓取网页内容
+	page, err := h.fetchWebContent(ctx, fetchReq.URL, fetchReq.MaxLength)
 	if err != nil {
 		return nil, fmt.Errorf("抓取网页失败: %v", err)
 	}
 
-	// 调用LLM解析和提取结构化信息
-	parsedData, err := h.parseWebContentWithLLM(webContent, fetchReq)
+	// 从网页标记中提取结构化信息，缺失的字段再调用LLM补充
+	response, err := h.buildFetchResponse(page, fetchReq)
 	if err != nil {
 		return nil, fmt.Errorf("解析网页内容失败: %v", err)
 	}
 
-	// 构造成功响应
-	response := models.FetchResponse{
-		URL:           fetchReq.URL,
-		Title:         parsedData.Title,
-		Content:       parsedData.Content,
-		Summary:       parsedData.Summary,
-		ExtractedData: parsedData.ExtractedData,
-		Language:      parsedData.Language,
-		Status:        "success",
-		FetchTime:     time.Now(),
-	}
-
 	return response, nil
 }
 
@@ -797,9 +697,8 @@ func (h *Handlers) Fetch(c *gin.Context) {
 		req.Language = "auto"
 	}
 
-	// 模拟调用Fetch MCP获取网页内容
-	// 在实际应用中，这里会调用Docker运行mcp/fetch
-	webContent, err := h.fetchWebContent(req.URL, req.MaxLength)
+	// 抓取网页内容
+	page, err := h.fetchWebContent(c.Request.Context(), req.URL, req.MaxLength)
 	if err != nil {
 		response := models.FetchResponse{
 			URL:       req.URL,
@@ -811,8 +710,8 @@ func (h *Handlers) Fetch(c *gin.Context) {
 		return
 	}
 
-	// 调用LLM解析和提取结构化信息
-	parsedData, err := h.parseWebContentWithLLM(webContent, req)
+	// 从网页标记中提取结构化信息，缺失的字段再调用LLM补充
+	response, err := h.buildFetchResponse(page, req)
 	if err != nil {
 		response := models.FetchResponse{
 			URL:       req.URL,
@@ -824,98 +723,163 @@ func (h *Handlers) Fetch(c *gin.Context) {
 		return
 	}
 
-	// 构造成功响应
-	response := models.FetchResponse{
-		URL:           req.URL,
-		Title:         parsedData.Title,
-		Content:       parsedData.Content,
-		Summary:       parsedData.Summary,
-		ExtractedData: parsedData.ExtractedData,
-		Language:      parsedData.Language,
-		Status:        "success",
-		FetchTime:     time.Now(),
-	}
-
 	c.JSON(http.StatusOK, response)
 }
 
-// fetchWebContent 调用Fetch MCP获取网页内容
-func (h *Handlers) fetchWebContent(url string, maxLength int) (string, error) {
-	// 这里集成真实的Fetch MCP功能
-	// 在实际应用中，这会调用: echo '{"method": "fetch", "params": {"url": "..."}}' | docker run -i --rm mcp/fetch
-	// 现在我们使用内置的fetch工具来获取内容
-
-	// 模拟MCP调用的JSON输入
-	mcpInput := fmt.Sprintf(`{"method": "fetch", "params": {"url": "%s", "max_length": %d}}`, url, maxLength)
-
-	// 在实际部署时，这里应该执行Docker命令
-	// 现在我们直接使用一个简化版本来获取内容
+// fetchWebContent 下载网页并提取可读文本和元数据，客户端断开连接时取消下载
+func (h *Handlers) fetchWebContent(ctx context.Context, url string, maxLength int) (*fetcher.Page, error) {
+	return h.Fetcher.Fetch(ctx, url, maxLength)
+}
 
-	// 注意：在真实环境中，这里会调用Docker运行mcp/fetch
-	// 并解析返回的JSON结果
-	content := fmt.Sprintf(`从 %s 获取的网页内容:
+// buildFetchResponse 根据网页标记填充响应，只有摘要和标记中缺失的字段才交给LLM
+func (h *Handlers) buildFetchResponse(page *fetcher.Page, req models.FetchRequest) (*models.FetchResponse, error) {
+	article := page.Article
+	content := article.Content
+	if content == "" {
+		content = page.Text
+	}
 
-新华网 English.news.cn 主页
-标题: Xinhua – China, World, Business, Sports, Photos and Video
+	response := &models.FetchResponse{
+		URL:           req.URL,
+		Title:         article.Title,
+		Content:       content,
+		ExtractedData: map[string]interface{}{},
+		Language:      article.Language,
+		Status:        "success",
+		FetchTime:     time.Now(),
+	}
+	if response.Language == "" && req.Language != "auto" {
+		response.Language = req.Language
+	}
+	if article.CanonicalURL != "" {
+		response.ExtractedData["canonical_url"] = article.CanonicalURL
+	}
 
-主要新闻:
-- Book on Xi's views on strengthening, revitalizing armed forces published
-- Chinese premier urges efforts to meet annual development goals  
-- China's lawmaking efforts continue to advance socialist democracy
-- China extends anti-subsidy probe into EU dairy products
-- Taiwan's restoration to China is outcome of WWII victory, part of postwar int'l order: Chinese FM
+	// 收集标记中无法获取的字段
+	var missing []string
+	for _, field := range req.ExtractFields {
+		switch field {
+		case "content":
+			continue
+		case "summary":
+			missing = append(missing, field)
+			continue
+		}
+		value, ok := article.Field(field)
+		if !ok {
+			missing = append(missing, field)
+			continue
+		}
+		if field != "title" {
+			response.ExtractedData[field] = value
+		}
+	}
 
-导航栏包含: Home, China, Biz, Culture & Lifestyle, World, Video & Live, GLOBALink, Xinhua Headlines
+	// 调用LLM补充摘要和缺失字段
+	if len(missing) > 0 {
+		result, served, err := h.parseWebContentWithLLM(content, req, missing)
+		if err != nil {
+			return nil, err
+		}
+		response.Provider = string(served.Provider)
+		response.Model = served.Model
+		for _, field := range missing {
+			value, ok := result[field]
+			if !ok || isEmptyValue(value) {
+				continue
+			}
+			switch field {
+			case "summary":
+				response.Summary, _ = value.(string)
+			case "title":
+				response.Title, _ = value.(string)
+			default:
+				response.ExtractedData[field] = value
+			}
+		}
+	}
 
-最新新闻内容:
-Host city of 2025 World Games unfolds new vitality in opening wider to globe
-Extravehicular spacesuit of China's space station supports 20 EVAs
-60-year transformation of China's Xizang: Cultural developments in numbers
-China's summer box office surpasses 10 billion yuan, led by domestic films
+	if req.ContentType == "news" {
+		response.News = newsItemFromResponse(response)
+	}
 
-多语言版本: 中文 Français Русский Deutsch Español عربي 한국어 日本語 Português
+	return response, nil
+}
 
-业务板块:
-- 中国新闻: 聚焦台海、经济发展、文化生活
-- 国际新闻: 涵盖亚太、欧洲、非洲、北美地区
-- 视频直播: GLOBALink系列、短视频内容
-- 专题报道: 一带一路、体育、深度报道
+// newsItemFromResponse 根据抓取结果构造新闻条目
+func newsItemFromResponse(response *models.FetchResponse) *models.NewsItem {
+	item := &models.NewsItem{
+		Title:     response.Title,
+		Content:   response.Content,
+		Summary:   response.Summary,
+		URL:       response.URL,
+		FetchTime: response.FetchTime,
+	}
+	item.Author, _ = response.ExtractedData["author"].(string)
+	item.PublishDate, _ = response.ExtractedData["publish_date"].(string)
+	item.Category, _ = response.ExtractedData["category"].(string)
+	switch tags := response.ExtractedData["tags"].(type) {
+	case []string:
+		item.Tags = tags
+	case []interface{}:
+		for _, tag := range tags {
+			if str, ok := tag.(string); ok {
+				item.Tags = append(item.Tags, str)
+			}
+		}
+	}
+	return item
+}
 
-MCP输入: %s`, url, mcpInput)
+// isEmptyValue 判断LLM返回的字段值是否为空
+func isEmptyValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
 
-	return content, nil
+// fetchFieldDescriptions LLM补充字段时使用的字段说明
+var fetchFieldDescriptions = map[string]string{
+	"title":        "页面标题",
+	"summary":      "内容摘要(限制在200字内)",
+	"author":       "作者",
+	"publish_date": "发布日期",
+	"category":     "分类",
+	"tags":         "标签数组，例如[\"标签1\", \"标签2\"]",
 }
 
-// parseWebContentWithLLM 使用LLM解析网页内容
-func (h *Handlers) parseWebContentWithLLM(webContent string, req models.FetchRequest) (*models.FetchResponse, error) {
+// parseWebContentWithLLM 使用LLM提取网页标记中无法获取的字段
+func (h *Handlers) parseWebContentWithLLM(webContent string, req models.FetchRequest, fields []string) (map[string]interface{}, llm.ServedBy, error) {
 	// 构造LLM提示词
-	extractFieldsStr := strings.Join(req.ExtractFields, ", ")
-	prompt := fmt.Sprintf(`请分析以下网页内容，提取结构化信息。
+	var fieldLines strings.Builder
+	for _, field := range fields {
+		description, ok := fetchFieldDescriptions[field]
+		if !ok {
+			description = field
+		}
+		fieldLines.WriteString(fmt.Sprintf("- %s: %s\n", field, description))
+	}
+	prompt := fmt.Sprintf(`请分析以下网页内容，提取指定的字段。
 
 网页URL: %s
 内容类型: %s
-需要提取的字段: %s
 目标语言: %s
 
+需要提取的字段:
+%s
 网页内容:
 %s
 
-请按照以下JSON格式返回结果，只返回JSON，不要包含其他内容:
-{
-  "title": "页面标题",
-  "content": "主要内容(限制在1000字内)",
-  "summary": "内容摘要(限制在200字内)",
-  "language": "检测到的语言(zh/en/auto)",
-  "extracted_data": {
-    "author": "作者",
-    "publish_date": "发布日期",
-    "category": "分类",
-    "tags": ["标签1", "标签2"]
-  }
-}`, req.URL, req.ContentType, extractFieldsStr, req.Language, webContent)
+请只返回一个包含以上字段的JSON对象，无法确定的字段返回空字符串，不要包含其他内容。`, req.URL, req.ContentType, req.Language, fieldLines.String(), webContent)
 
 	// 使用默认模型解析内容
-	provider, model := h.LLMClient.ParseModel("")
+	provider, model := h.LLMClient.DefaultModel()
 
 	// 构造聊天消息
 	messages := []openai.ChatCompletionMessage{
@@ -927,23 +891,17 @@ func (h *Handlers) parseWebContentWithLLM(webContent string, req models.FetchReq
 
 	// 调用大模型API
 	ctx := context.Background()
-	client, _, err := h.LLMClient.GetClient(provider)
-	if err != nil {
-		return nil, fmt.Errorf("获取大模型客户端失败: %v", err)
-	}
-
 	reqBody := openai.ChatCompletionRequest{
-		Model:    model,
 		Messages: messages,
 	}
 
-	resp, err := client.CreateChatCompletion(ctx, reqBody)
+	resp, served, err := h.LLMClient.CreateChatCompletion(ctx, provider, model, reqBody)
 	if err != nil {
-		return nil, fmt.Errorf("调用大模型API失败: %v", err)
+		return nil, served, fmt.Errorf("调用大模型API失败: %v", err)
 	}
 
 	if len(resp.Choices) == 0 {
-		return nil, fmt.Errorf("大模型未返回有效内容")
+		return nil, served, fmt.Errorf("大模型未返回有效内容")
 	}
 
 	// 解析LLM响应的JSON
@@ -956,26 +914,12 @@ func (h *Handlers) parseWebContentWithLLM(webContent string, req models.FetchReq
 	content = strings.TrimSpace(content)
 
 	// 解析JSON
-	var result struct {
-		Title         string                 `json:"title"`
-		Content       string                 `json:"content"`
-		Summary       string                 `json:"summary"`
-		Language      string                 `json:"language"`
-		ExtractedData map[string]interface{} `json:"extracted_data"`
-	}
-
+	var result map[string]interface{}
 	if err := json.Unmarshal([]byte(content), &result); err != nil {
-		return nil, fmt.Errorf("解析LLM响应失败: %v, 原始内容: %s", err, content)
+		return nil, served, fmt.Errorf("解析LLM响应失败: %v, 原始内容: %s", err, content)
 	}
 
-	// 返回解析结果
-	return &models.FetchResponse{
-		Title:         result.Title,
-		Content:       result.Content,
-		Summary:       result.Summary,
-		Language:      result.Language,
-		ExtractedData: result.ExtractedData,
-	}, nil
+	return result, served, nil
 }
 
 // WebSearch 联网搜索接口
@@ -1005,7 +949,7 @@ func (h *Handlers) WebSearch(c *gin.Context) {
 	}
 
 	// 创建联网搜索客户端
-	webSearchClient, err := models.NewWebSearchClient()
+	webSearchClient, err := websearch.NewClient(h.MCPManager)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "创建联网搜索客户端失败: " + err.Error(),
@@ -1014,7 +958,7 @@ func (h *Handlers) WebSearch(c *gin.Context) {
 	}
 
 	// 执行搜索
-	response, err := webSearchClient.Search(req)
+	response, err := webSearchClient.Search(c.Request.Context(), req)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "执行联网搜索失败: " + err.Error(),
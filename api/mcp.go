@@ -0,0 +1,411 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/mcp"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mcpDiscoveryTimeout 获取单个服务器工具列表的超时时间
+const mcpDiscoveryTimeout = 90 * time.Second
+
+// MCP MCP服务接口
+func (h *Handlers) MCP(c *gin.Context) {
+	var req models.MCPRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 获取当前的MCP服务器配置
+	servers := h.MCPManager.Servers()
+
+	// 构建响应中的MCP服务器配置
+	responseServers := make(map[string]models.MCPServer)
+
+	// 遍历请求的MCP服务器名称
+	for _, serverName := range req.MCPServers {
+		// 检查是否为已配置的服务器
+		if server, exists := servers[serverName]; exists {
+			responseServers[serverName] = server
+		} else {
+			// 如果不是已配置的服务器，返回错误
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("不支持的MCP服务器: %s，支持的服务器: %v",
+					serverName, getAvailableServerNames(servers)),
+			})
+			return
+		}
+	}
+
+	// 构造响应
+	response := models.MCPResponse{
+		Model:      req.Model,
+		MCPServers: responseServers,
+		Timestamp:  time.Now(),
+		Query:      req.Query,
+		Tool:       req.Tool,
+	}
+
+	// 如果指定了工具调用，执行相应的工具
+	if req.Tool != "" {
+		toolResult, serverName, approval, err := h.executeMCPTool(c.Request.Context(), req)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "工具调用失败: " + err.Error(),
+			})
+			return
+		}
+		response.Server = serverName
+
+		// 工具不在自动批准列表中，等待人工审批
+		if approval != nil {
+			response.Approval = approval
+			c.JSON(http.StatusAccepted, response)
+			return
+		}
+		response.ToolResult = toolResult
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// ListMCPServers 列出已配置的MCP服务器及其实时工具列表
+func (h *Handlers) ListMCPServers(c *gin.Context) {
+	names := h.MCPManager.Names()
+	servers := h.MCPManager.Servers()
+
+	// 并发获取各服务器的工具列表
+	infos := make([]models.MCPServerInfo, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		infos[i] = models.MCPServerInfo{Server: servers[name], Source: h.mcpServerSource(name)}
+		if servers[name].Disabled {
+			continue
+		}
+		wg.Add(1)
+		go func(info *models.MCPServerInfo, name string) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(c.Request.Context(), mcpDiscoveryTimeout)
+			defer cancel()
+			tools, err := h.MCPManager.Tools(ctx, name)
+			if err != nil {
+				info.Error = err.Error()
+				return
+			}
+			info.Tools = toModelTools(tools)
+		}(&infos[i], name)
+	}
+	wg.Wait()
+
+	// 附加运行状态
+	statuses := h.MCPManager.Status()
+	for i := range infos {
+		for _, status := range statuses {
+			if status.Name == names[i] {
+				infos[i].Running = status.Running
+				infos[i].Restarts = status.Restarts
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"servers": infos,
+	})
+}
+
+// ListMCPServerTools 获取指定MCP服务器的实时工具列表
+func (h *Handlers) ListMCPServerTools(c *gin.Context) {
+	name := c.Param("name")
+
+	server, ok := h.MCPManager.Server(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "MCP服务器不存在: " + name,
+		})
+		return
+	}
+	if server.Disabled {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "MCP服务器已禁用: " + name,
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), mcpDiscoveryTimeout)
+	defer cancel()
+	tools, err := h.MCPManager.Tools(ctx, name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取工具列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"server": name,
+		"tools":  toModelTools(tools),
+	})
+}
+
+// getAvailableServerNames 获取可用的服务器名称列表
+func getAvailableServerNames(servers map[string]models.MCPServer) []string {
+	names := make([]string, 0, len(servers))
+	for name := range servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// toModelTools 转换为接口返回的工具结构
+func toModelTools(tools []mcp.Tool) []models.MCPTool {
+	result := make([]models.MCPTool, 0, len(tools))
+	for _, tool := range tools {
+		result = append(result, models.MCPTool{
+			Name:        tool.Name,
+			Description: tool.Description,
+			InputSchema: tool.InputSchema,
+		})
+	}
+	return result
+}
+
+// findMCPTool 在指定的服务器中查找提供该工具的服务器
+func (h *Handlers) findMCPTool(ctx context.Context, serverNames []string, tool string) (string, *mcp.Tool, error) {
+	var errs []string
+	for _, name := range serverNames {
+		discoverCtx, cancel := context.WithTimeout(ctx, mcpDiscoveryTimeout)
+		tools, err := h.MCPManager.Tools(discoverCtx, name)
+		cancel()
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		for i := range tools {
+			if tools[i].Name == tool {
+				return name, &tools[i], nil
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return "", nil, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return "", nil, nil
+}
+
+// executeMCPTool 执行MCP工具调用，优先路由到提供该工具的MCP服务器，
+// 其次使用内置的web-search和fetch工具，返回结果和实际处理的服务器名称。
+// 服务器上不在AutoApprove列表中的工具不会立即执行，而是创建待审批的审批单返回
+func (h *Handlers) executeMCPTool(ctx context.Context, req models.MCPRequest) (interface{}, string, *models.MCPApproval, error) {
+	serverName, discovered, discoverErr := h.findMCPTool(ctx, req.MCPServers, req.Tool)
+	if discovered != nil {
+		arguments := make(map[string]interface{}, len(req.Params)+1)
+		for key, value := range req.Params {
+			arguments[key] = value
+		}
+		// 未显式传入query参数时，使用请求中的query填充
+		if _, ok := arguments["query"]; !ok && req.Query != "" && schemaHasProperty(discovered.InputSchema, "query") {
+			arguments["query"] = req.Query
+		}
+
+		server, _ := h.MCPManager.Server(serverName)
+		if !server.IsAutoApproved(req.Tool) {
+			approval, err := h.requestApproval(serverName, req.Tool, arguments, req.ConversationID, req.Model)
+			if err != nil {
+				return nil, serverName, nil, err
+			}
+			return nil, serverName, approval, nil
+		}
+
+		result, err := h.MCPManager.CallTool(ctx, serverName, req.Tool, arguments)
+		if err != nil {
+			return nil, serverName, nil, err
+		}
+		return result, serverName, nil, nil
+	}
+
+	// 内置工具由后端直接实现，不需要审批
+	switch req.Tool {
+	case "web-search":
+		if req.Query == "" {
+			return nil, "", nil, fmt.Errorf("web-search工具需要query参数")
+		}
+		result, err := h.executeWebSearchTool(req.Query, req.Params)
+		return result, "", nil, err
+	case "fetch":
+		result, err := h.executeFetchTool(ctx, req.Query, req.Params)
+		return result, "", nil, err
+	default:
+		if discoverErr != nil {
+			return nil, "", nil, fmt.Errorf("未找到工具%s，部分服务器的工具列表获取失败: %v", req.Tool, discoverErr)
+		}
+		return nil, "", nil, fmt.Errorf("不支持的工具: %s", req.Tool)
+	}
+}
+
+// requestApproval 创建待审批的工具调用
+func (h *Handlers) requestApproval(serverName, tool string, arguments map[string]interface{}, conversationID, model string) (*models.MCPApproval, error) {
+	approval := &models.MCPApproval{
+		ApprovalID:     uuid.New().String(),
+		Server:         serverName,
+		Tool:           tool,
+		Arguments:      arguments,
+		ConversationID: conversationID,
+		Model:          model,
+		Status:         models.ApprovalPending,
+		CreatedAt:      time.Now(),
+	}
+	if err := h.Approvals.CreateApproval(approval); err != nil {
+		return nil, fmt.Errorf("创建审批单失败: %v", err)
+	}
+	return approval, nil
+}
+
+// ListMCPApprovals 获取工具调用审批列表，默认只返回待审批的审批单
+func (h *Handlers) ListMCPApprovals(c *gin.Context) {
+	status := c.DefaultQuery("status", models.ApprovalPending)
+	if status == "all" {
+		status = ""
+	}
+
+	approvals, err := h.Approvals.ListApprovals(status)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取审批列表失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"approvals": approvals,
+	})
+}
+
+// GetMCPApproval 获取审批单详情
+func (h *Handlers) GetMCPApproval(c *gin.Context) {
+	approval, err := h.Approvals.GetApproval(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "审批单不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// DecideMCPApproval 批准或拒绝工具调用，批准后立即执行工具并返回结果。只有管理员可以审批
+func (h *Handlers) DecideMCPApproval(c *gin.Context) {
+	id := c.Param("id")
+	if !isAdminRequest(c) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "审批工具调用需要管理员权限",
+		})
+		return
+	}
+
+	var req models.MCPApprovalDecision
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	approval, err := h.Approvals.GetApproval(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "审批单不存在",
+		})
+		return
+	}
+
+	status := models.ApprovalDenied
+	if req.Action == "approve" {
+		status = models.ApprovalApproved
+	}
+
+	// 只有待审批的审批单可以被处理，避免重复执行
+	decided, err := h.Approvals.DecideApproval(id, status, req.Reason, req.DecidedBy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "更新审批单失败: " + err.Error(),
+		})
+		return
+	}
+	if !decided {
+		// 审批单在查询之后被其他请求处理，重新读取当前状态
+		if current, err := h.Approvals.GetApproval(id); err == nil {
+			approval = current
+		}
+		c.JSON(http.StatusConflict, gin.H{
+			"error": fmt.Sprintf("审批单已处理，当前状态: %s", approval.Status),
+		})
+		return
+	}
+
+	if status == models.ApprovalApproved {
+		h.runApprovedTool(c.Request.Context(), approval)
+	}
+
+	approval, err = h.Approvals.GetApproval(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取审批单失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, approval)
+}
+
+// runApprovedTool 执行已批准的工具调用并记录结果
+func (h *Handlers) runApprovedTool(ctx context.Context, approval *models.MCPApproval) (*mcp.CallToolResult, error) {
+	result, err := h.MCPManager.CallTool(ctx, approval.Server, approval.Tool, approval.Arguments)
+	if err == nil && result.IsError {
+		err = fmt.Errorf("工具返回错误: %s", result.Text())
+	}
+
+	var resultJSON json.RawMessage
+	if result != nil {
+		resultJSON, _ = json.Marshal(result)
+	}
+
+	status, errMsg := models.ApprovalCompleted, ""
+	if err != nil {
+		status, errMsg = models.ApprovalFailed, err.Error()
+	}
+	if saveErr := h.Approvals.CompleteApproval(approval.ApprovalID, status, resultJSON, errMsg); saveErr != nil {
+		log.Printf("保存工具执行结果失败: %v", saveErr)
+	}
+	return result, err
+}
+
+// schemaHasProperty 判断JSON Schema中是否声明了指定属性
+func schemaHasProperty(schema json.RawMessage, name string) bool {
+	var parsed struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(schema, &parsed); err != nil {
+		return false
+	}
+	_, ok := parsed.Properties[name]
+	return ok
+}
@@ -0,0 +1,427 @@
+package api
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+	"github.com/aimmetal-tech/wistrans-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+// adminTokenHeader 管理员令牌请求头，与环境变量MCP_ADMIN_TOKEN一致时允许保存高风险命令和审批工具调用
+const adminTokenHeader = "X-Admin-Token"
+
+// dangerousCommands 可以执行任意代码或破坏系统的命令，只有管理员可以配置
+var dangerousCommands = map[string]bool{
+	"sh": true, "bash": true, "zsh": true, "dash": true, "ksh": true, "fish": true,
+	"cmd": true, "powershell": true, "pwsh": true,
+	"sudo": true, "su": true, "doas": true, "env": true, "eval": true, "exec": true, "xargs": true,
+	"rm": true, "dd": true, "mkfs": true, "shred": true, "chmod": true, "chown": true,
+	"curl": true, "wget": true, "nc": true, "ncat": true, "netcat": true, "socat": true, "ssh": true, "scp": true,
+}
+
+// inlineCodeFlags 解释器执行内联代码的参数
+var inlineCodeFlags = map[string][]string{
+	"python":  {"-c"},
+	"python3": {"-c"},
+	"node":    {"-e", "--eval", "-p", "--print"},
+	"deno":    {"eval"},
+	"bun":     {"-e", "--eval"},
+	"perl":    {"-e", "-E"},
+	"ruby":    {"-e"},
+	"php":     {"-r"},
+}
+
+// dangerousDockerFlags 容器逃逸或访问宿主机资源的docker参数
+var dangerousDockerFlags = []string{
+	"--privileged", "--pid=host", "--network=host", "--net=host", "--ipc=host", "--userns=host",
+	"--cap-add", "--device", "--security-opt",
+}
+
+// shellMetaSequences 参数中出现时可能被拼接成shell命令的字符序列
+var shellMetaSequences = []string{";", "&&", "||", "|", "`", "$(", ">", "<", "\n"}
+
+// GetMCPServer 获取单个MCP服务器配置
+func (h *Handlers) GetMCPServer(c *gin.Context) {
+	name := c.Param("name")
+
+	server, ok := h.MCPManager.Server(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "MCP服务器不存在: " + name,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"server": server,
+		"source": h.mcpServerSource(name),
+	})
+}
+
+// CreateMCPServer 新增MCP服务器配置
+func (h *Handlers) CreateMCPServer(c *gin.Context) {
+	var server models.MCPServer
+	if err := c.ShouldBindJSON(&server); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	server.Name = strings.TrimSpace(server.Name)
+
+	if status, err := validateMCPServer(server, nil, isAdminRequest(c)); err != nil {
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	h.serversMu.Lock()
+	defer h.serversMu.Unlock()
+
+	// 与内置服务器同名时应通过PATCH修改
+	if _, exists := h.MCPManager.Server(server.Name); exists {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "MCP服务器已存在: " + server.Name,
+		})
+		return
+	}
+
+	if err := h.MCPServers.CreateServer(server); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "保存MCP服务器失败: " + err.Error(),
+		})
+		return
+	}
+	if err := h.reloadMCPServers(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "重新加载MCP服务器失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"server": server,
+		"source": h.mcpServerSource(server.Name),
+	})
+}
+
+// UpdateMCPServer 部分更新MCP服务器配置，修改内置服务器时保存为覆盖配置
+func (h *Handlers) UpdateMCPServer(c *gin.Context) {
+	name := c.Param("name")
+
+	var patch models.MCPServerPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	h.serversMu.Lock()
+	defer h.serversMu.Unlock()
+
+	current, ok := h.MCPManager.Server(name)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "MCP服务器不存在: " + name,
+		})
+		return
+	}
+
+	server := patch.Apply(current)
+	if status, err := validateMCPServer(server, &current, isAdminRequest(c)); err != nil {
+		c.JSON(status, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := h.MCPServers.SaveServer(server); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "保存MCP服务器失败: " + err.Error(),
+		})
+		return
+	}
+	if err := h.reloadMCPServers(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "重新加载MCP服务器失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"server": server,
+		"source": h.mcpServerSource(name),
+	})
+}
+
+// DeleteMCPServer 删除用户配置的MCP服务器，删除内置服务器的覆盖配置后恢复为默认配置
+func (h *Handlers) DeleteMCPServer(c *gin.Context) {
+	name := c.Param("name")
+
+	h.serversMu.Lock()
+	defer h.serversMu.Unlock()
+
+	deleted, err := h.MCPServers.DeleteServer(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "删除MCP服务器失败: " + err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		if _, builtin := models.GetDefaultMCPServers()[name]; builtin {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "内置MCP服务器不能删除，可以通过PATCH设置disabled禁用: " + name,
+			})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "MCP服务器不存在: " + name,
+		})
+		return
+	}
+
+	if err := h.reloadMCPServers(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "重新加载MCP服务器失败: " + err.Error(),
+		})
+		return
+	}
+
+	response := gin.H{
+		"message": "MCP服务器已删除",
+		"server":  name,
+	}
+	if server, ok := h.MCPManager.Server(name); ok {
+		response["message"] = "已恢复为内置配置"
+		response["restored"] = server
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// loadMCPServers 合并内置的默认配置和数据库中的用户配置，同名时用户配置优先
+func loadMCPServers(servers *store.MCPServerStore) (map[string]models.MCPServer, map[string]bool, error) {
+	merged := models.GetDefaultMCPServers()
+	custom, err := servers.ListServers()
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载MCP服务器配置失败: %v", err)
+	}
+	names := make(map[string]bool, len(custom))
+	for _, server := range custom {
+		merged[server.Name] = server
+		names[server.Name] = true
+	}
+	return merged, names, nil
+}
+
+// reloadMCPServers 重新加载配置并更新MCP服务器管理器，调用方需持有serversMu
+func (h *Handlers) reloadMCPServers() error {
+	merged, custom, err := loadMCPServers(h.MCPServers)
+	if err != nil {
+		return err
+	}
+	h.MCPManager.SetServers(merged)
+	h.customMu.Lock()
+	h.customServers = custom
+	h.customMu.Unlock()
+	return nil
+}
+
+// mcpServerSource 返回服务器配置的来源
+func (h *Handlers) mcpServerSource(name string) string {
+	_, builtin := models.GetDefaultMCPServers()[name]
+	h.customMu.RLock()
+	custom := h.customServers[name]
+	h.customMu.RUnlock()
+	switch {
+	case builtin && custom:
+		return models.MCPServerOverride
+	case builtin:
+		return models.MCPServerBuiltin
+	default:
+		return models.MCPServerCustom
+	}
+}
+
+// isAdminRequest 判断请求是否携带了有效的管理员令牌
+func isAdminRequest(c *gin.Context) bool {
+	token := os.Getenv("MCP_ADMIN_TOKEN")
+	if token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(c.GetHeader(adminTokenHeader)), []byte(token)) == 1
+}
+
+// validateMCPServer 校验服务器配置，返回错误时同时返回对应的HTTP状态码。
+// previous为修改前的配置，新增时为nil。高风险命令、环境变量和自动批准的工具只有管理员可以修改，
+// 内置服务器以及配置了env或autoApprove的服务器只有管理员可以修改连接方式
+func validateMCPServer(server models.MCPServer, previous *models.MCPServer, admin bool) (int, error) {
+	if server.Name == "" || strings.ContainsAny(server.Name, "/\\") {
+		return http.StatusBadRequest, errors.New("服务器名称不能为空且不能包含斜杠")
+	}
+	if server.Command == "" && server.URL == "" {
+		return http.StatusBadRequest, errors.New("必须配置command或url")
+	}
+
+	if server.URL != "" {
+		u, err := url.Parse(server.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return http.StatusBadRequest, fmt.Errorf("url必须是有效的http或https地址: %s", server.URL)
+		}
+	}
+	switch server.Transport {
+	case "", "sse", "streamable-http", "http":
+	default:
+		return http.StatusBadRequest, fmt.Errorf("不支持的传输类型: %s", server.Transport)
+	}
+
+	if admin {
+		return 0, nil
+	}
+
+	// 自动批准的工具调用不再经过确认，环境变量可以改变被启动进程的行为
+	var before models.MCPServer
+	if previous != nil {
+		before = *previous
+	}
+	if !sameStrings(server.AutoApprove, before.AutoApprove) {
+		return http.StatusForbidden, errors.New("修改autoApprove需要管理员权限")
+	}
+	if !sameEnv(server.Env, before.Env) {
+		return http.StatusForbidden, errors.New("修改env需要管理员权限")
+	}
+	// 管理员配置的env中可能引用了密钥，自动批准的工具不经确认就会执行，
+	// 修改连接的命令或地址会把密钥发送给其他服务器，或让自动批准的工具在其他服务器上执行
+	_, builtin := models.GetDefaultMCPServers()[server.Name]
+	if previous != nil && (builtin || len(before.Env) > 0 || len(before.AutoApprove) > 0) && !sameConnection(server, before) {
+		return http.StatusForbidden, errors.New("修改内置服务器或配置了env、autoApprove的服务器的command、args、url和headers需要管理员权限")
+	}
+
+	if server.Command == "" {
+		return 0, nil
+	}
+	if reason := dangerousCommandReason(server.Command, server.Args); reason != "" {
+		return http.StatusForbidden, fmt.Errorf("命令存在安全风险(%s)，需要管理员权限", reason)
+	}
+	return 0, nil
+}
+
+// sameStrings 判断两个字符串列表是否相同
+func sameStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// sameConnection 判断两个配置的命令、参数、地址和请求头是否相同
+func sameConnection(a, b models.MCPServer) bool {
+	return a.Command == b.Command && sameStrings(a.Args, b.Args) && a.URL == b.URL && sameEnv(a.Headers, b.Headers)
+}
+
+// sameEnv 判断两组键值是否相同
+func sameEnv(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
+
+// dangerousCommandReason 检查命令和参数是否存在明显的安全风险，返回风险说明
+func dangerousCommandReason(command string, args []string) string {
+	if strings.Contains(command, "..") {
+		return "命令路径包含.."
+	}
+	for _, seq := range shellMetaSequences {
+		if strings.Contains(command, seq) {
+			return "命令包含shell元字符"
+		}
+	}
+
+	base := strings.ToLower(filepath.Base(command))
+	base = strings.TrimSuffix(base, ".exe")
+	if dangerousCommands[base] {
+		return "不允许直接执行" + base
+	}
+
+	for _, arg := range args {
+		for _, seq := range shellMetaSequences {
+			if strings.Contains(arg, seq) {
+				return "参数包含shell元字符"
+			}
+		}
+	}
+
+	for _, flag := range inlineCodeFlags[base] {
+		for _, arg := range args {
+			if arg == flag {
+				return base + "执行内联代码"
+			}
+		}
+	}
+
+	if base == "docker" || base == "podman" {
+		for i, arg := range args {
+			for _, flag := range dangerousDockerFlags {
+				if arg == flag || strings.HasPrefix(arg, flag+"=") {
+					return "容器参数" + flag
+				}
+			}
+			// 挂载宿主机根目录或docker套接字
+			if arg == "-v" || arg == "--volume" || arg == "--mount" {
+				if i+1 < len(args) && isSensitiveMount(args[i+1]) {
+					return "挂载宿主机敏感目录"
+				}
+			}
+			if (strings.HasPrefix(arg, "--volume=") || strings.HasPrefix(arg, "--mount=")) && isSensitiveMount(arg[strings.Index(arg, "=")+1:]) {
+				return "挂载宿主机敏感目录"
+			}
+		}
+	}
+	return ""
+}
+
+// isSensitiveMount 判断挂载源是否为宿主机根目录、系统目录或docker套接字
+func isSensitiveMount(spec string) bool {
+	source := spec
+	if strings.Contains(spec, "source=") || strings.Contains(spec, "src=") {
+		for _, part := range strings.Split(spec, ",") {
+			if key, value, ok := strings.Cut(part, "="); ok && (key == "source" || key == "src") {
+				source = value
+			}
+		}
+	} else if before, _, ok := strings.Cut(spec, ":"); ok {
+		source = before
+	}
+	source = filepath.Clean(source)
+	if source == "/" || strings.Contains(source, "docker.sock") {
+		return true
+	}
+	for _, dir := range []string{"/etc", "/root", "/proc", "/sys", "/dev", "/var/run", "/run", "/boot"} {
+		if source == dir || strings.HasPrefix(source, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListModels 模型目录接口，返回可作为model参数使用的模型及其能力。
+// 支持provider参数按服务商过滤，refresh=true时重新获取服务商的模型列表
+func (h *Handlers) ListModels(c *gin.Context) {
+	refresh := c.Query("refresh") == "true"
+	catalog := h.LLMClient.Models(c.Request.Context(), refresh)
+
+	if name := c.Query("provider"); name != "" {
+		provider, ok := h.LLMClient.Registry().Lookup(name)
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "服务商不存在: " + name,
+			})
+			return
+		}
+		filtered := catalog.Models[:0]
+		for _, model := range catalog.Models {
+			if strings.EqualFold(model.Provider, provider.Name) {
+				filtered = append(filtered, model)
+			}
+		}
+		catalog.Models = filtered
+	}
+
+	c.JSON(http.StatusOK, catalog)
+}
@@ -0,0 +1,240 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+	"github.com/aimmetal-tech/wistrans-backend/translate"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxTranslateTargets 一次请求最多的目标语言数
+const maxTranslateTargets = 10
+
+// Translate 网页翻译接口
+// 该函数处理网页翻译请求，接收多个文本片段并翻译为目标语言；指定targets时同时翻译为多种语言，按目标语言返回结果
+func (h *Handlers) Translate(c *gin.Context) {
+	var req models.TranslateRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 校验片段ID，解析并校验模型和采样参数
+	provider, model, err := h.validateTranslateRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 多个目标语言并发翻译，全部目标语言都翻译失败时返回错误
+	if len(req.Targets) > 0 {
+		multiResp := h.Translator.TranslateTargets(c.Request.Context(), provider, model, req)
+		if len(multiResp.Failed) == len(req.Targets) {
+			var errs []string
+			for _, target := range req.Targets {
+				errs = append(errs, target+": "+strings.Join(multiResp.Translations[target].Errors, "; "))
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "翻译失败: 全部片段翻译失败: " + strings.Join(errs, "; "),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, multiResp)
+		return
+	}
+
+	// 分批并发翻译，服务商不可用时自动切换到备用服务商
+	translateResp, err := h.Translator.Translate(c.Request.Context(), provider, model, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "翻译失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, translateResp)
+}
+
+// TranslateStream 流式翻译接口
+// 以SSE格式返回结果，每批片段翻译完成后立即推送其中的片段，最后推送汇总信息
+func (h *Handlers) TranslateStream(c *gin.Context) {
+	var req models.TranslateRequest
+
+	// 绑定请求参数
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	// 校验片段ID，解析并校验模型和采样参数
+	provider, model, err := h.validateTranslateRequest(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if len(req.Targets) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: 流式翻译不支持targets，请为每种目标语言分别请求",
+		})
+		return
+	}
+
+	// 设置SSE响应头
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("Access-Control-Allow-Origin", "*")
+
+	c.SSEvent("start", gin.H{
+		"target": req.Target,
+		"total":  len(req.Segments),
+	})
+	c.Writer.Flush()
+
+	// 每个片段翻译完成后立即推送，客户端断开时停止翻译
+	response := h.Translator.TranslateEach(c.Request.Context(), provider, model, req, func(segment models.TranslateSegment) {
+		c.SSEvent("segment", segment)
+		c.Writer.Flush()
+	})
+
+	// 质量评估在全部片段完成后进行，逐个推送评估结果
+	for _, segment := range response.Segments {
+		if segment.Quality != nil {
+			c.SSEvent("quality", gin.H{
+				"id":      segment.ID,
+				"quality": segment.Quality,
+			})
+		}
+	}
+
+	summary := models.TranslateSummary{
+		Target:     req.Target,
+		Total:      len(req.Segments),
+		Translated: len(response.Segments) - len(response.Failed) - len(response.Skipped),
+		Skipped:    len(response.Skipped),
+		Failed:     response.Failed,
+		Errors:     response.Errors,
+		Provider:   response.Provider,
+		Model:      response.Model,
+		Detected:   response.Detected,
+		Quality:    response.Quality,
+	}
+	if response.Memory != nil {
+		summary.Cached = response.Memory.Hits
+	}
+	c.SSEvent("summary", summary)
+	c.SSEvent("end", gin.H{})
+	c.Writer.Flush()
+}
+
+// validateTranslateRequest 校验目标语言、片段ID不重复和翻译选项，解析请求中的模型，并按服务商注册表中的模型信息校验采样参数。
+// 旧版本的extra_args会转换为options
+func (h *Handlers) validateTranslateRequest(req *models.TranslateRequest) (llm.ModelProvider, string, error) {
+	if err := validateTargets(req); err != nil {
+		return "", "", err
+	}
+
+	// 片段ID用于合并和校验翻译结果，必须唯一
+	ids := make(map[string]bool, len(req.Segments))
+	for _, segment := range req.Segments {
+		if ids[segment.ID] {
+			return "", "", fmt.Errorf("片段ID重复: %s", segment.ID)
+		}
+		ids[segment.ID] = true
+	}
+
+	if !translate.ValidQualityMethod(req.QualityMethod) {
+		return "", "", fmt.Errorf("不支持的质量评估方式: %s，可选值为judge、back_translation、both", req.QualityMethod)
+	}
+	if req.QualityModel != "" {
+		if _, _, err := h.LLMClient.ParseModel(req.QualityModel); err != nil {
+			return "", "", fmt.Errorf("quality_model: %v", err)
+		}
+	}
+
+	if !translate.ValidFormat(req.Format) {
+		return "", "", fmt.Errorf("不支持的格式: %s，可选值为text、html、markdown", req.Format)
+	}
+
+	if req.Options != nil && req.ExtraArgs != nil {
+		return "", "", fmt.Errorf("options和extra_args不能同时使用，extra_args已废弃")
+	}
+	var options models.TranslateOptions
+	if req.ExtraArgs != nil {
+		// 兼容旧版本客户端，extra_args中无法识别的内容忽略
+		options = translate.NormalizeLegacyOptions(req.ExtraArgs.TranslateOptions)
+	} else {
+		normalized, err := translate.NormalizeOptions(req.EffectiveOptions())
+		if err != nil {
+			return "", "", err
+		}
+		options = normalized
+	}
+	req.Options, req.ExtraArgs = &options, nil
+
+	provider, model, err := h.LLMClient.ParseModel(req.Model)
+	if err != nil {
+		return "", "", err
+	}
+
+	if req.Temperature != nil && (*req.Temperature < 0 || *req.Temperature > 2) {
+		return "", "", fmt.Errorf("temperature必须在0到2之间")
+	}
+	if req.TopP != nil && (*req.TopP <= 0 || *req.TopP > 1) {
+		return "", "", fmt.Errorf("top_p必须大于0且不超过1")
+	}
+	if req.MaxTokens < 0 {
+		return "", "", fmt.Errorf("max_tokens不能为负数")
+	}
+	if config, ok := h.LLMClient.Registry().Lookup(string(provider)); ok {
+		if info, ok := config.FindModel(model); ok && info.ContextWindow > 0 && req.MaxTokens > info.ContextWindow {
+			return "", "", fmt.Errorf("max_tokens超过模型%s的上下文窗口%d", model, info.ContextWindow)
+		}
+	}
+	return provider, model, nil
+}
+
+// validateTargets 校验target和targets二选一，targets中的语言去掉首尾空白后不能为空或重复（不区分大小写）
+func validateTargets(req *models.TranslateRequest) error {
+	if len(req.Targets) == 0 {
+		if strings.TrimSpace(req.Target) == "" {
+			return fmt.Errorf("缺少目标语言target")
+		}
+		return nil
+	}
+	if strings.TrimSpace(req.Target) != "" {
+		return fmt.Errorf("target和targets不能同时使用")
+	}
+	if len(req.Targets) > maxTranslateTargets {
+		return fmt.Errorf("targets最多包含%d种语言", maxTranslateTargets)
+	}
+
+	seen := make(map[string]bool, len(req.Targets))
+	for i, target := range req.Targets {
+		target = strings.TrimSpace(target)
+		if target == "" {
+			return fmt.Errorf("targets中不能有空的目标语言")
+		}
+		if seen[normalizeLang(target)] {
+			return fmt.Errorf("目标语言重复: %s", target)
+		}
+		seen[normalizeLang(target)] = true
+		req.Targets[i] = target
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/document"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxJobFileSize 翻译任务上传文件的最大字节数
+const maxJobFileSize = 10 << 20
+
+// CreateTranslationJob 创建异步翻译任务，立即返回任务ID，片段由后台工作协程翻译。
+// 请求体可以是与网页翻译接口相同的JSON，也可以是multipart表单：file为要翻译的文件，request为JSON格式的翻译参数，
+// 上传的文件保存在任务中，翻译结束后可以下载同格式的译文文件
+func (h *Handlers) CreateTranslationJob(c *gin.Context) {
+	var req models.TranslateRequest
+	filename := ""
+	var content []byte
+	if c.ContentType() == "multipart/form-data" {
+		var err error
+		if filename, content, err = bindJobFile(c, &req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "请求参数错误: " + err.Error(),
+			})
+			return
+		}
+	} else if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if len(req.Targets) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: 翻译任务不支持targets，请为每种目标语言分别创建任务",
+		})
+		return
+	}
+
+	// 校验片段ID，解析并校验模型和采样参数
+	if _, _, err := h.validateTranslateRequest(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	job := &models.TranslationJob{
+		ID:        uuid.New().String(),
+		Status:    models.JobQueued,
+		Target:    req.Target,
+		Source:    req.Source,
+		Filename:  filename,
+		Total:     len(req.Segments),
+		Request:   req,
+		Document:  content,
+		CreatedAt: time.Now(),
+	}
+	job.UpdatedAt = job.CreatedAt
+	if err := h.Jobs.CreateJob(job, req.Segments); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "创建翻译任务失败: " + err.Error(),
+		})
+		return
+	}
+	h.JobQueue.Notify()
+
+	c.JSON(http.StatusAccepted, job)
+}
+
+// GetTranslationJob 获取翻译任务的状态、进度和已处理的片段，segments=false时不返回片段
+func (h *Handlers) GetTranslationJob(c *gin.Context) {
+	job, err := h.Jobs.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "翻译任务不存在",
+		})
+		return
+	}
+
+	if c.Query("segments") != "false" {
+		if job.Segments, err = h.Jobs.ListJobSegments(job.ID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": "获取翻译结果失败: " + err.Error(),
+			})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelTranslationJob 取消排队或处理中的翻译任务，已处理的片段仍然保留
+func (h *Handlers) CancelTranslationJob(c *gin.Context) {
+	id := c.Param("id")
+	cancelled, err := h.Jobs.CancelJob(id)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "取消翻译任务失败: " + err.Error(),
+		})
+		return
+	}
+	if cancelled {
+		h.JobQueue.Cancel(id)
+	}
+
+	job, err := h.Jobs.GetJob(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "翻译任务不存在",
+		})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "翻译任务已经结束，状态为" + job.Status,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// DownloadTranslationJob 下载上传文件翻译后的同格式文件，翻译失败、跳过和未处理的片段保留原文
+func (h *Handlers) DownloadTranslationJob(c *gin.Context) {
+	job, err := h.Jobs.GetJob(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "翻译任务不存在",
+		})
+		return
+	}
+	data, err := h.Jobs.GetJobDocument(job.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取上传的文件失败: " + err.Error(),
+		})
+		return
+	}
+	if data == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "翻译任务不是通过上传文件创建的",
+		})
+		return
+	}
+	if !job.Finished() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "翻译任务尚未结束，状态为" + job.Status,
+		})
+		return
+	}
+
+	doc, err := document.Parse(job.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "解析上传的文件失败: " + err.Error(),
+		})
+		return
+	}
+	segments, err := h.Jobs.ListJobSegments(job.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取翻译结果失败: " + err.Error(),
+		})
+		return
+	}
+	translations := make(map[string]string, len(segments))
+	for _, segment := range segments {
+		if segment.Status == models.SegmentTranslated {
+			translations[segment.ID] = segment.Text
+		}
+	}
+	content, err := doc.Assemble(job.Target, translations)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "生成译文文件失败: " + err.Error(),
+		})
+		return
+	}
+
+	filename := document.TranslatedName(filepath.Base(job.Filename), job.Target)
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	c.Data(http.StatusOK, contentType, content)
+}
+
+// bindJobFile 读取上传的文件并按文件格式拆分为片段，片段的文本格式由文件格式决定。
+// 返回上传的文件名和文件内容
+func bindJobFile(c *gin.Context, req *models.TranslateRequest) (string, []byte, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxJobFileSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		return "", nil, fmt.Errorf("缺少上传的文件: %v", err)
+	}
+	if header.Size > maxJobFileSize {
+		return "", nil, fmt.Errorf("文件不能超过%dMB", maxJobFileSize>>20)
+	}
+	if !document.Supported(header.Filename) {
+		return "", nil, fmt.Errorf("不支持的文件类型: %s，可选值为%s", header.Filename, strings.Join(document.Extensions(), "、"))
+	}
+
+	if params := c.PostForm("request"); params != "" {
+		if err := json.Unmarshal([]byte(params), req); err != nil {
+			return "", nil, fmt.Errorf("request不是有效的JSON: %v", err)
+		}
+	}
+	if req.Target == "" {
+		return "", nil, fmt.Errorf("缺少目标语言target")
+	}
+	if len(req.Segments) > 0 {
+		return "", nil, fmt.Errorf("上传文件时不能同时指定segments")
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return "", nil, err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", nil, err
+	}
+
+	doc, err := document.Parse(header.Filename, content)
+	if err != nil {
+		return "", nil, err
+	}
+	req.Format = doc.Format()
+	req.Segments = doc.Segments()
+	if len(req.Segments) == 0 {
+		return "", nil, fmt.Errorf("文件中没有需要翻译的内容")
+	}
+	return header.Filename, content, nil
+}
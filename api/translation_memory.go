@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultMemoryPageSize 翻译记忆列表默认每页条目数
+	defaultMemoryPageSize = 50
+	// maxMemoryPageSize 翻译记忆列表每页最多条目数
+	maxMemoryPageSize = 500
+)
+
+// ListTranslationMemory 获取翻译记忆列表，支持target、model、q参数过滤和limit、offset分页
+func (h *Handlers) ListTranslationMemory(c *gin.Context) {
+	filter, err := translationMemoryFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	limit, err := queryInt(c, "limit", defaultMemoryPageSize)
+	if err != nil || limit <= 0 || limit > maxMemoryPageSize {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("请求参数错误: limit必须在1到%d之间", maxMemoryPageSize),
+		})
+		return
+	}
+	offset, err := queryInt(c, "offset", 0)
+	if err != nil || offset < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: offset不能为负数",
+		})
+		return
+	}
+
+	entries, total, err := h.Memory.ListTranslations(filter, limit, offset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取翻译记忆失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"total":   total,
+	})
+}
+
+// GetTranslationMemory 获取翻译记忆条目详情
+func (h *Handlers) GetTranslationMemory(c *gin.Context) {
+	entry, err := h.Memory.GetTranslation(c.Param("key"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "翻译记忆条目不存在",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
+
+// DeleteTranslationMemory 删除翻译记忆条目，同时从进程内缓存中移除
+func (h *Handlers) DeleteTranslationMemory(c *gin.Context) {
+	key := c.Param("key")
+
+	deleted, err := h.Memory.DeleteTranslation(key)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "删除翻译记忆失败: " + err.Error(),
+		})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "翻译记忆条目不存在",
+		})
+		return
+	}
+	h.Translator.ForgetTranslation(key)
+
+	c.JSON(http.StatusOK, gin.H{
+		"key": key,
+	})
+}
+
+// PurgeTranslationMemory 按target、model、q、before参数清理翻译记忆，all=true时清空全部条目。
+// 为避免误删，未指定任何条件时拒绝请求
+func (h *Handlers) PurgeTranslationMemory(c *gin.Context) {
+	filter, err := translationMemoryFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: " + err.Error(),
+		})
+		return
+	}
+	if filter == (store.TranslationMemoryFilter{}) && c.Query("all") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: 请指定target、model、q或before，清空全部条目请使用all=true",
+		})
+		return
+	}
+
+	deleted, err := h.Memory.PurgeTranslations(filter)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "清理翻译记忆失败: " + err.Error(),
+		})
+		return
+	}
+	// 进程内缓存不记录条目的属性，清理后整体清空
+	h.Translator.ClearMemoryCache()
+
+	c.JSON(http.StatusOK, gin.H{
+		"deleted": deleted,
+	})
+}
+
+// translationMemoryFilter 从查询参数中读取翻译记忆的过滤条件，before为RFC3339格式的时间
+func translationMemoryFilter(c *gin.Context) (store.TranslationMemoryFilter, error) {
+	filter := store.TranslationMemoryFilter{
+		Target: normalizeLang(c.Query("target")),
+		Model:  c.Query("model"),
+		Query:  c.Query("q"),
+	}
+	if before := c.Query("before"); before != "" {
+		t, err := time.Parse(time.RFC3339, before)
+		if err != nil {
+			return filter, fmt.Errorf("before必须是RFC3339格式的时间")
+		}
+		filter.Before = t
+	}
+	return filter, nil
+}
+
+// queryInt 读取整数查询参数，未设置时使用默认值
+func queryInt(c *gin.Context, name string, fallback int) (int, error) {
+	value := c.Query(name)
+	if value == "" {
+		return fallback, nil
+	}
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/store"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// defaultQualityDays 翻译质量统计默认的天数
+	defaultQualityDays = 30
+	// maxQualityDays 翻译质量统计最多的天数
+	maxQualityDays = 365
+)
+
+// TranslationQualityStats 按服务商、模型和日期统计翻译质量，支持provider、model、target参数过滤，
+// days指定统计最近多少天，默认30天
+func (h *Handlers) TranslationQualityStats(c *gin.Context) {
+	days, err := queryInt(c, "days", defaultQualityDays)
+	if err != nil || days <= 0 || days > maxQualityDays {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "请求参数错误: days必须在1到365之间",
+		})
+		return
+	}
+
+	stats, err := h.Quality.QualityStats(store.QualityFilter{
+		Provider: c.Query("provider"),
+		Model:    c.Query("model"),
+		Target:   normalizeLang(c.Query("target")),
+		Since:    time.Now().AddDate(0, 0, -days),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "获取翻译质量统计失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"days":  days,
+		"stats": stats,
+	})
+}
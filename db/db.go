@@ -94,6 +94,182 @@ func createTables() error {
 		return fmt.Errorf("创建 messages 表失败: %v", err)
 	}
 
+	// 为 messages 表补充工具调用相关的列
+	_, err = DB.Exec(`
+		ALTER TABLE messages
+			ADD COLUMN IF NOT EXISTS name TEXT,
+			ADD COLUMN IF NOT EXISTS tool_calls TEXT,
+			ADD COLUMN IF NOT EXISTS tool_call_id TEXT
+	`)
+	if err != nil {
+		return fmt.Errorf("更新 messages 表失败: %v", err)
+	}
+
+	// 创建 mcp_approvals 表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS mcp_approvals (
+			approval_id TEXT PRIMARY KEY,
+			server TEXT NOT NULL,
+			tool TEXT NOT NULL,
+			arguments TEXT,
+			conversation_id TEXT,
+			model TEXT,
+			status TEXT NOT NULL,
+			reason TEXT,
+			decided_by TEXT,
+			result TEXT,
+			error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			decided_at TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 mcp_approvals 表失败: %v", err)
+	}
+
+	// 创建 mcp_servers 表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS mcp_servers (
+			name TEXT PRIMARY KEY,
+			command TEXT,
+			args TEXT,
+			env TEXT,
+			url TEXT,
+			transport TEXT,
+			headers TEXT,
+			disabled BOOLEAN NOT NULL DEFAULT FALSE,
+			auto_approve TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 mcp_servers 表失败: %v", err)
+	}
+
+	// 创建 translation_memory 表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS translation_memory (
+			key TEXT PRIMARY KEY,
+			source_text TEXT NOT NULL,
+			target TEXT NOT NULL,
+			model TEXT NOT NULL,
+			style TEXT,
+			format TEXT,
+			translation TEXT NOT NULL,
+			hits INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_translation_memory_target_model ON translation_memory (target, model)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 translation_memory 表失败: %v", err)
+	}
+
+	// 创建 glossary_terms 表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS glossary_terms (
+			term_id TEXT PRIMARY KEY,
+			glossary TEXT NOT NULL,
+			source_lang TEXT NOT NULL DEFAULT '',
+			target_lang TEXT NOT NULL,
+			source_term TEXT NOT NULL,
+			target_term TEXT NOT NULL,
+			case_sensitive BOOLEAN NOT NULL DEFAULT FALSE,
+			note TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_glossary_terms_unique ON glossary_terms (glossary, source_lang, target_lang, source_term)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 glossary_terms 表失败: %v", err)
+	}
+
+	// 创建 translation_quality 表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS translation_quality (
+			id SERIAL PRIMARY KEY,
+			provider TEXT NOT NULL,
+			model TEXT NOT NULL,
+			judge TEXT,
+			method TEXT NOT NULL,
+			source_lang TEXT,
+			target_lang TEXT NOT NULL,
+			score INTEGER NOT NULL,
+			issues TEXT,
+			omission_count INTEGER NOT NULL DEFAULT 0,
+			mistranslation_count INTEGER NOT NULL DEFAULT 0,
+			untranslated_count INTEGER NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_translation_quality_model ON translation_quality (provider, model, created_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 translation_quality 表失败: %v", err)
+	}
+
+	// 创建 translation_jobs 表
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS translation_jobs (
+			job_id TEXT PRIMARY KEY,
+			status TEXT NOT NULL,
+			target TEXT NOT NULL,
+			source TEXT,
+			filename TEXT,
+			request TEXT NOT NULL,
+			provider TEXT,
+			model TEXT,
+			errors TEXT[],
+			error TEXT,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			started_at TIMESTAMP,
+			finished_at TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_translation_jobs_status ON translation_jobs (status, created_at)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 translation_jobs 表失败: %v", err)
+	}
+
+	// 为 translation_jobs 表补充上传的原始文件，用于下载译文文件
+	_, err = DB.Exec(`
+		ALTER TABLE translation_jobs
+			ADD COLUMN IF NOT EXISTS document BYTEA
+	`)
+	if err != nil {
+		return fmt.Errorf("更新 translation_jobs 表失败: %v", err)
+	}
+
+	// 为 translation_jobs 表补充处理任务的实例和租约，租约过期的任务可以被其他实例接管
+	_, err = DB.Exec(`
+		ALTER TABLE translation_jobs
+			ADD COLUMN IF NOT EXISTS worker_id TEXT,
+			ADD COLUMN IF NOT EXISTS lease_expires_at TIMESTAMP
+	`)
+	if err != nil {
+		return fmt.Errorf("更新 translation_jobs 表失败: %v", err)
+	}
+
+	// 创建 translation_job_segments 表，status为空表示片段尚未处理
+	_, err = DB.Exec(`
+		CREATE TABLE IF NOT EXISTS translation_job_segments (
+			job_id TEXT REFERENCES translation_jobs(job_id) ON DELETE CASCADE,
+			position INTEGER NOT NULL,
+			segment_id TEXT NOT NULL,
+			source_text TEXT NOT NULL,
+			status TEXT,
+			cached BOOLEAN NOT NULL DEFAULT FALSE,
+			result TEXT,
+			PRIMARY KEY (job_id, position)
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("创建 translation_job_segments 表失败: %v", err)
+	}
+
 	log.Println("数据库表检查完成")
 	return nil
 }
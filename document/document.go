@@ -0,0 +1,208 @@
+// Package document 将Markdown、字幕、XLIFF、PO和DOCX等文件拆分为翻译片段，并按译文重新组装为同格式的文件
+package document
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// 片段的文本格式，与translate包中的格式一致
+const (
+	formatText     = "text"
+	formatHTML     = "html"
+	formatMarkdown = "markdown"
+)
+
+// Document 拆分后的文档
+type Document interface {
+	// Format 片段的文本格式 (text, html, markdown)，决定翻译时保护哪些标签和代码
+	Format() string
+	// Segments 按在文件中出现的顺序返回需要翻译的片段
+	Segments() []models.TranslateSegment
+	// Assemble 用译文替换对应的片段，返回同格式的文件。translations中没有的片段保留原样；
+	// 全部片段的译文与原文相同时，单语格式返回与原文件完全相同的内容
+	Assemble(target string, translations map[string]string) ([]byte, error)
+}
+
+// parser 解析一种格式的文件
+type parser func(data []byte) (Document, error)
+
+// parsers 支持的文件扩展名及其解析函数
+var parsers = map[string]parser{
+	".txt":      parseText(formatText),
+	".html":     parseText(formatHTML),
+	".htm":      parseText(formatHTML),
+	".md":       parseMarkdown,
+	".markdown": parseMarkdown,
+	".srt":      parseSRT,
+	".vtt":      parseVTT,
+	".xlf":      parseXLIFF,
+	".xliff":    parseXLIFF,
+	".po":       parsePO,
+	".pot":      parsePO,
+	".docx":     parseDOCX,
+}
+
+// Extensions 返回支持的文件扩展名，按字母顺序排列
+func Extensions() []string {
+	extensions := make([]string, 0, len(parsers))
+	for extension := range parsers {
+		extensions = append(extensions, extension)
+	}
+	sort.Strings(extensions)
+	return extensions
+}
+
+// Supported 判断是否支持该文件
+func Supported(filename string) bool {
+	_, ok := parsers[strings.ToLower(filepath.Ext(filename))]
+	return ok
+}
+
+// Parse 按文件扩展名解析文件
+func Parse(filename string, data []byte) (Document, error) {
+	extension := strings.ToLower(filepath.Ext(filename))
+	parse, ok := parsers[extension]
+	if !ok {
+		return nil, fmt.Errorf("不支持的文件类型: %s，可选值为%s", filename, strings.Join(Extensions(), "、"))
+	}
+	// DOCX是压缩包，其他格式都是文本文件
+	if extension != ".docx" && !utf8.Valid(data) {
+		return nil, fmt.Errorf("%s必须使用UTF-8编码", filepath.Base(filename))
+	}
+	document, err := parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析%s失败: %v", filepath.Base(filename), err)
+	}
+	return document, nil
+}
+
+// TranslatedName 返回译文文件名，在扩展名前加上目标语言，例如guide.md翻译为英语时为guide.en.md
+func TranslatedName(filename, target string) string {
+	extension := filepath.Ext(filename)
+	return strings.TrimSuffix(filename, extension) + "." + target + extension
+}
+
+// span 文件中一个片段对应的位置。单语格式中位置上就是原文，双语格式中位置上是译文应写入的地方
+type span struct {
+	start, end int
+	segment    models.TranslateSegment
+	inPlace    bool                // 位置上的内容就是原文，译文与原文相同时保留原始内容
+	edit       bool                // 只在同ID的片段有译文时修改该位置，例如去掉PO条目的fuzzy标记，不作为单独的片段
+	encode     func(string) string // 将译文转换为写入文件的内容
+}
+
+// spanDocument 基于位置替换的文档，替换范围以外的内容原样保留
+type spanDocument struct {
+	data   []byte
+	format string
+	spans  []span
+	target func(data []byte, target string) []byte // 有译文时更新文件中的目标语言，为nil时不更新
+}
+
+func (d *spanDocument) Format() string {
+	return d.format
+}
+
+func (d *spanDocument) Segments() []models.TranslateSegment {
+	segments := make([]models.TranslateSegment, 0, len(d.spans))
+	for _, s := range d.spans {
+		if !s.edit {
+			segments = append(segments, s.segment)
+		}
+	}
+	return segments
+}
+
+func (d *spanDocument) Assemble(target string, translations map[string]string) ([]byte, error) {
+	var b strings.Builder
+	last, changed := 0, false
+	for _, s := range d.spans {
+		text, ok := translations[s.segment.ID]
+		if !ok || (s.inPlace && text == s.segment.Text) {
+			continue
+		}
+		b.Write(d.data[last:s.start])
+		b.WriteString(s.encode(text))
+		last, changed = s.end, true
+	}
+	b.Write(d.data[last:])
+
+	result := []byte(b.String())
+	if changed && d.target != nil && target != "" {
+		result = d.target(result, target)
+	}
+	return result, nil
+}
+
+// add 添加一个片段，ID为空时按顺序编号
+func (d *spanDocument) add(s span) {
+	if s.segment.ID == "" {
+		s.segment.ID = fmt.Sprintf("s%d", len(d.spans)+1)
+	}
+	if s.encode == nil {
+		s.encode = func(text string) string { return text }
+	}
+	d.spans = append(d.spans, s)
+}
+
+// uniqueID 在ID重复时加上序号，保证片段ID唯一
+func uniqueID(seen map[string]bool, id string) string {
+	unique := id
+	for i := 2; seen[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	seen[unique] = true
+	return unique
+}
+
+// newSegment 创建片段，文本中的换行统一为\n
+func newSegment(id, text string) models.TranslateSegment {
+	return models.TranslateSegment{ID: id, Text: strings.ReplaceAll(text, "\r\n", "\n")}
+}
+
+// line 文件中的一行，start和end为不含换行符的内容在文件中的位置
+type line struct {
+	start, end int
+	text       string
+}
+
+// splitLines 按行拆分文件内容，同时支持\n和\r\n
+func splitLines(content string) []line {
+	var lines []line
+	for start := 0; start < len(content); {
+		end := strings.IndexByte(content[start:], '\n')
+		next := start + end + 1
+		if end < 0 {
+			end, next = len(content)-start, len(content)
+		}
+		text := strings.TrimSuffix(content[start:start+end], "\r")
+		lines = append(lines, line{start: start, end: start + len(text), text: text})
+		start = next
+	}
+	return lines
+}
+
+// lineEnding 返回文件使用的换行符
+func lineEnding(data []byte) string {
+	if strings.Contains(string(data), "\r\n") {
+		return "\r\n"
+	}
+	return "\n"
+}
+
+// withLineEnding 将译文中的换行转换为文件使用的换行符
+func withLineEnding(newline string) func(string) string {
+	return func(text string) string {
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+		if newline == "\n" {
+			return text
+		}
+		return strings.ReplaceAll(text, "\n", newline)
+	}
+}
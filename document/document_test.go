@@ -0,0 +1,267 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// identity 每个片段的译文都与原文相同
+func identity(segments []models.TranslateSegment) map[string]string {
+	translations := make(map[string]string, len(segments))
+	for _, segment := range segments {
+		translations[segment.ID] = segment.Text
+	}
+	return translations
+}
+
+// prefixed 每个片段的译文为原文加上前缀"T:"，保证组装时写入了译文
+func prefixed(segments []models.TranslateSegment) map[string]string {
+	translations := make(map[string]string, len(segments))
+	for _, segment := range segments {
+		translations[segment.ID] = "T:" + segment.Text
+	}
+	return translations
+}
+
+func TestAssembleIdentity(t *testing.T) {
+	tests := []struct {
+		file      string
+		format    string
+		bilingual bool // 双语格式把译文写入目标元素，与原文相同的译文也会改变文件
+	}{
+		{"sample.txt", formatText, false},
+		{"sample.html", formatHTML, false},
+		{"sample.md", formatMarkdown, false},
+		{"sample.srt", formatHTML, false},
+		{"sample.vtt", formatHTML, false},
+		{"sample.docx", formatHTML, false},
+		{"sample.po", formatText, true},
+		{"sample.xlf", formatHTML, true},
+		{"sample.xliff", formatHTML, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := Parse(tt.file, data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if doc.Format() != tt.format {
+				t.Errorf("Format = %q, want %q", doc.Format(), tt.format)
+			}
+			segments := doc.Segments()
+			if len(segments) == 0 {
+				t.Fatal("Segments: want at least one segment")
+			}
+
+			// 没有译文时所有格式都原样返回
+			if out, err := doc.Assemble("zh-CN", nil); err != nil || string(out) != string(data) {
+				t.Errorf("Assemble(nil) changed the file: %v\n%s", err, out)
+			}
+
+			out, err := doc.Assemble("zh-CN", identity(segments))
+			if err != nil {
+				t.Fatalf("Assemble: %v", err)
+			}
+			if !tt.bilingual {
+				if string(out) != string(data) {
+					t.Errorf("Assemble(identity) changed the file:\n%s", out)
+				}
+				return
+			}
+
+			// 双语格式：写入目标后重新解析得到相同的片段，再次组装不再改变文件
+			again, err := Parse(tt.file, out)
+			if err != nil {
+				t.Fatalf("Parse assembled file: %v\n%s", err, out)
+			}
+			if !reflect.DeepEqual(again.Segments(), segments) {
+				t.Errorf("Segments after Assemble = %+v, want %+v", again.Segments(), segments)
+			}
+			if twice, err := again.Assemble("zh-CN", identity(segments)); err != nil || string(twice) != string(out) {
+				t.Errorf("second Assemble(identity) changed the file: %v\n%s\nwant\n%s", err, twice, out)
+			}
+		})
+	}
+}
+
+func TestAssembleTranslated(t *testing.T) {
+	tests := []struct {
+		file      string
+		bilingual bool     // 双语格式重新解析时片段仍为原文
+		contains  []string // 组装结果中必须保留的结构
+	}{
+		{"sample.txt", false, []string{"这一段有两行。\n\nT:第二段。\n\n\n\nT:第三段"}},
+		{"sample.html", false, []string{`<strong>多种语言</strong>，并且<a href="https://example.com">免费试用</a>`}},
+		{"sample.md", false, []string{
+			"---\ntitle: 使用指南\ntags: [guide]\n---",
+			"# T:快速开始 #",
+			"- [x] T:支持术语表",
+			"```go\nfmt.Println(\"不要翻译代码\")\n```",
+			"    缩进的代码块也不翻译",
+			"| T:参数 | T:说明 |\n| ---- | :--: |",
+			"<!-- 注释不翻译 -->\n[控制台]: http://localhost:8080",
+		}},
+		// 示例字幕使用\r\n，译文中的换行按文件的换行符写回
+		{"sample.srt", false, []string{"2\r\n00:00:03,500 --> 00:00:06,000\r\nT:<i>这是第二条字幕，</i>\r\n有两行。\r\n"}},
+		{"sample.vtt", false, []string{
+			"WEBVTT - 示例字幕\n\nSTYLE\n::cue { color: white; }\n\nNOTE 这条注释不翻译\n",
+			"intro\n00:00:01.000 --> 00:00:03.000 align:start position:10%\nT:你好，欢迎收看。",
+		}},
+		{"sample.po", true, []string{
+			`"Plural-Forms: nplurals=2; plural=(n != 1);\n"`,
+			"#: main.go:10\nmsgid \"Hello, world\"\nmsgstr \"T:Hello, world\"",
+			// 写入译文后去掉fuzzy标记，保留其他标记和上下文
+			"#, c-format\nmsgctxt \"button\"\nmsgid \"Save %s\"\nmsgstr \"T:Save %s\"",
+			"msgid \"\"\n\"Multi-line \"\n\"message\\n\"\nmsgstr \"\"\n\"T:Multi-line message\\n\"",
+			"msgid_plural \"%d files\"\nmsgstr[0] \"T:One file\"\nmsgstr[1] \"T:%d files\"",
+			"#~ msgid \"Obsolete\"\n#~ msgstr \"已废弃\"",
+		}},
+		{"sample.xlf", true, []string{
+			`<file source-language="en" datatype="plaintext" original="messages" target-language="zh-CN">`,
+			`<source>Hello, <g id="1">world</g>!</source>
+        <target>T:Hello, <g id="1">world</g>!</target>`,
+			`<target state="needs-translation">T:Save &amp; exit</target>`,
+			`<trans-unit id="fixed" translate="no">
+        <source>WisTrans</source>
+      </trans-unit>`,
+		}},
+		{"sample.xliff", true, []string{
+			`srcLang="en" trgLang="zh-CN"`,
+			`<target>T:Click <pc id="1">here</pc> to continue.</target>`,
+			`<target>T:Thanks &lt;3</target>`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc, err := Parse(tt.file, data)
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			segments := doc.Segments()
+			translations := prefixed(segments)
+			out, err := doc.Assemble("zh-CN", translations)
+			if err != nil {
+				t.Fatalf("Assemble: %v", err)
+			}
+
+			// 重新解析组装结果：单语格式得到译文，双语格式得到原文
+			again, err := Parse(tt.file, out)
+			if err != nil {
+				t.Fatalf("Parse assembled file: %v\n%s", err, out)
+			}
+			want := make([]models.TranslateSegment, len(segments))
+			for i, segment := range segments {
+				want[i] = segment
+				if !tt.bilingual {
+					want[i].Text = translations[segment.ID]
+				}
+			}
+			if !reflect.DeepEqual(again.Segments(), want) {
+				t.Errorf("Segments after Assemble = %+v, want %+v", again.Segments(), want)
+			}
+
+			// 字幕的时间轴原样保留
+			for _, line := range strings.Split(string(data), "\n") {
+				if strings.Contains(line, "-->") && !strings.Contains(string(out), line) {
+					t.Errorf("timing %q was not preserved", line)
+				}
+			}
+			for _, part := range tt.contains {
+				if !strings.Contains(string(out), part) {
+					t.Errorf("Assemble result does not contain %q:\n%s", part, out)
+				}
+			}
+		})
+	}
+}
+
+// zipEntries 读取压缩包中的全部文件
+func zipEntries(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := make(map[string]string, len(reader.File))
+	for _, file := range reader.File {
+		content, err := readZipFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[file.Name] = string(content)
+	}
+	return entries
+}
+
+func TestAssembleDOCX(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "sample.docx"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := Parse("sample.docx", data)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	out, err := doc.Assemble("en", map[string]string{
+		"document/p1": "User manual",
+		// 开头的文字归入第一组，组后的文字归入前一组
+		"document/p2": "Note: <g1>this is </g1><g2>bold</g2><g3> text.\tAfter tab</g3> End",
+		"document/p3": "Line one\nLine two",
+		"header1/p1":  "Header",
+	})
+	if err != nil {
+		t.Fatalf("Assemble: %v", err)
+	}
+
+	again, err := Parse("sample.docx", out)
+	if err != nil {
+		t.Fatalf("Parse assembled file: %v", err)
+	}
+	want := []models.TranslateSegment{
+		{ID: "document/p1", Text: "User manual"},
+		{ID: "document/p2", Text: "<g1>Note: this is </g1><g2>bold</g2><g3> text.\tAfter tab End</g3>"},
+		{ID: "document/p3", Text: "Line one\nLine two"},
+		{ID: "header1/p1", Text: "Header"},
+	}
+	if !reflect.DeepEqual(again.Segments(), want) {
+		t.Errorf("Segments after Assemble = %+v, want %+v", again.Segments(), want)
+	}
+
+	// 段落样式、文字块格式、制表符和换行保留，其他部件原样复制
+	original, assembled := zipEntries(t, data), zipEntries(t, out)
+	document := assembled["word/document.xml"]
+	for _, part := range []string{
+		`<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r>`,
+		`<w:r><w:rPr><w:b/></w:rPr><w:t xml:space="preserve">bold</w:t></w:r>`,
+		`<w:tab/>`,
+		`<w:br/>`,
+		`<w:p/><w:sectPr/></w:body>`,
+	} {
+		if !strings.Contains(document, part) {
+			t.Errorf("word/document.xml does not contain %q:\n%s", part, document)
+		}
+	}
+	for name, content := range original {
+		if name != "word/document.xml" && name != "word/header1.xml" && assembled[name] != content {
+			t.Errorf("%s changed:\n%s", name, assembled[name])
+		}
+	}
+	if len(assembled) != len(original) {
+		t.Errorf("assembled file has %d entries, want %d", len(assembled), len(original))
+	}
+}
@@ -0,0 +1,326 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+var (
+	// docxPartPattern 需要翻译的DOCX部件：正文、页眉、页脚、脚注和尾注
+	docxPartPattern = regexp.MustCompile(`^word/(?:document|header\d*|footer\d*|footnotes|endnotes)\.xml$`)
+	// docxParagraphTagPattern 段落的开始和结束标签
+	docxParagraphTagPattern = regexp.MustCompile(`<w:p\b[^>]*?(/?)>|</w:p>`)
+	// docxRunPattern 文字块，第1组为内容
+	docxRunPattern = regexp.MustCompile(`(?s)<w:r\b[^>]*>(.*?)</w:r>`)
+	// docxRunPropsPattern 文字块的格式
+	docxRunPropsPattern = regexp.MustCompile(`(?s)<w:rPr\b.*?</w:rPr>`)
+	// docxTextPattern 文字元素，第1组为内容，自闭合时为空；没有属性的制表符和换行也作为文字
+	docxTextPattern = regexp.MustCompile(`(?s)<w:t\b[^>]*?(?:/>|>(.*?)</w:t>)|<w:tab\s*/>|<w:br\s*/>`)
+	// docxGroupPattern 译文中表示格式分组的标签
+	docxGroupPattern = regexp.MustCompile(`(?s)<g(\d+)>(.*?)</g(\d+)>`)
+)
+
+// docxEscaper 转义片段中的文字，引号不转义以便大模型翻译
+var docxEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// docxBreakReplacer 将译文中的制表符和换行写回为w:tab和w:br元素
+var docxBreakReplacer = strings.NewReplacer(
+	"\r\n", `</w:t><w:br/><w:t xml:space="preserve">`,
+	"\n", `</w:t><w:br/><w:t xml:space="preserve">`,
+	"\t", `</w:t><w:tab/><w:t xml:space="preserve">`,
+)
+
+// docxDocument 拆分后的DOCX文件，每个需要翻译的部件按位置替换
+type docxDocument struct {
+	data  []byte
+	parts map[string]*spanDocument // 部件名称到拆分结果
+}
+
+// docxText 段落中的一个文字元素
+type docxText struct {
+	start, end int    // 元素在段落中的位置
+	group      int    // 所属的格式分组，从1开始
+	text       string // 解码后的文字
+}
+
+// parseDOCX 拆分DOCX文件。正文、页眉、页脚、脚注和尾注中的每个段落作为一个片段，片段ID为部件名/p序号，例如document/p3。
+// 段落中格式不同的连续文字分为多组，用<g1>、<g2>等标签标记，翻译后每组译文写入该组的第一个文字元素，
+// 格式、图片、域代码和其他部件原样保留
+func parseDOCX(data []byte) (Document, error) {
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+
+	d := &docxDocument{data: data, parts: make(map[string]*spanDocument)}
+	for _, file := range reader.File {
+		if !docxPartPattern.MatchString(file.Name) {
+			continue
+		}
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file.Name, err)
+		}
+		d.parts[file.Name] = parseDOCXPart(strings.TrimSuffix(path.Base(file.Name), ".xml"), content)
+	}
+	if _, ok := d.parts["word/document.xml"]; !ok {
+		return nil, fmt.Errorf("缺少word/document.xml")
+	}
+	return d, nil
+}
+
+// parseDOCXPart 拆分一个XML部件中的段落。包含文本框等嵌套段落的外层段落不翻译，只翻译最内层的段落
+func parseDOCXPart(name string, content []byte) *spanDocument {
+	d := &spanDocument{data: content, format: formatHTML}
+	xml := string(content)
+
+	type open struct {
+		start  int
+		nested bool
+	}
+	var stack []open
+	for _, tag := range docxParagraphTagPattern.FindAllStringSubmatchIndex(xml, -1) {
+		switch {
+		case xml[tag[0]:tag[0]+2] == "</":
+			if len(stack) == 0 {
+				continue
+			}
+			p := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				stack[len(stack)-1].nested = true
+			}
+			if !p.nested {
+				addDOCXParagraph(d, name, xml, p.start, tag[1])
+			}
+		case tag[3] > tag[2]:
+			// 自闭合的空段落
+		default:
+			stack = append(stack, open{start: tag[0]})
+		}
+	}
+	return d
+}
+
+// addDOCXParagraph 将xml[start:end]中的段落添加为片段，没有文字的段落不添加
+func addDOCXParagraph(d *spanDocument, name, xml string, start, end int) {
+	paragraph := xml[start:end]
+
+	var texts []docxText
+	groups, props := 0, ""
+	for _, run := range docxRunPattern.FindAllStringSubmatchIndex(paragraph, -1) {
+		body := paragraph[run[2]:run[3]]
+		matches := docxTextPattern.FindAllStringSubmatchIndex(body, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		// 格式与前一个文字块不同时开始新的分组
+		if runProps := docxRunPropsPattern.FindString(body); groups == 0 || runProps != props {
+			groups, props = groups+1, runProps
+		}
+		for _, match := range matches {
+			text := ""
+			switch element := body[match[0]:match[1]]; {
+			case strings.HasPrefix(element, "<w:tab"):
+				text = "\t"
+			case strings.HasPrefix(element, "<w:br"):
+				text = "\n"
+			case match[2] >= 0:
+				text = html.UnescapeString(body[match[2]:match[3]])
+			}
+			texts = append(texts, docxText{start: run[2] + match[0], end: run[2] + match[1], group: groups, text: text})
+		}
+	}
+
+	// 各组的文字，只有一组时不加标签
+	grouped := make([]string, groups+1)
+	for _, t := range texts {
+		grouped[t.group] += t.text
+	}
+	var b strings.Builder
+	for group := 1; group <= groups; group++ {
+		if groups == 1 {
+			b.WriteString(docxEscaper.Replace(grouped[group]))
+			continue
+		}
+		if grouped[group] != "" {
+			fmt.Fprintf(&b, "<g%d>%s</g%d>", group, docxEscaper.Replace(grouped[group]), group)
+		}
+	}
+	if strings.TrimSpace(b.String()) == "" {
+		return
+	}
+
+	d.add(span{
+		start:   start,
+		end:     end,
+		segment: models.TranslateSegment{ID: fmt.Sprintf("%s/p%d", name, len(d.spans)+1), Text: b.String()},
+		inPlace: true,
+		encode: func(text string) string {
+			return assembleDOCXParagraph(paragraph, texts, splitDOCXGroups(text, groups))
+		},
+	})
+}
+
+// splitDOCXGroups 按<gN>标签将译文分到各组，标签外的文字归入前一组，开头的归入第一组
+func splitDOCXGroups(text string, groups int) []string {
+	result := make([]string, groups+1)
+	if groups == 1 {
+		result[1] = html.UnescapeString(text)
+		return result
+	}
+
+	last, current := 0, 1
+	for _, match := range docxGroupPattern.FindAllStringSubmatchIndex(text, -1) {
+		group, err := strconv.Atoi(text[match[2]:match[3]])
+		if err != nil || group < 1 || group > groups || text[match[2]:match[3]] != text[match[6]:match[7]] {
+			continue
+		}
+		result[current] += html.UnescapeString(text[last:match[0]])
+		result[group] += html.UnescapeString(text[match[4]:match[5]])
+		last, current = match[1], group
+	}
+	result[current] += html.UnescapeString(text[last:])
+	return result
+}
+
+// assembleDOCXParagraph 将各组译文写入该组的第一个文字元素，同组其他文字元素清空，原有的制表符和换行按译文重新生成
+func assembleDOCXParagraph(paragraph string, texts []docxText, groups []string) string {
+	var b strings.Builder
+	last := 0
+	written := make([]bool, len(groups))
+	for _, t := range texts {
+		b.WriteString(paragraph[last:t.start])
+		if written[t.group] {
+			b.WriteString("<w:t/>")
+		} else {
+			b.WriteString(`<w:t xml:space="preserve">` + docxBreakReplacer.Replace(docxEscaper.Replace(groups[t.group])) + "</w:t>")
+			written[t.group] = true
+		}
+		last = t.end
+	}
+	b.WriteString(paragraph[last:])
+	return b.String()
+}
+
+func (d *docxDocument) Format() string {
+	return formatHTML
+}
+
+func (d *docxDocument) Segments() []models.TranslateSegment {
+	var segments []models.TranslateSegment
+	for _, name := range d.partNames() {
+		segments = append(segments, d.parts[name].Segments()...)
+	}
+	return segments
+}
+
+// Assemble 重新组装DOCX文件。没有修改的部件按原始的压缩数据复制，所有部件都没有修改时返回原文件
+func (d *docxDocument) Assemble(target string, translations map[string]string) ([]byte, error) {
+	changed := make(map[string][]byte)
+	for name, part := range d.parts {
+		content, err := part.Assemble(target, translations)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(content, part.data) {
+			changed[name] = content
+		}
+	}
+	if len(changed) == 0 {
+		return d.data, nil
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(d.data), int64(len(d.data)))
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for _, file := range reader.File {
+		content, ok := changed[file.Name]
+		if !ok {
+			if err := copyZipFile(writer, file); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		// 压缩后的大小和校验和由zip.Writer重新计算
+		header := file.FileHeader
+		header.CRC32, header.CompressedSize, header.UncompressedSize = 0, 0, 0
+		header.CompressedSize64, header.UncompressedSize64 = 0, 0
+		w, err := writer.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// partNames 按正文、页眉、页脚、脚注、尾注的顺序返回部件名称，同类部件按编号排列
+func (d *docxDocument) partNames() []string {
+	rank := func(name string) (int, int) {
+		base := strings.TrimSuffix(path.Base(name), ".xml")
+		for i, prefix := range []string{"document", "header", "footer", "footnotes", "endnotes"} {
+			if strings.HasPrefix(base, prefix) {
+				number, _ := strconv.Atoi(strings.TrimPrefix(base, prefix))
+				return i, number
+			}
+		}
+		return 0, 0
+	}
+
+	names := make([]string, 0, len(d.parts))
+	for name := range d.parts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		ri, ni := rank(names[i])
+		rj, nj := rank(names[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return ni < nj
+	})
+	return names
+}
+
+// readZipFile 读取压缩包中的文件
+func readZipFile(file *zip.File) ([]byte, error) {
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// copyZipFile 按原始的压缩数据复制压缩包中的文件
+func copyZipFile(writer *zip.Writer, file *zip.File) error {
+	r, err := file.OpenRaw()
+	if err != nil {
+		return err
+	}
+	w, err := writer.CreateRaw(&file.FileHeader)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(w, r)
+	return err
+}
@@ -0,0 +1,195 @@
+package document
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// mdFencePattern 代码块的开始标记
+	mdFencePattern = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	// mdIndentedCodePattern 缩进代码块的行
+	mdIndentedCodePattern = regexp.MustCompile(`^(?: {4}|\t)`)
+	// mdRulePattern 分隔线和Setext标题的下划线
+	mdRulePattern = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,}|=+[ \t]*)$`)
+	// mdReferencePattern 链接引用定义
+	mdReferencePattern = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:\s*\S+`)
+	// mdTableSeparatorPattern 表格表头下的分隔行
+	mdTableSeparatorPattern = regexp.MustCompile(`^\s*\|?\s*:?-+:?\s*(?:\|\s*:?-+:?\s*)*\|?\s*$`)
+	// mdQuotePattern 引用的前缀
+	mdQuotePattern = regexp.MustCompile(`^(?: {0,3}>[ \t]?)+`)
+	// mdHeadingPattern ATX标题，第2组为标题文字，结尾的#不翻译
+	mdHeadingPattern = regexp.MustCompile(`^( {0,3}#{1,6}[ \t]+)(.*?)(?:[ \t]+#+)?[ \t]*$`)
+	// mdListPattern 列表项的标记和任务列表的复选框
+	mdListPattern = regexp.MustCompile(`^[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?`)
+)
+
+// mdParser Markdown的解析状态
+type mdParser struct {
+	d       *spanDocument
+	content string
+	encode  func(string) string
+	open    int  // 可以继续添加后续行的段落在spans中的序号，-1表示没有
+	inList  bool // 是否在列表中，列表中的缩进行不是代码块
+}
+
+// parseMarkdown 按块拆分Markdown：段落、列表项、引用中的行、标题和表格的单元格分别作为片段，
+// 代码块、Front Matter、HTML注释、分隔线和链接引用定义不翻译
+func parseMarkdown(data []byte) (Document, error) {
+	p := &mdParser{
+		d:       &spanDocument{data: data, format: formatMarkdown},
+		content: string(data),
+		encode:  withLineEnding(lineEnding(data)),
+		open:    -1,
+	}
+	lines := splitLines(p.content)
+
+	fence := ""
+	inCode, inTable, blank := false, false, true
+	for i := 0; i < len(lines); i++ {
+		text := lines[i].text
+		trimmed := strings.TrimSpace(text)
+
+		switch {
+		// Front Matter
+		case i == 0 && trimmed == "---":
+			for i++; i < len(lines); i++ {
+				if end := strings.TrimSpace(lines[i].text); end == "---" || end == "..." {
+					break
+				}
+			}
+			continue
+		// 代码块内的行原样保留，直到出现相同字符、长度不小于开始标记的结束标记
+		case fence != "":
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		case inCode && (trimmed == "" || mdIndentedCodePattern.MatchString(text)):
+			continue
+		}
+		inCode = false
+
+		if trimmed == "" {
+			p.open, inTable, blank = -1, false, true
+			continue
+		}
+		afterBlank := blank
+		blank = false
+		// 空行之后没有缩进的内容结束列表
+		if afterBlank && !strings.HasPrefix(text, " ") && !strings.HasPrefix(text, "\t") && !mdListPattern.MatchString(text) {
+			p.inList = false
+		}
+
+		if match := mdFencePattern.FindStringSubmatch(text); match != nil {
+			fence, p.open = match[1], -1
+			continue
+		}
+		if afterBlank && !p.inList && mdIndentedCodePattern.MatchString(text) {
+			inCode = true
+			continue
+		}
+		if strings.HasPrefix(trimmed, "<!--") {
+			for ; !strings.Contains(lines[i].text, "-->") && i+1 < len(lines); i++ {
+			}
+			p.open = -1
+			continue
+		}
+		if mdRulePattern.MatchString(text) || mdReferencePattern.MatchString(text) {
+			p.open = -1
+			continue
+		}
+
+		// 表格：表头的下一行是分隔行，之后包含竖线的行都是表格行
+		if !inTable && strings.Contains(text, "|") && i+1 < len(lines) && mdTableSeparatorPattern.MatchString(lines[i+1].text) {
+			inTable = true
+		}
+		if inTable {
+			if strings.Contains(text, "|") {
+				if !mdTableSeparatorPattern.MatchString(text) {
+					p.addCells(lines[i])
+				}
+				p.open = -1
+				continue
+			}
+			inTable = false
+		}
+
+		p.addLine(lines[i])
+	}
+	return p.d, nil
+}
+
+// addLine 处理段落中的一行：标题单独作为片段，列表项和引用中的行开始新的片段，其他行接在前一个段落之后
+func (p *mdParser) addLine(l line) {
+	offset := 0
+	quote := mdQuotePattern.FindString(l.text)
+	offset += len(quote)
+	rest := l.text[offset:]
+
+	if match := mdHeadingPattern.FindStringSubmatchIndex(rest); match != nil {
+		p.open = -1
+		p.add(l.start+offset+match[4], l.start+offset+match[5])
+		return
+	}
+
+	if marker := mdListPattern.FindString(rest); marker != "" {
+		p.inList, p.open = true, -1
+		offset += len(marker)
+	} else if p.open >= 0 && quote == "" {
+		// 段落的后续行接在前一个片段之后。引用中的每一行单独作为片段，避免译文中丢失行首的>
+		p.d.spans[p.open].end = l.start + len(strings.TrimRight(l.text, " \t"))
+		p.d.spans[p.open].segment = newSegment(p.d.spans[p.open].segment.ID, p.content[p.d.spans[p.open].start:p.d.spans[p.open].end])
+		return
+	}
+
+	start := l.start + offset
+	start += len(p.content[start:l.end]) - len(strings.TrimLeft(p.content[start:l.end], " \t"))
+	end := l.start + len(strings.TrimRight(l.text, " \t"))
+	if start >= end {
+		p.open = -1
+		return
+	}
+	p.add(start, end)
+	if quote == "" {
+		p.open = len(p.d.spans) - 1
+	} else {
+		p.open = -1
+	}
+}
+
+// addCells 将表格行中的每个非空单元格作为片段，反斜杠转义和行内代码中的竖线不作为分隔符
+func (p *mdParser) addCells(l line) {
+	cellStart, inCode := 0, false
+	for i := 0; i <= len(l.text); i++ {
+		if i < len(l.text) {
+			switch {
+			case l.text[i] == '\\':
+				i++
+				continue
+			case l.text[i] == '`':
+				inCode = !inCode
+				continue
+			case l.text[i] != '|' || inCode:
+				continue
+			}
+		}
+		cell := l.text[cellStart:i]
+		if trimmed := strings.TrimSpace(cell); trimmed != "" {
+			start := l.start + cellStart + strings.Index(cell, trimmed)
+			p.add(start, start+len(trimmed))
+		}
+		cellStart = i + 1
+	}
+}
+
+// add 添加原文位于[start, end)的片段
+func (p *mdParser) add(start, end int) {
+	p.d.add(span{
+		start:   start,
+		end:     end,
+		segment: newSegment("", p.content[start:end]),
+		inPlace: true,
+		encode:  p.encode,
+	})
+}
@@ -0,0 +1,197 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// poKeywordPattern PO条目中的关键字行，第1组为关键字，第2组为引号中的字符串
+var poKeywordPattern = regexp.MustCompile(`^(msgctxt|msgid_plural|msgid|msgstr(?:\[\d+\])?)\s+"(.*)"\s*$`)
+
+// poField PO条目中的一个字段，可能跨多行
+type poField struct {
+	keyword     string
+	value       string
+	first, last int // 字段所在的第一行和最后一行在条目中的序号
+}
+
+// parsePO 拆分gettext的PO或POT文件。每个条目的msgid作为一个片段，片段ID为m加上条目的序号；
+// 复数条目的msgid_plural作为ID为m序号.plural的片段，译文写入msgstr[1]及之后的复数形式。
+// 文件头和已废弃的条目不翻译，写入译文时去掉条目的fuzzy标记
+func parsePO(data []byte) (Document, error) {
+	d := &spanDocument{data: data, format: formatText}
+	content := string(data)
+	newline := lineEnding(data)
+	lines := splitLines(content)
+
+	entry := 0
+	for i := 0; i < len(lines); {
+		if strings.TrimSpace(lines[i].text) == "" {
+			i++
+			continue
+		}
+		start := i
+		for i < len(lines) && strings.TrimSpace(lines[i].text) != "" {
+			i++
+		}
+		block := lines[start:i]
+
+		fields, flags, err := parsePOEntry(block)
+		if err != nil {
+			return nil, fmt.Errorf("第%d行: %v", start+1, err)
+		}
+		msgid, ok := fields["msgid"]
+		if !ok || msgid.value == "" {
+			// 没有msgid的块只有注释或是已废弃的条目，msgid为空的是文件头
+			continue
+		}
+		entry++
+		id := fmt.Sprintf("m%d", entry)
+
+		if flags >= 0 && strings.Contains(block[flags].text, "fuzzy") {
+			// 标记行之后一定有msgid，替换范围包含换行符，没有其他标记时删除整行
+			l := block[flags]
+			d.add(span{
+				start:   l.start,
+				end:     block[flags+1].start,
+				segment: newSegment(id, ""),
+				edit:    true,
+				encode: func(string) string {
+					if flags := removeFuzzy(l.text); flags != "" {
+						return flags + newline
+					}
+					return ""
+				},
+			})
+		}
+
+		if plural, ok := fields["msgid_plural"]; ok {
+			first, ok := fields["msgstr[0]"]
+			if !ok {
+				return nil, fmt.Errorf("第%d行: 复数条目缺少msgstr[0]", start+1)
+			}
+			d.add(span{start: block[first.first].start, end: block[first.last].end, segment: newSegment(id, msgid.value), encode: poStrings(newline, "msgstr[0]")})
+
+			var keywords []string
+			for n := 1; ; n++ {
+				keyword := fmt.Sprintf("msgstr[%d]", n)
+				if _, ok := fields[keyword]; !ok {
+					break
+				}
+				keywords = append(keywords, keyword)
+			}
+			if len(keywords) > 0 {
+				from, to := fields[keywords[0]], fields[keywords[len(keywords)-1]]
+				d.add(span{start: block[from.first].start, end: block[to.last].end, segment: newSegment(id+".plural", plural.value), encode: poStrings(newline, keywords...)})
+			}
+			continue
+		}
+
+		msgstr, ok := fields["msgstr"]
+		if !ok {
+			return nil, fmt.Errorf("第%d行: 条目缺少msgstr", start+1)
+		}
+		d.add(span{start: block[msgstr.first].start, end: block[msgstr.last].end, segment: newSegment(id, msgid.value), encode: poStrings(newline, "msgstr")})
+	}
+	return d, nil
+}
+
+// parsePOEntry 解析一个条目中的字段，返回字段和#,标记行的序号（没有时为-1）。已废弃的条目（#~）没有字段
+func parsePOEntry(block []line) (map[string]poField, int, error) {
+	fields := make(map[string]poField)
+	flags := -1
+	var current *poField
+	for i, l := range block {
+		text := strings.TrimSpace(l.text)
+		switch {
+		case strings.HasPrefix(text, "#,"):
+			flags = i
+			current = nil
+		case strings.HasPrefix(text, "#"):
+			current = nil
+		case strings.HasPrefix(text, `"`):
+			if current == nil || !strings.HasSuffix(text, `"`) || len(text) < 2 {
+				return nil, -1, fmt.Errorf("无效的字符串: %s", text)
+			}
+			current.value += poUnescape(text[1 : len(text)-1])
+			current.last = i
+			fields[current.keyword] = *current
+		default:
+			match := poKeywordPattern.FindStringSubmatch(text)
+			if match == nil {
+				return nil, -1, fmt.Errorf("无法识别的内容: %s", text)
+			}
+			current = &poField{keyword: match[1], value: poUnescape(match[2]), first: i, last: i}
+			fields[current.keyword] = *current
+		}
+	}
+	return fields, flags, nil
+}
+
+// poUnescape 解析C风格的转义字符
+func poUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// poQuote 将字符串转换为PO中带引号的字符串
+func poQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s) + `"`
+}
+
+// poStrings 返回将译文写为一个或多个msgstr字段的函数。包含换行的译文按行拆分为多行字符串
+func poStrings(newline string, keywords ...string) func(string) string {
+	return func(text string) string {
+		value := poQuote(text)
+		if lines := strings.SplitAfter(text, "\n"); len(lines) > 1 {
+			var quoted []string
+			for _, l := range lines {
+				if l != "" {
+					quoted = append(quoted, poQuote(l))
+				}
+			}
+			value = `""` + newline + strings.Join(quoted, newline)
+		}
+
+		fields := make([]string, 0, len(keywords))
+		for _, keyword := range keywords {
+			fields = append(fields, keyword+" "+value)
+		}
+		return strings.Join(fields, newline)
+	}
+}
+
+// removeFuzzy 从#,标记行中去掉fuzzy，没有其他标记时返回空字符串
+func removeFuzzy(flags string) string {
+	var kept []string
+	for _, flag := range strings.Split(strings.TrimPrefix(strings.TrimSpace(flags), "#,"), ",") {
+		if flag = strings.TrimSpace(flag); flag != "" && flag != "fuzzy" {
+			kept = append(kept, flag)
+		}
+	}
+	if len(kept) == 0 {
+		return ""
+	}
+	return "#, " + strings.Join(kept, ", ")
+}
@@ -0,0 +1,90 @@
+package document
+
+import (
+	"fmt"
+	"strings"
+)
+
+// parseSRT 拆分SRT字幕，每条字幕的文字作为一个片段，序号和时间轴原样保留
+func parseSRT(data []byte) (Document, error) {
+	return parseSubtitles(data, false)
+}
+
+// parseVTT 拆分WebVTT字幕，每条字幕的文字作为一个片段，文件头、时间轴、设置和NOTE、STYLE、REGION块原样保留
+func parseVTT(data []byte) (Document, error) {
+	if !strings.HasPrefix(strings.TrimPrefix(string(data), "\ufeff"), "WEBVTT") {
+		return nil, fmt.Errorf("文件不是以WEBVTT开头")
+	}
+	return parseSubtitles(data, true)
+}
+
+// parseSubtitles 按空行将字幕分为多条，时间轴之后的行为字幕文字。片段ID为cue-加上字幕的序号或标识，
+// 字幕文字中的<i>等标签在翻译时保护
+func parseSubtitles(data []byte, vtt bool) (Document, error) {
+	d := &spanDocument{data: data, format: formatHTML}
+	content := string(data)
+	newline := lineEnding(data)
+	lines := splitLines(content)
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(lines); {
+		// 取出一条字幕的全部行
+		if strings.TrimSpace(lines[i].text) == "" {
+			i++
+			continue
+		}
+		start := i
+		for i < len(lines) && strings.TrimSpace(lines[i].text) != "" {
+			i++
+		}
+		block := lines[start:i]
+
+		if vtt {
+			header := strings.TrimPrefix(block[0].text, "\ufeff")
+			if start == 0 || strings.HasPrefix(header, "NOTE") || strings.HasPrefix(header, "STYLE") || strings.HasPrefix(header, "REGION") {
+				continue
+			}
+		}
+
+		timing := -1
+		for j, l := range block {
+			if strings.Contains(l.text, "-->") {
+				timing = j
+				break
+			}
+		}
+		if timing < 0 {
+			return nil, fmt.Errorf("第%d行: 字幕缺少时间轴", start+1)
+		}
+		if timing+1 >= len(block) {
+			continue
+		}
+
+		name := fmt.Sprintf("%d", len(d.spans)+1)
+		if timing > 0 {
+			name = strings.TrimSpace(strings.TrimPrefix(block[0].text, "\ufeff"))
+		}
+		first, last := block[timing+1], block[len(block)-1]
+		d.add(span{
+			start:   first.start,
+			end:     last.end,
+			segment: newSegment(uniqueID(seen, "cue-"+name), content[first.start:last.end]),
+			inPlace: true,
+			encode:  subtitleText(newline),
+		})
+	}
+	return d, nil
+}
+
+// subtitleText 去掉译文中的空行，空行会结束一条字幕
+func subtitleText(newline string) func(string) string {
+	return func(text string) string {
+		var lines []string
+		for _, l := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+			if strings.TrimSpace(l) != "" {
+				lines = append(lines, l)
+			}
+		}
+		return strings.Join(lines, newline)
+	}
+}
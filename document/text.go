@@ -0,0 +1,38 @@
+package document
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// paragraphSeparator 段落之间的空行
+var paragraphSeparator = regexp.MustCompile(`\r?\n[ \t]*\r?\n`)
+
+// parseText 按空行将纯文本或HTML文件分为段落，每段作为一个片段，片段ID依次为p1、p2……
+func parseText(format string) parser {
+	return func(data []byte) (Document, error) {
+		d := &spanDocument{data: data, format: format}
+		encode := withLineEnding(lineEnding(data))
+		content := string(data)
+
+		start := 0
+		bounds := append(paragraphSeparator.FindAllStringIndex(content, -1), []int{len(content), len(content)})
+		for _, bound := range bounds {
+			paragraph := content[start:bound[0]]
+			trimmed := strings.TrimSpace(paragraph)
+			if trimmed != "" {
+				offset := start + strings.Index(paragraph, trimmed)
+				d.add(span{
+					start:   offset,
+					end:     offset + len(trimmed),
+					segment: newSegment(fmt.Sprintf("p%d", len(d.spans)+1), trimmed),
+					inPlace: true,
+					encode:  encode,
+				})
+			}
+			start = bound[1]
+		}
+		return d, nil
+	}
+}
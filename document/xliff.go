@@ -0,0 +1,158 @@
+package document
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// xliffVersionPattern XLIFF 2.x的版本号或命名空间
+	xliffVersionPattern = regexp.MustCompile(`<xliff\b[^>]*(?:version\s*=\s*["']2\.|urn:oasis:names:tc:xliff:document:2\.)`)
+	// xliffUnitPattern XLIFF 1.2的翻译单元
+	xliffUnitPattern = regexp.MustCompile(`(?s)<trans-unit\b([^>]*)>(.*?)</trans-unit\s*>`)
+	// xliff2UnitPattern XLIFF 2.x的单元
+	xliff2UnitPattern = regexp.MustCompile(`(?s)<unit\b([^>]*)>(.*?)</unit\s*>`)
+	// xliff2SegmentPattern XLIFF 2.x单元中的句段，ignorable不翻译
+	xliff2SegmentPattern = regexp.MustCompile(`(?s)<segment\b([^>]*)>(.*?)</segment\s*>`)
+	// xliffAltTransPattern XLIFF 1.2中的候选译文，其中的source和target不是翻译单元的原文和译文
+	xliffAltTransPattern = regexp.MustCompile(`(?s)<alt-trans\b.*?</alt-trans\s*>`)
+	// xliffSourcePattern 原文元素，第1组为内容
+	xliffSourcePattern = regexp.MustCompile(`(?s)<source\b(?:[^>]*[^/])?>(.*?)</source\s*>`)
+	// xliffTargetPattern 译文元素，第1组为属性，第2组为内容，自闭合时没有第2组
+	xliffTargetPattern = regexp.MustCompile(`(?s)<target\b([^>]*?)(?:/>|>(.*?)</target\s*>)`)
+	// xmlAttrPattern XML属性
+	xmlAttrPattern = regexp.MustCompile(`([A-Za-z_:][-A-Za-z0-9_:.]*)\s*=\s*(?:"([^"]*)"|'([^']*)')`)
+	// xmlEntityPattern XML实体和字符引用
+	xmlEntityPattern = regexp.MustCompile(`^&(?:[A-Za-z][A-Za-z0-9]*|#\d+|#[xX][0-9A-Fa-f]+);`)
+	// xmlMarkupPattern 标签、注释和CDATA的开头
+	xmlMarkupPattern = regexp.MustCompile(`^<(?:/?[A-Za-z_]|!--|!\[CDATA\[)`)
+	// xliffFilePattern XLIFF 1.2的file元素
+	xliffFilePattern = regexp.MustCompile(`<file\b[^>]*>`)
+	// xliffRootPattern XLIFF 2.x的根元素
+	xliffRootPattern = regexp.MustCompile(`<xliff\b[^>]*>`)
+)
+
+// parseXLIFF 拆分XLIFF 1.2或2.x文件。每个翻译单元（2.x为每个segment）的原文作为一个片段，片段ID为单元的id，
+// 原文中的内联标签在翻译时保护。译文写入target元素，没有target时在source之后添加；translate="no"的单元不翻译
+func parseXLIFF(data []byte) (Document, error) {
+	content := string(data)
+	if !strings.Contains(content, "<xliff") {
+		return nil, fmt.Errorf("缺少xliff根元素")
+	}
+
+	d := &spanDocument{data: data, format: formatHTML}
+	seen := make(map[string]bool)
+	if xliffVersionPattern.MatchString(content) {
+		d.target = setRootAttr(xliffRootPattern, "trgLang")
+		for _, unit := range xliff2UnitPattern.FindAllStringSubmatchIndex(content, -1) {
+			attrs := xmlAttrs(content[unit[2]:unit[3]])
+			if attrs["translate"] == "no" {
+				continue
+			}
+			segments := xliff2SegmentPattern.FindAllStringSubmatchIndex(content[unit[4]:unit[5]], -1)
+			for i, segment := range segments {
+				id := attrs["id"]
+				if len(segments) > 1 {
+					name := xmlAttrs(content[unit[4]+segment[2] : unit[4]+segment[3]])["id"]
+					if name == "" {
+						name = fmt.Sprintf("%d", i+1)
+					}
+					id += "/" + name
+				}
+				addXLIFFUnit(d, content, unit[4]+segment[4], unit[4]+segment[5], uniqueID(seen, id))
+			}
+		}
+		return d, nil
+	}
+
+	d.target = setRootAttr(xliffFilePattern, "target-language")
+	for _, unit := range xliffUnitPattern.FindAllStringSubmatchIndex(content, -1) {
+		attrs := xmlAttrs(content[unit[2]:unit[3]])
+		if attrs["translate"] == "no" {
+			continue
+		}
+		addXLIFFUnit(d, content, unit[4], unit[5], uniqueID(seen, attrs["id"]))
+	}
+	return d, nil
+}
+
+// addXLIFFUnit 在content[start:end]中查找原文和译文，添加一个片段。原文为空时不添加
+func addXLIFFUnit(d *spanDocument, content string, start, end int, id string) {
+	// 忽略候选译文中的source和target，替换为等长的空格以保持位置不变
+	body := xliffAltTransPattern.ReplaceAllStringFunc(content[start:end], func(match string) string {
+		return strings.Repeat(" ", len(match))
+	})
+
+	source := xliffSourcePattern.FindStringSubmatchIndex(body)
+	if source == nil || strings.TrimSpace(body[source[2]:source[3]]) == "" {
+		return
+	}
+	s := span{segment: newSegment(id, body[source[2]:source[3]]), encode: xmlText}
+
+	target := xliffTargetPattern.FindStringSubmatchIndex(body)
+	switch {
+	case target != nil && target[4] >= 0:
+		s.start, s.end = start+target[4], start+target[5]
+	case target != nil:
+		// 自闭合的target替换为带内容的元素
+		attrs := body[target[2]:target[3]]
+		s.start, s.end = start+target[0], start+target[1]
+		s.encode = func(text string) string {
+			return "<target" + attrs + ">" + xmlText(text) + "</target>"
+		}
+	default:
+		// 在source之后添加target，缩进与source相同
+		s.start, s.end = start+source[1], start+source[1]
+		indent := ""
+		if lineStart := strings.LastIndexByte(body[:source[0]], '\n'); lineStart >= 0 && strings.TrimSpace(body[lineStart:source[0]]) == "" {
+			indent = body[lineStart:source[0]]
+		}
+		s.encode = func(text string) string {
+			return indent + "<target>" + xmlText(text) + "</target>"
+		}
+	}
+	d.add(s)
+}
+
+// xmlAttrs 解析标签中的属性
+func xmlAttrs(tag string) map[string]string {
+	attrs := make(map[string]string)
+	for _, match := range xmlAttrPattern.FindAllStringSubmatch(tag, -1) {
+		attrs[match[1]] = match[2] + match[3]
+	}
+	return attrs
+}
+
+// xmlText 转义译文中不属于标签或实体的&和<，保证写入的内容是合法的XML
+func xmlText(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '&' && !xmlEntityPattern.MatchString(text[i:]):
+			b.WriteString("&amp;")
+		case text[i] == '<' && !xmlMarkupPattern.MatchString(text[i:]):
+			b.WriteString("&lt;")
+		default:
+			b.WriteByte(text[i])
+		}
+	}
+	return b.String()
+}
+
+// setRootAttr 返回为匹配的元素添加目标语言属性的函数，元素已有该属性时不修改
+func setRootAttr(pattern *regexp.Regexp, name string) func([]byte, string) []byte {
+	return func(data []byte, target string) []byte {
+		return pattern.ReplaceAllFunc(data, func(tag []byte) []byte {
+			if _, ok := xmlAttrs(string(tag))[name]; ok {
+				return tag
+			}
+			end := len(tag) - 1
+			if tag[end-1] == '/' {
+				end--
+			}
+			return []byte(string(tag[:end]) + fmt.Sprintf(` %s="%s"`, name, html.EscapeString(target)) + string(tag[end:]))
+		})
+	}
+}
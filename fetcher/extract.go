@@ -0,0 +1,643 @@
+package fetcher
+
+import (
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// Article 从网页标记中确定性提取的文章信息
+type Article struct {
+	Title        string   `json:"title,omitempty"`         // 标题
+	Description  string   `json:"description,omitempty"`   // 描述
+	Author       string   `json:"author,omitempty"`        // 作者
+	PublishDate  string   `json:"publish_date,omitempty"`  // 发布日期
+	ModifiedDate string   `json:"modified_date,omitempty"` // 修改日期
+	Category     string   `json:"category,omitempty"`      // 分类
+	Tags         []string `json:"tags,omitempty"`          // 标签
+	SiteName     string   `json:"site_name,omitempty"`     // 站点名称
+	CanonicalURL string   `json:"canonical_url,omitempty"` // 规范URL
+	Image        string   `json:"image,omitempty"`         // 封面图片
+	Language     string   `json:"language,omitempty"`      // 页面声明或检测到的语言
+	Content      string   `json:"content,omitempty"`       // 正文内容
+}
+
+// Field 根据字段名获取提取结果，字段名与models.GetDefaultExtractFields一致
+func (a *Article) Field(name string) (interface{}, bool) {
+	var value string
+	switch name {
+	case "title":
+		value = a.Title
+	case "content":
+		value = a.Content
+	case "summary", "description":
+		value = a.Description
+	case "author":
+		value = a.Author
+	case "publish_date":
+		value = a.PublishDate
+	case "modified_date":
+		value = a.ModifiedDate
+	case "category":
+		value = a.Category
+	case "site_name":
+		value = a.SiteName
+	case "canonical_url":
+		value = a.CanonicalURL
+	case "image":
+		value = a.Image
+	case "language":
+		value = a.Language
+	case "tags":
+		if len(a.Tags) == 0 {
+			return nil, false
+		}
+		return a.Tags, true
+	default:
+		return nil, false
+	}
+	return value, value != ""
+}
+
+// ExtractArticle 从HTML中提取元数据和正文，pageURL用于解析相对链接
+func ExtractArticle(document, pageURL string) (*Article, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return nil, err
+	}
+	return extractArticle(root, pageURL), nil
+}
+
+// extractArticle 按 JSON-LD > OpenGraph/Twitter > 普通meta > 页面元素 的优先级合并元数据
+func extractArticle(root *html.Node, pageURL string) *Article {
+	article := &Article{}
+	meta := collectMeta(root)
+
+	ld := extractJSONLD(root)
+	article.Title = firstNonEmpty(ld.Title, meta["og:title"], meta["twitter:title"], documentTitle(root), firstHeading(root))
+	article.Description = firstNonEmpty(ld.Description, meta["og:description"], meta["twitter:description"], meta["description"])
+	article.Author = firstNonEmpty(ld.Author, meta["author"], meta["article:author"], meta["byl"], meta["dc.creator"], meta["twitter:creator"])
+	article.PublishDate = firstNonEmpty(ld.PublishDate, meta["article:published_time"], meta["og:published_time"], meta["pubdate"],
+		meta["publishdate"], meta["dc.date"], meta["date"], firstTimeElement(root))
+	article.ModifiedDate = firstNonEmpty(ld.ModifiedDate, meta["article:modified_time"], meta["og:updated_time"])
+	article.Category = firstNonEmpty(ld.Category, meta["article:section"], meta["category"])
+	article.SiteName = firstNonEmpty(meta["og:site_name"], ld.SiteName, meta["application-name"])
+	article.Image = resolveURL(pageURL, firstNonEmpty(ld.Image, meta["og:image"], meta["twitter:image"]))
+	article.CanonicalURL = resolveURL(pageURL, firstNonEmpty(canonicalLink(root), meta["og:url"], ld.URL))
+	article.Language = NormalizeLanguage(firstNonEmpty(documentLanguage(root), meta["og:locale"], meta["content-language"]))
+
+	article.Tags = ld.Tags
+	if len(article.Tags) == 0 {
+		article.Tags = meta.all("article:tag")
+	}
+	if len(article.Tags) == 0 && meta["keywords"] != "" {
+		article.Tags = splitKeywords(meta["keywords"])
+	}
+
+	if body := findMainContent(root); body != nil {
+		article.Content = NodeText(body)
+	}
+	if article.Language == "" {
+		article.Language = DetectLanguage(article.Content)
+	}
+
+	return article
+}
+
+// metaValues meta标签集合，键为小写的name/property
+type metaValues map[string]string
+
+// all 获取可重复出现的meta值，按出现顺序存储为 key#0, key#1 ...
+func (m metaValues) all(key string) []string {
+	var values []string
+	for i := 0; ; i++ {
+		v, ok := m[key+"#"+strconv.Itoa(i)]
+		if !ok {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// collectMeta 收集所有meta标签
+func collectMeta(root *html.Node) metaValues {
+	meta := metaValues{}
+	counts := map[string]int{}
+	walkElements(root, func(n *html.Node) bool {
+		if n.DataAtom != atom.Meta {
+			return true
+		}
+		key := strings.ToLower(firstNonEmpty(attr(n, "property"), attr(n, "name"), attr(n, "itemprop"), attr(n, "http-equiv")))
+		value := strings.TrimSpace(attr(n, "content"))
+		if key == "" || value == "" {
+			return true
+		}
+		if _, exists := meta[key]; !exists {
+			meta[key] = value
+		}
+		meta[key+"#"+strconv.Itoa(counts[key])] = value
+		counts[key]++
+		return true
+	})
+	return meta
+}
+
+// jsonLDArticle 从JSON-LD中提取的文章字段
+type jsonLDArticle struct {
+	Title        string
+	Description  string
+	Author       string
+	PublishDate  string
+	ModifiedDate string
+	Category     string
+	SiteName     string
+	Image        string
+	URL          string
+	Tags         []string
+}
+
+// articleTypes 视为文章的JSON-LD类型
+var articleTypes = map[string]bool{
+	"article":              true,
+	"newsarticle":          true,
+	"blogposting":          true,
+	"reportagenewsarticle": true,
+	"analysisnewsarticle":  true,
+	"techarticle":          true,
+	"scholarlyarticle":     true,
+}
+
+// extractJSONLD 解析application/ld+json中的Article/NewsArticle等对象
+func extractJSONLD(root *html.Node) jsonLDArticle {
+	var result jsonLDArticle
+	found := false
+	walkElements(root, func(n *html.Node) bool {
+		if found {
+			return false
+		}
+		if n.DataAtom != atom.Script || !strings.EqualFold(attr(n, "type"), "application/ld+json") || n.FirstChild == nil {
+			return true
+		}
+		var data interface{}
+		if err := json.Unmarshal([]byte(n.FirstChild.Data), &data); err != nil {
+			return true
+		}
+		for _, obj := range flattenJSONLD(data) {
+			if !isArticleType(obj["@type"]) {
+				continue
+			}
+			result = jsonLDArticle{
+				Title:        firstNonEmpty(jsonString(obj["headline"]), jsonString(obj["name"])),
+				Description:  jsonString(obj["description"]),
+				Author:       jsonNames(obj["author"]),
+				PublishDate:  jsonString(obj["datePublished"]),
+				ModifiedDate: jsonString(obj["dateModified"]),
+				Category:     firstNonEmpty(jsonStrings(obj["articleSection"])...),
+				SiteName:     jsonNames(obj["publisher"]),
+				Image:        jsonURL(obj["image"]),
+				URL:          firstNonEmpty(jsonURL(obj["mainEntityOfPage"]), jsonString(obj["url"])),
+				Tags:         jsonKeywords(obj["keywords"]),
+			}
+			found = true
+			return false
+		}
+		return true
+	})
+	return result
+}
+
+// flattenJSONLD 展开数组和@graph中的对象
+func flattenJSONLD(data interface{}) []map[string]interface{} {
+	var objects []map[string]interface{}
+	switch v := data.(type) {
+	case []interface{}:
+		for _, item := range v {
+			objects = append(objects, flattenJSONLD(item)...)
+		}
+	case map[string]interface{}:
+		objects = append(objects, v)
+		if graph, ok := v["@graph"]; ok {
+			objects = append(objects, flattenJSONLD(graph)...)
+		}
+	}
+	return objects
+}
+
+// isArticleType 判断@type是否为文章类型，@type可能是字符串或数组
+func isArticleType(t interface{}) bool {
+	for _, name := range jsonStrings(t) {
+		if articleTypes[strings.ToLower(name)] {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonString 获取JSON值中的字符串
+func jsonString(v interface{}) string {
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	return ""
+}
+
+// jsonStrings 获取字符串或字符串数组
+func jsonStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		if s := strings.TrimSpace(t); s != "" {
+			return []string{s}
+		}
+	case []interface{}:
+		var values []string
+		for _, item := range t {
+			values = append(values, jsonStrings(item)...)
+		}
+		return values
+	}
+	return nil
+}
+
+// jsonNames 获取人物/组织的名称，支持字符串、对象和数组
+func jsonNames(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]interface{}:
+		return jsonString(t["name"])
+	case []interface{}:
+		var names []string
+		for _, item := range t {
+			if name := jsonNames(item); name != "" {
+				names = append(names, name)
+			}
+		}
+		return strings.Join(names, ", ")
+	}
+	return ""
+}
+
+// jsonURL 获取URL，支持字符串、ImageObject/WebPage对象和数组
+func jsonURL(v interface{}) string {
+	switch t := v.(type) {
+	case string:
+		return strings.TrimSpace(t)
+	case map[string]interface{}:
+		return firstNonEmpty(jsonString(t["url"]), jsonString(t["@id"]))
+	case []interface{}:
+		for _, item := range t {
+			if u := jsonURL(item); u != "" {
+				return u
+			}
+		}
+	}
+	return ""
+}
+
+// jsonKeywords 获取关键词，支持逗号分隔的字符串和数组
+func jsonKeywords(v interface{}) []string {
+	if s, ok := v.(string); ok {
+		return splitKeywords(s)
+	}
+	return jsonStrings(v)
+}
+
+// splitKeywords 拆分逗号分隔的关键词
+func splitKeywords(s string) []string {
+	var tags []string
+	for _, tag := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == '，' || r == ';' || r == '、' }) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// documentTitle 获取<title>内容
+func documentTitle(root *html.Node) string {
+	var title string
+	walkElements(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Title {
+			title = strings.TrimSpace(textContent(n))
+			return false
+		}
+		return n.DataAtom != atom.Body
+	})
+	return title
+}
+
+// firstHeading 获取第一个h1内容
+func firstHeading(root *html.Node) string {
+	var heading string
+	walkElements(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.H1 {
+			heading = strings.Join(strings.Fields(textContent(n)), " ")
+			return false
+		}
+		return true
+	})
+	return heading
+}
+
+// firstTimeElement 获取第一个<time datetime>的值
+func firstTimeElement(root *html.Node) string {
+	var value string
+	walkElements(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Time {
+			if dt := strings.TrimSpace(attr(n, "datetime")); dt != "" {
+				value = dt
+				return false
+			}
+		}
+		return true
+	})
+	return value
+}
+
+// canonicalLink 获取<link rel="canonical">
+func canonicalLink(root *html.Node) string {
+	var href string
+	walkElements(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Link && strings.EqualFold(attr(n, "rel"), "canonical") {
+			href = strings.TrimSpace(attr(n, "href"))
+			return false
+		}
+		return true
+	})
+	return href
+}
+
+// documentLanguage 获取<html lang>
+func documentLanguage(root *html.Node) string {
+	var lang string
+	walkElements(root, func(n *html.Node) bool {
+		if n.DataAtom == atom.Html {
+			lang = strings.TrimSpace(attr(n, "lang"))
+			return false
+		}
+		return true
+	})
+	return lang
+}
+
+// NormalizeLanguage 将zh-CN、en_US等语言标记规范化为主语言代码
+func NormalizeLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i > 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// DetectLanguage 根据字符分布粗略判断文本是中文还是英文
+func DetectLanguage(text string) string {
+	var han, latin int
+	for _, r := range text {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			han++
+		case r < utf8.RuneSelf && unicode.IsLetter(r):
+			latin++
+		}
+	}
+	switch {
+	case han == 0 && latin == 0:
+		return ""
+	case han*3 >= latin:
+		return "zh"
+	default:
+		return "en"
+	}
+}
+
+// resolveURL 将相对链接解析为绝对链接
+func resolveURL(base, ref string) string {
+	if ref == "" || base == "" {
+		return ref
+	}
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return ref
+	}
+	refURL, err := url.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return baseURL.ResolveReference(refURL).String()
+}
+
+var (
+	// unlikelyCandidates 通常不是正文的class/id
+	unlikelyCandidates = regexp.MustCompile(`(?i)banner|breadcrumb|combx|comment|community|cover-wrap|disqus|extra|footer|gdpr|header|legends|menu|related|remark|replies|rss|shoutbox|sidebar|skyscraper|social|sponsor|ad-break|agegate|pagination|pager|popup|share|nav|copyright`)
+	// maybeCandidates 即使命中unlikely也可能是正文的class/id
+	maybeCandidates = regexp.MustCompile(`(?i)and|article|body|column|content|main|shadow|text`)
+	// positiveNames 正文倾向的class/id
+	positiveNames = regexp.MustCompile(`(?i)article|body|content|entry|hentry|h-entry|main|page|pagination|post|text|blog|story|detail`)
+	// negativeNames 非正文倾向的class/id
+	negativeNames = regexp.MustCompile(`(?i)-ad-|hidden|^hid$| hid$| hid |^hid |banner|combx|comment|com-|contact|foot|footer|footnote|gdpr|masthead|media|meta|outbrain|promo|related|scroll|share|shoutbox|sidebar|skyscraper|sponsor|shopping|tags|tool|widget|nav|copyright`)
+)
+
+// findMainContent 使用Readability风格的打分算法定位正文节点
+func findMainContent(root *html.Node) *html.Node {
+	body := findFirst(root, atom.Body)
+	if body == nil {
+		return root
+	}
+
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node
+	addScore := func(n *html.Node, score float64) {
+		if n == nil || n.Type != html.ElementNode {
+			return
+		}
+		if _, ok := scores[n]; !ok {
+			scores[n] = initialScore(n)
+			candidates = append(candidates, n)
+		}
+		scores[n] += score
+	}
+
+	walkElements(body, func(n *html.Node) bool {
+		if skippedElements[n.DataAtom] || isHidden(n) {
+			return false
+		}
+		if n != body && isUnlikely(n) {
+			return false
+		}
+		if n.DataAtom != atom.P && n.DataAtom != atom.Pre && n.DataAtom != atom.Td && n.DataAtom != atom.Blockquote {
+			return true
+		}
+		text := strings.TrimSpace(textContent(n))
+		length := utf8.RuneCountInString(text)
+		if length < 25 {
+			return false
+		}
+		// 基础分 + 逗号数量 + 每100字加1分（最多3分）
+		score := 1 + float64(strings.Count(text, ",")+strings.Count(text, "，")+strings.Count(text, "。"))
+		score += minFloat(float64(length)/100, 3)
+		addScore(n.Parent, score)
+		if n.Parent != nil {
+			addScore(n.Parent.Parent, score/2)
+		}
+		return false
+	})
+
+	if len(candidates) == 0 {
+		return body
+	}
+
+	for _, n := range candidates {
+		scores[n] *= 1 - linkDensity(n)
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return scores[candidates[i]] > scores[candidates[j]] })
+
+	top := candidates[0]
+	// 正文过短时退回到整个body，避免只取到一个小段落
+	if utf8.RuneCountInString(textContent(top)) < 50 {
+		return body
+	}
+	return top
+}
+
+// initialScore 根据标签和class/id给出初始分数
+func initialScore(n *html.Node) float64 {
+	var score float64
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score = 10
+	case atom.Div:
+		score = 5
+	case atom.Pre, atom.Td, atom.Blockquote, atom.Section:
+		score = 3
+	case atom.Address, atom.Ol, atom.Ul, atom.Dl, atom.Dd, atom.Dt, atom.Li, atom.Form:
+		score = -3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score = -5
+	}
+	return score + classWeight(n)
+}
+
+// classWeight 根据class和id计算权重
+func classWeight(n *html.Node) float64 {
+	var weight float64
+	for _, name := range []string{attr(n, "class"), attr(n, "id")} {
+		if name == "" {
+			continue
+		}
+		if negativeNames.MatchString(name) {
+			weight -= 25
+		}
+		if positiveNames.MatchString(name) {
+			weight += 25
+		}
+	}
+	return weight
+}
+
+// isUnlikely 判断节点是否不太可能包含正文
+func isUnlikely(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Nav, atom.Footer, atom.Aside:
+		return true
+	case atom.Article, atom.Main, atom.Table, atom.Tbody, atom.Tr, atom.Td:
+		return false
+	}
+	if attr(n, "role") == "navigation" || attr(n, "role") == "complementary" {
+		return true
+	}
+	names := attr(n, "class") + " " + attr(n, "id")
+	return unlikelyCandidates.MatchString(names) && !maybeCandidates.MatchString(names)
+}
+
+// linkDensity 计算链接文本占比
+func linkDensity(n *html.Node) float64 {
+	total := utf8.RuneCountInString(textContent(n))
+	if total == 0 {
+		return 0
+	}
+	var linked int
+	walkElements(n, func(c *html.Node) bool {
+		if c.DataAtom == atom.A {
+			linked += utf8.RuneCountInString(textContent(c))
+			return false
+		}
+		return true
+	})
+	return float64(linked) / float64(total)
+}
+
+// walkElements 深度优先遍历元素节点，fn返回false时不再进入该节点的子节点
+func walkElements(n *html.Node, fn func(*html.Node) bool) {
+	if n.Type == html.ElementNode && !fn(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walkElements(c, fn)
+	}
+}
+
+// findFirst 查找第一个指定标签的元素
+func findFirst(root *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walkElements(root, func(n *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if n.DataAtom == a {
+			found = n
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+// textContent 获取节点下的全部文本（不做格式化）
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && (c.DataAtom == atom.Script || c.DataAtom == atom.Style) {
+			continue
+		}
+		b.WriteString(textContent(c))
+	}
+	return b.String()
+}
+
+// attr 获取属性值
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// firstNonEmpty 返回第一个非空字符串
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func minFloat(a, b float64) float64 {
+	if a < b {
+		return a
+	}
+	return b
+}
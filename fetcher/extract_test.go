@@ -0,0 +1,113 @@
+package fetcher
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExtractArticle(t *testing.T) {
+	tests := []struct {
+		file     string
+		want     Article  // 除Content以外的字段
+		contains []string // 正文中必须包含的内容
+		excludes []string // 导航、侧栏、评论等不属于正文的内容
+	}{
+		{
+			// JSON-LD优先于OpenGraph和普通meta，<link rel="canonical">优先于JSON-LD中的地址
+			file: "jsonld.html",
+			want: Article{
+				Title:        "机器翻译进入新阶段",
+				Description:  "大模型让网页翻译更加自然。",
+				Author:       "张三, 李四",
+				PublishDate:  "2024-03-01T08:00:00+08:00",
+				ModifiedDate: "2024-03-02T10:30:00+08:00",
+				Category:     "科技",
+				Tags:         []string{"机器翻译", "大模型"},
+				SiteName:     "示例新闻",
+				CanonicalURL: "https://news.example.com/news/2024/translation",
+				Image:        "https://news.example.com/images/cover.jpg",
+				Language:     "zh",
+			},
+			contains: []string{"机器翻译进入新阶段\n\n过去一年", "与传统的统计机器翻译相比", "在质量和速度之间取得平衡。"},
+			excludes: []string{"首页", "热门推荐", "推荐文章一", "评论区", "版权所有"},
+		},
+		{
+			// 没有JSON-LD时使用OpenGraph、Twitter和文章meta，og:url作为规范URL
+			file: "opengraph.html",
+			want: Article{
+				Title:        "How we cut translation latency in half",
+				Description:  "Batching, caching and streaming.",
+				Author:       "Jane Doe",
+				PublishDate:  "2024-05-20T09:00:00Z",
+				ModifiedDate: "2024-05-21T12:00:00Z",
+				Category:     "Engineering",
+				Tags:         []string{"performance", "caching"},
+				SiteName:     "Example Blog",
+				CanonicalURL: "https://blog.example.com/posts/latency",
+				Image:        "https://news.example.com/static/latency.png",
+				Language:     "en",
+			},
+			contains: []string{"Our translation service used to send every paragraph", "as soon as each batch finishes."},
+			excludes: []string{"Archive", "Subscribe", "Related post"},
+		},
+		{
+			// 只有页面元素：<title>、<time datetime>和keywords，语言按正文检测
+			file: "plain.html",
+			want: Article{
+				Title:       "Release notes",
+				PublishDate: "2024-06-01",
+				Tags:        []string{"release", "notes", "changelog"},
+				Language:    "en",
+			},
+			contains: []string{"Version 2.0 released", "Published June 1 by the release team.", "resume after a restart."},
+			excludes: []string{"Docs", "Download", "Share this page"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			article, err := ExtractArticle(string(data), "https://news.example.com/a/1?ref=home")
+			if err != nil {
+				t.Fatalf("ExtractArticle: %v", err)
+			}
+
+			got := *article
+			got.Content = ""
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractArticle = %+v, want %+v", got, tt.want)
+			}
+			for _, text := range tt.contains {
+				if !strings.Contains(article.Content, text) {
+					t.Errorf("Content does not contain %q:\n%s", text, article.Content)
+				}
+			}
+			for _, text := range tt.excludes {
+				if strings.Contains(article.Content, text) {
+					t.Errorf("Content contains %q:\n%s", text, article.Content)
+				}
+			}
+		})
+	}
+}
+
+func TestArticleField(t *testing.T) {
+	article := &Article{Title: "标题", Description: "描述", Tags: []string{"a", "b"}}
+	if v, ok := article.Field("summary"); !ok || v != "描述" {
+		t.Errorf("Field(summary) = %v, %v", v, ok)
+	}
+	if v, ok := article.Field("tags"); !ok || !reflect.DeepEqual(v, []string{"a", "b"}) {
+		t.Errorf("Field(tags) = %v, %v", v, ok)
+	}
+	if _, ok := article.Field("author"); ok {
+		t.Error("Field(author) should report a missing value")
+	}
+	if _, ok := article.Field("unknown"); ok {
+		t.Error("Field(unknown) should report an unknown field")
+	}
+}
@@ -0,0 +1,341 @@
+package fetcher
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+const (
+	// DefaultTimeout 默认请求超时时间
+	DefaultTimeout = 20 * time.Second
+	// DefaultMaxRedirects 默认最大重定向次数
+	DefaultMaxRedirects = 5
+	// DefaultMaxBodyBytes 默认最大下载字节数
+	DefaultMaxBodyBytes = 5 << 20
+	// DefaultUserAgent 默认User-Agent
+	DefaultUserAgent = "Mozilla/5.0 (compatible; WistransFetcher/1.0; +https://github.com/aimmetal-tech/wistrans-backend)"
+)
+
+// Options 抓取器配置
+type Options struct {
+	Timeout      time.Duration // 单次抓取的总超时时间
+	MaxRedirects int           // 最大重定向次数
+	MaxBodyBytes int64         // 响应体最大读取字节数
+	UserAgent    string        // 请求使用的User-Agent
+}
+
+// Page 抓取到的网页
+type Page struct {
+	URL         string   // 请求的URL
+	FinalURL    string   // 跟随重定向后的最终URL
+	StatusCode  int      // HTTP状态码
+	ContentType string   // 响应的Content-Type
+	Charset     string   // 检测到的字符集
+	HTML        string   // 解码为UTF-8后的原始HTML
+	Text        string   // 提取出的整页可读文本
+	Article     *Article // 从标记中提取的元数据和正文
+	Truncated   bool     // 内容是否被截断
+}
+
+// blockedNetworks 不允许访问的保留地址段，回环、私有、链路本地（包括169.254.169.254等云服务器元数据地址）和组播地址另外判断
+var blockedNetworks = parseCIDRs(
+	"0.0.0.0/8",       // 本网络
+	"100.64.0.0/10",   // 运营商级NAT
+	"192.0.0.0/24",    // IETF协议分配
+	"192.0.2.0/24",    // 文档示例
+	"198.18.0.0/15",   // 基准测试
+	"198.51.100.0/24", // 文档示例
+	"203.0.113.0/24",  // 文档示例
+	"240.0.0.0/4",     // 保留地址和广播地址
+	"64:ff9b::/96",    // NAT64，可能映射到内网的IPv4地址
+	"2001:db8::/32",   // 文档示例
+)
+
+// parseCIDRs 解析地址段
+func parseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks = append(networks, network)
+	}
+	return networks
+}
+
+// isPublicIP 判断是否为公网地址
+func isPublicIP(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return false
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// Fetcher 网页抓取器
+type Fetcher struct {
+	client       *http.Client
+	maxBodyBytes int64
+	userAgent    string
+	allowIP      func(net.IP) bool // 允许连接的非公网地址，只在测试中使用
+}
+
+// NewFetcher 使用默认配置创建抓取器
+func NewFetcher() *Fetcher {
+	return NewFetcherWithOptions(Options{})
+}
+
+// NewFetcherWithOptions 使用指定配置创建抓取器，未填写的字段使用默认值
+func NewFetcherWithOptions(opts Options) *Fetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = DefaultMaxRedirects
+	}
+	if opts.MaxBodyBytes <= 0 {
+		opts.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	if opts.UserAgent == "" {
+		opts.UserAgent = DefaultUserAgent
+	}
+
+	f := &Fetcher{
+		maxBodyBytes: opts.MaxBodyBytes,
+		userAgent:    opts.UserAgent,
+	}
+
+	// 每次建立连接时检查解析后的IP，重定向和DNS重绑定同样无法访问内网。
+	// 不使用环境变量中的代理，否则检查的是代理的地址而不是目标地址
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   f.checkAddress,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	maxRedirects := opts.MaxRedirects
+	f.client = &http.Client{
+		Timeout:   opts.Timeout,
+		Transport: transport,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("重定向次数超过限制(%d)", maxRedirects)
+			}
+			if req.URL.Scheme != "http" && req.URL.Scheme != "https" {
+				return fmt.Errorf("不支持重定向到%s协议", req.URL.Scheme)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// checkAddress 拒绝连接回环、私有、链路本地等非公网地址，防止通过抓取接口访问内网服务
+func (f *Fetcher) checkAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip != nil && f.allowIP != nil && f.allowIP(ip) {
+		return nil
+	}
+	if ip == nil || !isPublicIP(ip) {
+		return fmt.Errorf("不允许访问非公网地址: %s", host)
+	}
+	return nil
+}
+
+// Fetch 下载网页并提取可读文本，maxLength限制返回文本的字符数（<=0表示不限制）
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string, maxLength int) (*Page, error) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("URL格式错误: %v", err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return nil, fmt.Errorf("仅支持http和https协议: %s", rawURL)
+	}
+	if parsed.Host == "" {
+		return nil, fmt.Errorf("URL缺少主机名: %s", rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, parsed.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("创建HTTP请求失败: %v", err)
+	}
+	req.Header.Set("User-Agent", f.userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,text/plain;q=0.9,*/*;q=0.5")
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9,en;q=0.8")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求网页失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("网页返回异常状态码: %d", resp.StatusCode)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if !isTextContent(contentType) {
+		return nil, fmt.Errorf("不支持的内容类型: %s", contentType)
+	}
+
+	// 多读一个字节用于判断是否超过大小限制
+	body, err := io.ReadAll(io.LimitReader(resp.Body, f.maxBodyBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("读取网页内容失败: %v", err)
+	}
+	truncated := false
+	if int64(len(body)) > f.maxBodyBytes {
+		body = body[:f.maxBodyBytes]
+		truncated = true
+	}
+
+	decoded, charsetName, err := decodeBody(body, contentType)
+	if err != nil {
+		return nil, fmt.Errorf("转换网页编码失败: %v", err)
+	}
+
+	page := &Page{
+		URL:         rawURL,
+		FinalURL:    resp.Request.URL.String(),
+		StatusCode:  resp.StatusCode,
+		ContentType: contentType,
+		Charset:     charsetName,
+		Truncated:   truncated,
+	}
+
+	if strings.HasPrefix(strings.ToLower(contentType), "text/plain") {
+		page.Text = normalizeText(decoded)
+		page.Article = &Article{Content: page.Text, Language: DetectLanguage(page.Text)}
+	} else {
+		root, err := html.Parse(strings.NewReader(decoded))
+		if err != nil {
+			return nil, fmt.Errorf("解析HTML失败: %v", err)
+		}
+		page.HTML = decoded
+		page.Text = NodeText(root)
+		page.Article = extractArticle(root, page.FinalURL)
+	}
+
+	if maxLength > 0 {
+		var cut bool
+		page.Text, cut = truncateRunes(page.Text, maxLength)
+		page.Truncated = page.Truncated || cut
+		page.Article.Content, cut = truncateRunes(page.Article.Content, maxLength)
+		page.Truncated = page.Truncated || cut
+	}
+
+	return page, nil
+}
+
+// truncateRunes 按字符数截断文本
+func truncateRunes(text string, maxLength int) (string, bool) {
+	if utf8.RuneCountInString(text) <= maxLength {
+		return text, false
+	}
+	return string([]rune(text)[:maxLength]), true
+}
+
+// isTextContent 判断Content-Type是否为可提取文本的类型
+func isTextContent(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	contentType = strings.ToLower(contentType)
+	return strings.HasPrefix(contentType, "text/html") ||
+		strings.HasPrefix(contentType, "application/xhtml+xml") ||
+		strings.HasPrefix(contentType, "text/plain")
+}
+
+// decodeBody 根据响应头、BOM和meta标签检测字符集并转换为UTF-8
+func decodeBody(body []byte, contentType string) (string, string, error) {
+	enc, name, certain := charset.DetermineEncoding(body, contentType)
+
+	// 无法确定编码且内容不是合法UTF-8时，优先尝试GB18030（兼容GBK/GB2312）
+	if !certain && name == "windows-1252" && !utf8.Valid(body) && looksLikeGB(body) {
+		enc, name = simplifiedchinese.GB18030, "gb18030"
+	}
+
+	if enc == encoding.Nop || name == "utf-8" {
+		return strings.ToValidUTF8(string(body), "�"), "utf-8", nil
+	}
+
+	decoded, _, err := transform.Bytes(enc.NewDecoder(), body)
+	if err != nil {
+		return "", name, err
+	}
+	return string(decoded), name, nil
+}
+
+// looksLikeGB 判断内容按GB18030解码后是否主要为中文
+func looksLikeGB(body []byte) bool {
+	decoded, _, err := transform.Bytes(simplifiedchinese.GB18030.NewDecoder(), body)
+	if err != nil {
+		return false
+	}
+	var han, bad int
+	for _, r := range string(decoded) {
+		switch {
+		case r == utf8.RuneError:
+			bad++
+		case unicode.Is(unicode.Han, r):
+			han++
+		}
+	}
+	return han > 0 && bad*10 < han
+}
+
+// normalizeText 规范化纯文本中的空白字符
+func normalizeText(text string) string {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	var buf bytes.Buffer
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			blank = buf.Len() > 0
+			continue
+		}
+		if buf.Len() > 0 {
+			if blank {
+				buf.WriteString("\n\n")
+			} else {
+				buf.WriteString("\n")
+			}
+		}
+		buf.WriteString(line)
+		blank = false
+	}
+	return buf.String()
+}
@@ -0,0 +1,195 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// newTestFetcher 创建允许连接127.0.0.1的抓取器
+func newTestFetcher(opts Options) *Fetcher {
+	f := NewFetcherWithOptions(opts)
+	f.allowIP = func(ip net.IP) bool { return ip.Equal(net.IPv4(127, 0, 0, 1)) }
+	return f
+}
+
+func TestFetchFollowsRedirects(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/start", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/final", http.StatusFound)
+	})
+	mux.HandleFunc("/final", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<html><body><p>到达</p></body></html>")
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	f := newTestFetcher(Options{MaxRedirects: 3})
+	page, err := f.Fetch(context.Background(), server.URL+"/start", 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if page.FinalURL != server.URL+"/final" {
+		t.Errorf("FinalURL = %q, want %q", page.FinalURL, server.URL+"/final")
+	}
+	if page.Text != "到达" {
+		t.Errorf("Text = %q, want %q", page.Text, "到达")
+	}
+
+	if _, err := f.Fetch(context.Background(), server.URL+"/loop", 0); err == nil || !strings.Contains(err.Error(), "重定向次数超过限制") {
+		t.Errorf("redirect loop error = %v", err)
+	}
+}
+
+func TestFetchSizeCap(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprint(w, strings.Repeat("a", 100))
+	}))
+	defer server.Close()
+
+	page, err := newTestFetcher(Options{MaxBodyBytes: 10}).Fetch(context.Background(), server.URL, 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if page.Text != strings.Repeat("a", 10) || !page.Truncated {
+		t.Errorf("Text = %q, Truncated = %v, want 10 bytes and truncated", page.Text, page.Truncated)
+	}
+
+	page, err = newTestFetcher(Options{}).Fetch(context.Background(), server.URL, 20)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	if len(page.Text) != 20 || !page.Truncated {
+		t.Errorf("Text = %q, Truncated = %v, want 20 characters and truncated", page.Text, page.Truncated)
+	}
+}
+
+func TestFetchCharset(t *testing.T) {
+	gbk := func(s string) string {
+		encoded, err := simplifiedchinese.GBK.NewEncoder().String(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return encoded
+	}
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		charset     string
+	}{
+		{"header", "text/html; charset=gbk", gbk("<p>中文网页</p>"), "gbk"},
+		{"meta", "text/html", gbk(`<html><head><meta charset="gb2312"></head><body><p>中文网页</p></body></html>`), "gbk"},
+		{"undeclared gbk", "text/html", gbk("<p>中文网页</p>"), "gb18030"},
+		{"utf-8", "text/html; charset=utf-8", "<p>中文网页</p>", "utf-8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", tt.contentType)
+				fmt.Fprint(w, tt.body)
+			}))
+			defer server.Close()
+
+			page, err := newTestFetcher(Options{}).Fetch(context.Background(), server.URL, 0)
+			if err != nil {
+				t.Fatalf("Fetch: %v", err)
+			}
+			if page.Text != "中文网页" {
+				t.Errorf("Text = %q, want %q", page.Text, "中文网页")
+			}
+			if page.Charset != tt.charset {
+				t.Errorf("Charset = %q, want %q", page.Charset, tt.charset)
+			}
+		})
+	}
+}
+
+func TestFetchPreservesPre(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, "<body><p>  示例   代码 </p><pre>func main() {\n    fmt.Println(1)\n}\n</pre><p>结束</p></body>")
+	}))
+	defer server.Close()
+
+	page, err := newTestFetcher(Options{}).Fetch(context.Background(), server.URL, 0)
+	if err != nil {
+		t.Fatalf("Fetch: %v", err)
+	}
+	want := "示例 代码\nfunc main() {\n    fmt.Println(1)\n}\n结束"
+	if page.Text != want {
+		t.Errorf("Text = %q, want %q", page.Text, want)
+	}
+}
+
+func TestFetchRejectsPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "internal")
+	}))
+	defer server.Close()
+
+	if _, err := NewFetcher().Fetch(context.Background(), server.URL, 0); err == nil || !strings.Contains(err.Error(), "不允许访问非公网地址") {
+		t.Errorf("loopback error = %v", err)
+	}
+
+	// 允许的地址重定向到其他内网地址时同样拒绝
+	listener, err := net.Listen("tcp", "127.0.0.2:0")
+	if err != nil {
+		t.Skipf("无法监听127.0.0.2: %v", err)
+	}
+	internal := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "secret")
+	}))
+	internal.Listener.Close()
+	internal.Listener = listener
+	internal.Start()
+	defer internal.Close()
+
+	redirect := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer redirect.Close()
+
+	if _, err := newTestFetcher(Options{}).Fetch(context.Background(), redirect.URL, 0); err == nil || !strings.Contains(err.Error(), "不允许访问非公网地址: 127.0.0.2") {
+		t.Errorf("redirect error = %v", err)
+	}
+}
+
+func TestIsPublicIP(t *testing.T) {
+	tests := []struct {
+		ip     string
+		public bool
+	}{
+		{"8.8.8.8", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"fe80::1", false},
+		{"fd00:ec2::254", false},
+		{"64:ff9b::a00:1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicIP(net.ParseIP(tt.ip)); got != tt.public {
+			t.Errorf("isPublicIP(%s) = %v, want %v", tt.ip, got, tt.public)
+		}
+	}
+}
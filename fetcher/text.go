@@ -0,0 +1,136 @@
+package fetcher
+
+import (
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// skippedElements 提取文本时整体忽略的元素
+var skippedElements = map[atom.Atom]bool{
+	atom.Head:     true,
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Noscript: true,
+	atom.Template: true,
+	atom.Svg:      true,
+	atom.Iframe:   true,
+	atom.Canvas:   true,
+	atom.Object:   true,
+	atom.Select:   true,
+	atom.Button:   true,
+	atom.Form:     true,
+}
+
+// blockElements 前后需要换行的块级元素
+var blockElements = map[atom.Atom]bool{
+	atom.Address: true, atom.Article: true, atom.Aside: true, atom.Blockquote: true,
+	atom.Body: true, atom.Dd: true, atom.Div: true, atom.Dl: true, atom.Dt: true,
+	atom.Fieldset: true, atom.Figcaption: true, atom.Figure: true, atom.Footer: true,
+	atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Header: true, atom.Hr: true, atom.Li: true, atom.Main: true, atom.Nav: true,
+	atom.Ol: true, atom.P: true, atom.Pre: true, atom.Section: true, atom.Table: true,
+	atom.Tr: true, atom.Ul: true, atom.Br: true, atom.Caption: true, atom.Summary: true,
+	atom.Details: true, atom.Tbody: true, atom.Thead: true, atom.Tfoot: true,
+}
+
+// ExtractText 将HTML转换为可读的纯文本，保留段落换行
+func ExtractText(document string) (string, error) {
+	root, err := html.Parse(strings.NewReader(document))
+	if err != nil {
+		return "", err
+	}
+	return NodeText(root), nil
+}
+
+// NodeText 提取节点及其子节点中的可读文本，pre中的代码和排版原样保留
+func NodeText(n *html.Node) string {
+	var w textWriter
+	w.walk(n)
+	w.flush()
+	return strings.Join(w.parts, "\n")
+}
+
+// textWriter 文本收集器
+type textWriter struct {
+	buf   strings.Builder
+	parts []string // 已完成的文本，普通文本规范化空白，pre中的文本原样保留
+}
+
+// flush 规范化缓冲区中的普通文本并加入已完成的文本
+func (w *textWriter) flush() {
+	if text := normalizeText(w.buf.String()); text != "" {
+		w.parts = append(w.parts, text)
+	}
+	w.buf.Reset()
+}
+
+func (w *textWriter) walk(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.buf.WriteString(n.Data)
+		return
+	case html.CommentNode, html.DoctypeNode:
+		return
+	case html.ElementNode:
+		if skippedElements[n.DataAtom] || isHidden(n) {
+			return
+		}
+		if n.DataAtom == atom.Pre {
+			w.flush()
+			w.writePre(n)
+			pre := strings.ReplaceAll(w.buf.String(), "\r\n", "\n")
+			if pre = strings.Trim(strings.TrimRight(pre, " \t\n"), "\n"); pre != "" {
+				w.parts = append(w.parts, pre)
+			}
+			w.buf.Reset()
+			return
+		}
+	}
+
+	block := n.Type == html.ElementNode && blockElements[n.DataAtom]
+	if block {
+		w.buf.WriteString("\n")
+	}
+	if n.DataAtom == atom.Td || n.DataAtom == atom.Th {
+		w.buf.WriteString(" ")
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.walk(c)
+	}
+	if block {
+		w.buf.WriteString("\n")
+	}
+}
+
+// writePre 按原样输出pre中的文本，每行单独保留
+func (w *textWriter) writePre(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.TextNode {
+			w.buf.WriteString(c.Data)
+		} else if c.Type == html.ElementNode {
+			w.writePre(c)
+		}
+	}
+}
+
+// isHidden 判断元素是否被显式隐藏
+func isHidden(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		switch attr.Key {
+		case "hidden":
+			return true
+		case "aria-hidden":
+			if attr.Val == "true" {
+				return true
+			}
+		case "style":
+			style := strings.ReplaceAll(strings.ToLower(attr.Val), " ", "")
+			if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+				return true
+			}
+		}
+	}
+	return false
+}
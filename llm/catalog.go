@@ -0,0 +1,195 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// catalogTTL 服务商模型列表的缓存时间
+	catalogTTL = 10 * time.Minute
+	// catalogErrorTTL 获取模型列表失败后多久再重试
+	catalogErrorTTL = time.Minute
+	// catalogFetchTimeout 获取单个服务商模型列表的超时时间
+	catalogFetchTimeout = 5 * time.Second
+)
+
+// 模型信息来源
+const (
+	ModelSourceStatic = "static" // 只在配置中声明
+	ModelSourceRemote = "remote" // 只在服务商的/models接口中返回
+	ModelSourceBoth   = "static+remote"
+)
+
+// ModelInfo 模型目录中的一个模型
+type ModelInfo struct {
+	Provider      string            `json:"provider"`                 // 服务商
+	ID            string            `json:"id"`                       // 模型ID
+	Name          string            `json:"name"`                     // 可直接作为model参数使用的"服务商/模型"
+	Aliases       []string          `json:"aliases,omitempty"`        // 模型别名
+	ContextWindow int               `json:"context_window,omitempty"` // 上下文窗口大小，未知时为空
+	Capabilities  ModelCapabilities `json:"capabilities"`             // 模型能力
+	Configured    bool              `json:"configured"`               // 服务商是否已配置API密钥
+	Default       bool              `json:"default,omitempty"`        // 是否为默认模型
+	Source        string            `json:"source"`                   // 信息来源 (static, remote, static+remote)
+}
+
+// ModelCatalog 全部服务商的模型目录
+type ModelCatalog struct {
+	Default string            `json:"default"`          // 未指定模型时使用的"服务商/模型"
+	Models  []ModelInfo       `json:"models"`           // 模型列表
+	Errors  map[string]string `json:"errors,omitempty"` // 获取模型列表失败的服务商及原因
+}
+
+// remoteListing 服务商/models接口返回的模型ID缓存
+type remoteListing struct {
+	ids       []string
+	err       error
+	fetchedAt time.Time
+}
+
+// fresh 判断缓存是否仍然有效，失败结果的缓存时间较短
+func (l *remoteListing) fresh() bool {
+	if l == nil {
+		return false
+	}
+	ttl := catalogTTL
+	if l.err != nil {
+		ttl = catalogErrorTTL
+	}
+	return time.Since(l.fetchedAt) < ttl
+}
+
+// contains 判断列表中是否有该模型，返回服务商使用的模型ID
+func (l *remoteListing) contains(name string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	for _, id := range l.ids {
+		if strings.EqualFold(id, name) {
+			return id, true
+		}
+	}
+	return "", false
+}
+
+// remoteModels 获取服务商/models接口返回的模型列表，结果会被缓存。未配置密钥的服务商返回nil
+func (c *Client) remoteModels(ctx context.Context, provider ProviderConfig, refresh bool) *remoteListing {
+	if !provider.Configured() {
+		return nil
+	}
+
+	c.catalogMu.Lock()
+	cached := c.catalog[provider.Name]
+	c.catalogMu.Unlock()
+	if !refresh && cached.fresh() {
+		return cached
+	}
+
+	listing := &remoteListing{fetchedAt: time.Now()}
+	client, _, err := c.GetClient(ModelProvider(provider.Name))
+	if err == nil {
+		fetchCtx, cancel := context.WithTimeout(ctx, catalogFetchTimeout)
+		var list []string
+		models, listErr := client.ListModels(fetchCtx)
+		cancel()
+		for _, model := range models.Models {
+			list = append(list, model.ID)
+		}
+		listing.ids, err = list, listErr
+	}
+	if err != nil {
+		listing.err = fmt.Errorf("获取模型列表失败: %v", err)
+	}
+
+	c.catalogMu.Lock()
+	c.catalog[provider.Name] = listing
+	c.catalogMu.Unlock()
+	return listing
+}
+
+// remoteModelsAll 并发获取全部服务商的模型列表
+func (c *Client) remoteModelsAll(ctx context.Context, providers []ProviderConfig, refresh bool) []*remoteListing {
+	listings := make([]*remoteListing, len(providers))
+	var wg sync.WaitGroup
+	for i, provider := range providers {
+		wg.Add(1)
+		go func(i int, provider ProviderConfig) {
+			defer wg.Done()
+			listings[i] = c.remoteModels(ctx, provider, refresh)
+		}(i, provider)
+	}
+	wg.Wait()
+	return listings
+}
+
+// Models 返回模型目录：配置中的静态元数据合并服务商/models接口的结果。
+// refresh为true时忽略缓存重新获取
+func (c *Client) Models(ctx context.Context, refresh bool) ModelCatalog {
+	registry := c.Registry()
+	providers := registry.Providers()
+	listings := c.remoteModelsAll(ctx, providers, refresh)
+
+	defaultProvider := registry.Default()
+	catalog := ModelCatalog{
+		Default: defaultProvider.Name + "/" + defaultProvider.DefaultModel,
+		Models:  []ModelInfo{},
+	}
+	for i, provider := range providers {
+		listing := listings[i]
+		if listing != nil && listing.err != nil {
+			if catalog.Errors == nil {
+				catalog.Errors = make(map[string]string)
+			}
+			catalog.Errors[provider.Name] = listing.err.Error()
+		}
+
+		listed := make(map[string]bool)
+		for _, model := range provider.Models {
+			info := ModelInfo{
+				Provider:      provider.Name,
+				ID:            model.ID,
+				Name:          provider.Name + "/" + model.ID,
+				Aliases:       model.Aliases,
+				ContextWindow: model.ContextWindow,
+				Capabilities:  model.Capabilities,
+				Configured:    provider.Configured(),
+				Default:       provider.Name == defaultProvider.Name && model.Matches(provider.DefaultModel),
+				Source:        ModelSourceStatic,
+			}
+			if id, ok := listing.contains(model.ID); ok {
+				info.Source = ModelSourceBoth
+				listed[strings.ToLower(id)] = true
+			}
+			catalog.Models = append(catalog.Models, info)
+		}
+
+		// 服务商返回但配置中没有声明的模型，能力未知，只标记支持流式输出
+		var remoteOnly []string
+		if listing != nil {
+			for _, id := range listing.ids {
+				if !listed[strings.ToLower(id)] {
+					if _, ok := provider.FindModel(id); !ok {
+						remoteOnly = append(remoteOnly, id)
+					}
+				}
+			}
+		}
+		sort.Strings(remoteOnly)
+		for _, id := range remoteOnly {
+			catalog.Models = append(catalog.Models, ModelInfo{
+				Provider:     provider.Name,
+				ID:           id,
+				Name:         provider.Name + "/" + id,
+				Capabilities: ModelCapabilities{Streaming: true},
+				Configured:   true,
+				Source:       ModelSourceRemote,
+			})
+		}
+	}
+	return catalog
+}
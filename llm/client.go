@@ -3,16 +3,19 @@ package llm
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"sync"
 
-	"github.com/sashabaranov/go-openai"
 	"github.com/joho/godotenv"
+	"github.com/sashabaranov/go-openai"
 )
 
-// ModelProvider 大模型提供商枚举
+// ModelProvider 大模型提供商名称，对应服务商注册表中的name
 type ModelProvider string
 
+// 内置的服务商
 const (
 	Qwen     ModelProvider = "qwen"
 	DeepSeek ModelProvider = "deepseek"
@@ -20,179 +23,315 @@ const (
 	Kimi     ModelProvider = "kimi"
 )
 
-// APIConfig API配置
-type APIConfig struct {
-	DeepSeekAPIKey string
-	OpenAIAPIKey   string
-	KimiAPIKey     string
-	QwenAPIKey     string
-}
-
-// Client 大模型客户端
+// Client 大模型客户端，可以被多个请求并发使用
 type Client struct {
-	config *APIConfig
+	configPath string
+	httpClient *http.Client
+
+	mu       sync.RWMutex
+	registry *Registry
+	clients  map[string]*cachedClient
+
+	budget     *retryBudget
+	breakersMu sync.Mutex
+	breakers   map[string]*breaker
+
+	catalogMu sync.Mutex
+	catalog   map[string]*remoteListing
 }
 
-// NewClient 创建新的大模型客户端
+// NewClient 创建新的大模型客户端，服务商配置从LLM_PROVIDERS_FILE指定的文件和环境变量加载
 func NewClient() (*Client, error) {
-	// 先从系统环境变量获取
-	config := &APIConfig{
-		DeepSeekAPIKey: os.Getenv("DEEPSEEK_API_KEY"),
-		OpenAIAPIKey:   os.Getenv("OPENAI_API_KEY"),
-		KimiAPIKey:     os.Getenv("KIMI_API_KEY"),
-		QwenAPIKey:     os.Getenv("QWEN_API_KEY"),
-	}
-
-	// 再从.env文件获取（如果环境变量中没有）
-	if config.DeepSeekAPIKey == "" || config.OpenAIAPIKey == "" || config.KimiAPIKey == "" || config.QwenAPIKey == "" {
-		// 加载.env文件
-		err := godotenv.Load()
-		if err != nil {
-			fmt.Printf("未找到.env文件: %v\n", err)
-		}
+	// 加载.env文件，已存在的系统环境变量不会被覆盖
+	err := godotenv.Load()
+	if err != nil {
+		fmt.Printf("未找到.env文件: %v\n", err)
+	}
+
+	configPath := os.Getenv("LLM_PROVIDERS_FILE")
+	registry, err := loadRegistry(configPath)
+	if err != nil {
+		return nil, err
+	}
+	client, err := NewClientWithRegistry(registry)
+	if err != nil {
+		return nil, err
+	}
+	client.configPath = configPath
+	return client, nil
+}
+
+// loadRegistry 从配置文件和环境变量加载服务商注册表
+func loadRegistry(path string) (*Registry, error) {
+	config, err := LoadRegistryConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewRegistry(config)
+}
 
-		if config.DeepSeekAPIKey == "" {
-			config.DeepSeekAPIKey = os.Getenv("DEEPSEEK_API_KEY")
+// NewClientWithRegistry 使用指定的服务商注册表创建客户端
+func NewClientWithRegistry(registry *Registry) (*Client, error) {
+	// 检查是否至少有一个服务商可用
+	for _, provider := range registry.Providers() {
+		if provider.Configured() {
+			return &Client{
+				httpClient: &http.Client{Transport: samplingTransport{base: sharedTransport}},
+				registry:   registry,
+				clients:    make(map[string]*cachedClient),
+				budget:     &retryBudget{tokens: registry.RetryPolicy().BudgetMax},
+				breakers:   make(map[string]*breaker),
+				catalog:    make(map[string]*remoteListing),
+			}, nil
 		}
-		if config.OpenAIAPIKey == "" {
-			config.OpenAIAPIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	return nil, fmt.Errorf("未填写API，请在系统环境变量或.env文件中填写至少一个大模型API")
+}
+
+// Registry 返回当前的服务商注册表
+func (c *Client) Registry() *Registry {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.registry
+}
+
+// systemEnv 进程启动时已存在的系统环境变量，与启动时一样，重新读取.env时不覆盖这些变量
+var systemEnv = environKeys()
+
+// environKeys 返回当前环境变量的名称
+func environKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, entry := range os.Environ() {
+		if key, _, ok := strings.Cut(entry, "="); ok {
+			keys[key] = true
 		}
-		if config.KimiAPIKey == "" {
-			config.KimiAPIKey = os.Getenv("KIMI_API_KEY")
+	}
+	return keys
+}
+
+// reloadDotEnv 重新读取.env文件，更新由.env设置的环境变量，系统环境变量优先
+func reloadDotEnv() error {
+	values, err := godotenv.Read()
+	if err != nil {
+		return err
+	}
+	for key, value := range values {
+		if systemEnv[key] {
+			continue
 		}
-		if config.QwenAPIKey == "" {
-			config.QwenAPIKey = os.Getenv("QWEN_API_KEY")
+		if err := os.Setenv(key, value); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// Reload 重新读取.env和服务商配置文件，与启动时一样系统环境变量优先，.env中的值只更新由.env设置的变量。
+// 已创建的客户端在下次使用时按新配置重建，进行中的请求不受影响
+func (c *Client) Reload() error {
+	if err := reloadDotEnv(); err != nil {
+		fmt.Printf("未找到.env文件: %v\n", err)
+	}
 
-	// 检查是否至少填写了一个API
-	if config.DeepSeekAPIKey == "" && config.OpenAIAPIKey == "" && config.KimiAPIKey == "" && config.QwenAPIKey == "" {
-		return nil, fmt.Errorf("未填写API，请在系统环境变量或.env文件中填写至少一个大模型API")
+	registry, err := loadRegistry(c.configPath)
+	if err != nil {
+		return err
 	}
 
-	return &Client{config: config}, nil
+	c.mu.Lock()
+	c.registry = registry
+	c.clients = make(map[string]*cachedClient)
+	c.mu.Unlock()
+
+	c.catalogMu.Lock()
+	c.catalog = make(map[string]*remoteListing)
+	c.catalogMu.Unlock()
+	return nil
+}
+
+// DefaultModel 返回默认服务商和默认模型
+func (c *Client) DefaultModel() (ModelProvider, string) {
+	provider := c.Registry().Default()
+	return ModelProvider(provider.Name), provider.DefaultModel
 }
 
-// ParseModel 解析模型字符串，返回提供商和模型名称
-func (c *Client) ParseModel(model string) (ModelProvider, string) {
+// ParseModel 解析模型字符串，返回提供商和模型ID。支持"服务商/模型"格式、模型ID和模型别名，
+// 先查配置中的模型列表，再查服务商/models接口返回的模型，都不存在时返回错误
+func (c *Client) ParseModel(model string) (ModelProvider, string, error) {
+	model = strings.TrimSpace(model)
 	if model == "" {
-		// 默认使用Qwen
-		return Qwen, "qwen-turbo-latest"
-	}
-
-	// 检查是否是 "提供商/模型" 格式
-	if strings.Contains(model, "/") {
-		parts := strings.Split(model, "/")
-		if len(parts) >= 2 {
-			provider := strings.ToLower(parts[0])
-			modelName := parts[1]
-			
-			switch provider {
-			case "qwen", "通义千问", "通义":
-				return Qwen, modelName
-			case "deepseek", "深度求索":
-				return DeepSeek, modelName
-			case "openai", "open ai", "gpt":
-				return OpenAI, modelName
-			case "kimi", "moonshot", "月之暗面", "月之":
-				return Kimi, modelName
-			default:
-				// 如果提供商不匹配，尝试根据模型名匹配提供商
-				return c.matchProviderByModel(modelName), modelName
+		// 使用默认服务商的默认模型
+		provider, name := c.DefaultModel()
+		return provider, name, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), catalogFetchTimeout)
+	defer cancel()
+
+	// 检查是否是 "服务商/模型" 格式
+	prefix, modelName, hasPrefix := strings.Cut(model, "/")
+	if hasPrefix {
+		if provider, found := c.Registry().Lookup(prefix); found {
+			if id, ok := c.resolveModel(ctx, provider, modelName); ok {
+				return ModelProvider(provider.Name), id, nil
 			}
+			return "", "", fmt.Errorf("服务商%s不支持模型%s，可通过GET /models查看可用模型", provider.Name, modelName)
 		}
 	}
-	
-	// 直接指定模型的情况
-	return c.matchProviderByModel(model), model
-}
 
-// matchProviderByModel 根据模型名称匹配提供商
-func (c *Client) matchProviderByModel(model string) ModelProvider {
-	modelLower := strings.ToLower(model)
-	
-	// Qwen模型匹配
-	if strings.Contains(modelLower, "qwen") || strings.Contains(modelLower, "通义") {
-		return Qwen
+	// 直接指定模型的情况，模型ID本身可能包含"/"，先按完整名称查找
+	if provider, id, ok := c.findModel(ctx, model); ok {
+		return ModelProvider(provider.Name), id, nil
 	}
-	
-	// DeepSeek模型匹配
-	if strings.Contains(modelLower, "deepseek") {
-		return DeepSeek
+	if hasPrefix {
+		if provider, id, ok := c.findModel(ctx, modelName); ok {
+			return ModelProvider(provider.Name), id, nil
+		}
 	}
-	
-	// OpenAI模型匹配
-	if strings.Contains(modelLower, "gpt") {
-		return OpenAI
+	return "", "", fmt.Errorf("不支持的模型: %s，可通过GET /models查看可用模型", model)
+}
+
+// resolveModel 在指定服务商中查找模型，返回服务商使用的模型ID
+func (c *Client) resolveModel(ctx context.Context, provider ProviderConfig, name string) (string, bool) {
+	if model, ok := provider.FindModel(name); ok {
+		return model.ID, true
 	}
-	
-	// Kimi模型匹配
-	if strings.Contains(modelLower, "kimi") {
-		return Kimi
+	return c.remoteModels(ctx, provider, false).contains(name)
+}
+
+// findModel 在全部服务商中查找模型：先查配置中的模型列表，再查各服务商/models接口返回的模型
+func (c *Client) findModel(ctx context.Context, name string) (ProviderConfig, string, bool) {
+	registry := c.Registry()
+	if provider, model, ok := registry.FindByModel(name); ok {
+		return provider, model.ID, true
+	}
+	providers := registry.Providers()
+	for i, listing := range c.remoteModelsAll(ctx, providers, false) {
+		if id, ok := listing.contains(name); ok {
+			return providers[i], id, true
+		}
 	}
-	
-	// 默认返回Qwen
-	return Qwen
+	return ProviderConfig{}, "", false
 }
 
-// GetClient 获取指定提供商的OpenAI客户端和模型名称
+// GetClient 获取指定提供商的OpenAI客户端和默认模型名称。
+// 客户端按服务商缓存并共用同一个连接池，密钥或接口地址变化时自动重建
 func (c *Client) GetClient(provider ModelProvider) (*openai.Client, string, error) {
-	switch provider {
-	case Qwen:
-		if c.config.QwenAPIKey == "" {
-			return nil, "", fmt.Errorf("未配置Qwen API密钥")
-		}
-		config := openai.DefaultConfig(c.config.QwenAPIKey)
-		config.BaseURL = "https://dashscope.aliyuncs.com/compatible-mode/v1"
-		return openai.NewClientWithConfig(config), "qwen-turbo-latest", nil
+	config, ok := c.Registry().Lookup(string(provider))
+	if !ok {
+		return nil, "", fmt.Errorf("不支持的服务商: %s", provider)
+	}
+	if !config.Configured() {
+		return nil, "", fmt.Errorf("未配置%s API密钥，请设置环境变量%s", config.Name, config.APIKeyEnv)
+	}
 
-	case DeepSeek:
-		if c.config.DeepSeekAPIKey == "" {
-			return nil, "", fmt.Errorf("未配置DeepSeek API密钥")
-		}
-		config := openai.DefaultConfig(c.config.DeepSeekAPIKey)
-		config.BaseURL = "https://api.deepseek.com/v1"
-		return openai.NewClientWithConfig(config), "deepseek-chat", nil
+	apiKey := config.APIKey()
+	baseURL := strings.TrimRight(config.BaseURL, "/")
 
-	case OpenAI:
-		if c.config.OpenAIAPIKey == "" {
-			return nil, "", fmt.Errorf("未配置OpenAI API密钥")
-		}
-		// OpenAI使用默认配置
-		config := openai.DefaultConfig(c.config.OpenAIAPIKey)
-		return openai.NewClientWithConfig(config), "gpt-4o", nil
+	c.mu.RLock()
+	cached := c.clients[config.Name]
+	c.mu.RUnlock()
+	if cached.matches(apiKey, baseURL) {
+		return cached.client, config.DefaultModel, nil
+	}
 
-	case Kimi:
-		if c.config.KimiAPIKey == "" {
-			return nil, "", fmt.Errorf("未配置Kimi API密钥")
-		}
-		config := openai.DefaultConfig(c.config.KimiAPIKey)
-		config.BaseURL = "https://api.moonshot.cn/v1"
-		return openai.NewClientWithConfig(config), "kimi-k2-0711-preview", nil
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// 其他请求可能已经重建过客户端
+	if cached := c.clients[config.Name]; cached.matches(apiKey, baseURL) {
+		return cached.client, config.DefaultModel, nil
+	}
 
-	default:
-		return nil, "", fmt.Errorf("不支持的服务商: %s", provider)
+	clientConfig := openai.DefaultConfig(apiKey)
+	if baseURL != "" {
+		clientConfig.BaseURL = baseURL
 	}
+	clientConfig.HTTPClient = c.httpClient
+	client := openai.NewClientWithConfig(clientConfig)
+	c.clients[config.Name] = &cachedClient{client: client, apiKey: apiKey, baseURL: baseURL}
+	return client, config.DefaultModel, nil
 }
 
-// StreamChat 流式对话
-func (c *Client) StreamChat(ctx context.Context, provider ModelProvider, model string, messages []openai.ChatCompletionMessage) (*openai.ChatCompletionStream, error) {
-	client, defaultModel, err := c.GetClient(provider)
+// CreateChatCompletion 非流式对话，遇到限流、服务端错误或超时时按重试策略重试，并依次切换到备用服务商。
+// 返回的ServedBy为实际处理请求的服务商和模型，req.Model会被替换为实际使用的模型
+func (c *Client) CreateChatCompletion(ctx context.Context, provider ModelProvider, model string, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, ServedBy, error) {
+	return c.createChatCompletion(ctx, provider, model, req, true)
+}
+
+// CreateChatCompletionPinned 只使用指定的服务商和模型的非流式对话，只重试不切换服务商，
+// 用于调用方明确指定了模型、不能接受其他模型结果的请求
+func (c *Client) CreateChatCompletionPinned(ctx context.Context, provider ModelProvider, model string, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, ServedBy, error) {
+	return c.createChatCompletion(ctx, provider, model, req, false)
+}
+
+// createChatCompletion 按尝试顺序发起非流式对话
+func (c *Client) createChatCompletion(ctx context.Context, provider ModelProvider, model string, req openai.ChatCompletionRequest, failover bool) (openai.ChatCompletionResponse, ServedBy, error) {
+	chain, err := c.failoverChain(provider, model, failover)
 	if err != nil {
-		return nil, err
+		return openai.ChatCompletionResponse{}, ServedBy{}, err
 	}
+	timeout := c.Registry().RetryPolicy().AttemptTimeout
 
-	// 如果没有指定模型，则使用默认模型
-	if model == "" {
-		model = defaultModel
-	}
+	var resp openai.ChatCompletionResponse
+	served, err := c.execute(ctx, chain, func(ctx context.Context, client *openai.Client, t target) error {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		attemptReq := req
+		attemptReq.Model = t.model
+		result, err := client.CreateChatCompletion(attemptCtx, attemptReq)
+		if err != nil {
+			if attemptCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+				return errAttemptTimeout
+			}
+			return err
+		}
+		resp = result
+		return nil
+	})
+	return resp, served, err
+}
+
+// StreamChat 流式对话
+func (c *Client) StreamChat(ctx context.Context, provider ModelProvider, model string, messages []openai.ChatCompletionMessage) (*Stream, ServedBy, error) {
+	return c.StreamChatWithTools(ctx, provider, model, messages, nil)
+}
+
+// StreamChatWithTools 流式对话，tools不为空时允许模型发起工具调用。
+// 在收到第一个数据块之前失败时会重试并切换到备用服务商，之后的错误直接由Recv返回
+func (c *Client) StreamChatWithTools(ctx context.Context, provider ModelProvider, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*Stream, ServedBy, error) {
+	return c.streamChat(ctx, provider, model, messages, tools, true)
+}
+
+// ContinueStream 在同一服务商上继续流式对话，只重试不切换服务商，
+// 用于已经向前端推送过内容的对话（例如工具调用后的下一轮）
+func (c *Client) ContinueStream(ctx context.Context, provider ModelProvider, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool) (*Stream, ServedBy, error) {
+	return c.streamChat(ctx, provider, model, messages, tools, false)
+}
 
-	// 创建流式请求
-	req := openai.ChatCompletionRequest{
-		Model:    model,
-		Messages: messages,
-		Stream:   true,
+// streamChat 按尝试顺序打开流式对话
+func (c *Client) streamChat(ctx context.Context, provider ModelProvider, model string, messages []openai.ChatCompletionMessage, tools []openai.Tool, failover bool) (*Stream, ServedBy, error) {
+	chain, err := c.failoverChain(provider, model, failover)
+	if err != nil {
+		return nil, ServedBy{}, err
 	}
+	timeout := c.Registry().RetryPolicy().FirstTokenTimeout
 
-	return client.CreateChatCompletionStream(ctx, req)
-}
\ No newline at end of file
+	var stream *Stream
+	served, err := c.execute(ctx, chain, func(ctx context.Context, client *openai.Client, t target) error {
+		// 创建流式请求
+		req := openai.ChatCompletionRequest{
+			Model:    t.model,
+			Messages: messages,
+			Stream:   true,
+			Tools:    tools,
+		}
+		s, err := openStream(ctx, client, req, timeout)
+		if err != nil {
+			return err
+		}
+		stream = s
+		return nil
+	})
+	return stream, served, err
+}
@@ -0,0 +1,152 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// BenchmarkTranslateCompletion 模拟翻译接口对同一服务商的连续请求：cached通过Client发起非流式对话，复用缓存的客户端；
+// uncached每次新建go-openai客户端，作为对照
+func BenchmarkTranslateCompletion(b *testing.B) {
+	b.Setenv("STANDIN_API_KEY", "bench-key")
+	server := newStandInProvider(b, "bench-key")
+	registry, err := NewRegistry(RegistryConfig{
+		Providers: []ProviderConfig{{Name: "standin", BaseURL: server.URL + "/v1", APIKeyEnv: "STANDIN_API_KEY", DefaultModel: "standin-chat"}},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	client, err := NewClientWithRegistry(registry)
+	if err != nil {
+		b.Fatal(err)
+	}
+	req := openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: "你是专业的翻译，将用户提供的片段翻译为英文，按JSON数组返回"},
+			{Role: openai.ChatMessageRoleUser, Content: `[{"id":"1","text":"你好，世界"},{"id":"2","text":"欢迎使用翻译服务"}]`},
+		},
+	}
+
+	cached := func() error {
+		_, _, err := client.CreateChatCompletion(context.Background(), "standin", "", req)
+		return err
+	}
+	uncached := func() error {
+		config := openai.DefaultConfig("bench-key")
+		config.BaseURL = server.URL + "/v1"
+		uncachedReq := req
+		uncachedReq.Model = "standin-chat"
+		_, err := openai.NewClientWithConfig(config).CreateChatCompletion(context.Background(), uncachedReq)
+		return err
+	}
+
+	for _, bench := range []struct {
+		name      string
+		translate func() error
+	}{
+		{"cached", cached},
+		{"uncached", uncached},
+	} {
+		b.Run(bench.name+"/serial", func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := bench.translate(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(bench.name+"/parallel", func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetParallelism(16)
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					if err := bench.translate(); err != nil {
+						b.Error(err)
+						return
+					}
+				}
+			})
+		})
+	}
+}
+
+func TestReloadDotEnv(t *testing.T) {
+	t.Chdir(t.TempDir())
+	t.Setenv("RELOAD_SYSTEM_KEY", "system")
+	t.Setenv("RELOAD_DOTENV_KEY", "old")
+	systemEnv["RELOAD_SYSTEM_KEY"] = true
+	t.Cleanup(func() { delete(systemEnv, "RELOAD_SYSTEM_KEY") })
+
+	if err := os.WriteFile(filepath.Join(".", ".env"), []byte("RELOAD_SYSTEM_KEY=file\nRELOAD_DOTENV_KEY=new\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := reloadDotEnv(); err != nil {
+		t.Fatalf("reloadDotEnv: %v", err)
+	}
+	// 与启动时一样系统环境变量优先，由.env设置的变量按新值更新
+	if got := os.Getenv("RELOAD_SYSTEM_KEY"); got != "system" {
+		t.Errorf("RELOAD_SYSTEM_KEY = %q, want the system value", got)
+	}
+	if got := os.Getenv("RELOAD_DOTENV_KEY"); got != "new" {
+		t.Errorf("RELOAD_DOTENV_KEY = %q, want the value from .env", got)
+	}
+}
+
+func TestSampling(t *testing.T) {
+	bodies := make(chan map[string]json.RawMessage, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		var body map[string]json.RawMessage
+		json.Unmarshal(data, &body)
+		bodies <- body
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{Choices: []openai.ChatCompletionChoice{{Message: openai.ChatCompletionMessage{Content: "ok"}}}})
+	}))
+	defer server.Close()
+	registry, err := NewRegistry(RegistryConfig{
+		Providers: []ProviderConfig{{Name: "standin", BaseURL: server.URL + "/v1", DefaultModel: "standin-chat"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, err := NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zero, topP := float32(0), float32(0.5)
+	tests := []struct {
+		name              string
+		ctx               context.Context
+		temperature, topP string // 为空表示请求体中没有该字段
+	}{
+		{"unset", context.Background(), "", ""},
+		{"zero temperature", WithSampling(context.Background(), Sampling{Temperature: &zero}), "0", ""},
+		{"top_p", WithSampling(context.Background(), Sampling{TopP: &topP}), "", "0.5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := openai.ChatCompletionRequest{Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}}}
+			if _, _, err := client.CreateChatCompletion(tt.ctx, "standin", "", req); err != nil {
+				t.Fatalf("CreateChatCompletion: %v", err)
+			}
+			body := <-bodies
+			if got := string(body["temperature"]); got != tt.temperature {
+				t.Errorf("temperature = %q, want %q", got, tt.temperature)
+			}
+			if got := string(body["top_p"]); got != tt.topP {
+				t.Errorf("top_p = %q, want %q", got, tt.topP)
+			}
+			if string(body["model"]) != `"standin-chat"` {
+				t.Errorf("model = %s, the rest of the body should be kept", body["model"])
+			}
+		})
+	}
+}
@@ -0,0 +1,373 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// errAttemptTimeout 单次尝试超时
+var errAttemptTimeout = errors.New("请求大模型超时")
+
+// RetryPolicy 重试与熔断策略
+type RetryPolicy struct {
+	MaxAttempts       int           `yaml:"max_attempts" json:"max_attempts"`               // 每个服务商最多尝试次数
+	BaseDelay         time.Duration `yaml:"base_delay" json:"base_delay"`                   // 首次重试前的等待时间
+	MaxDelay          time.Duration `yaml:"max_delay" json:"max_delay"`                     // 重试等待时间上限
+	AttemptTimeout    time.Duration `yaml:"attempt_timeout" json:"attempt_timeout"`         // 非流式请求单次尝试的超时时间
+	FirstTokenTimeout time.Duration `yaml:"first_token_timeout" json:"first_token_timeout"` // 流式请求等待首个数据块的超时时间
+	BudgetRatio       float64       `yaml:"budget_ratio" json:"budget_ratio"`               // 重试预算：每个请求增加的重试额度
+	BudgetMax         float64       `yaml:"budget_max" json:"budget_max"`                   // 重试额度上限
+	BreakerThreshold  int           `yaml:"breaker_threshold" json:"breaker_threshold"`     // 连续失败多少次后熔断
+	BreakerCooldown   time.Duration `yaml:"breaker_cooldown" json:"breaker_cooldown"`       // 熔断持续时间
+}
+
+// DefaultRetryPolicy 默认的重试与熔断策略
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:       2,
+		BaseDelay:         500 * time.Millisecond,
+		MaxDelay:          5 * time.Second,
+		AttemptTimeout:    2 * time.Minute,
+		FirstTokenTimeout: 30 * time.Second,
+		BudgetRatio:       0.2,
+		BudgetMax:         20,
+		BreakerThreshold:  5,
+		BreakerCooldown:   30 * time.Second,
+	}
+}
+
+// withDefaults 未配置的字段使用默认值
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.BaseDelay <= 0 {
+		p.BaseDelay = d.BaseDelay
+	}
+	if p.MaxDelay <= 0 {
+		p.MaxDelay = d.MaxDelay
+	}
+	if p.AttemptTimeout <= 0 {
+		p.AttemptTimeout = d.AttemptTimeout
+	}
+	if p.FirstTokenTimeout <= 0 {
+		p.FirstTokenTimeout = d.FirstTokenTimeout
+	}
+	if p.BudgetRatio <= 0 {
+		p.BudgetRatio = d.BudgetRatio
+	}
+	if p.BudgetMax <= 0 {
+		p.BudgetMax = d.BudgetMax
+	}
+	if p.BreakerThreshold <= 0 {
+		p.BreakerThreshold = d.BreakerThreshold
+	}
+	if p.BreakerCooldown <= 0 {
+		p.BreakerCooldown = d.BreakerCooldown
+	}
+	return p
+}
+
+// backoff 计算第n次重试前的等待时间，指数增长并加入随机抖动
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < retry && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	// 在[delay/2, delay]之间随机，避免大量请求同时重试
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// ServedBy 实际处理请求的服务商
+type ServedBy struct {
+	Provider ModelProvider `json:"provider"`           // 服务商
+	Model    string        `json:"model"`              // 模型
+	Attempts int           `json:"attempts"`           // 总尝试次数
+	Failover bool          `json:"failover,omitempty"` // 是否切换到了备用服务商
+}
+
+// retryBudget 重试预算，限制重试请求占总请求的比例，避免故障时重试放大流量
+type retryBudget struct {
+	mu     sync.Mutex
+	tokens float64
+}
+
+// deposit 每个新请求增加重试额度
+func (b *retryBudget) deposit(policy RetryPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += policy.BudgetRatio
+	if b.tokens > policy.BudgetMax {
+		b.tokens = policy.BudgetMax
+	}
+}
+
+// withdraw 消耗一次重试额度，额度不足时返回false
+func (b *retryBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// breaker 单个服务商的熔断器，连续失败达到阈值后在冷却期内拒绝请求，冷却结束后允许请求试探
+type breaker struct {
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+}
+
+// allow 判断当前是否允许请求
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return time.Now().After(b.openUntil)
+}
+
+// success 记录成功，关闭熔断
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+}
+
+// failure 记录失败，达到阈值后打开熔断
+func (b *breaker) failure(policy RetryPolicy) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	if b.failures >= policy.BreakerThreshold {
+		b.openUntil = time.Now().Add(policy.BreakerCooldown)
+	}
+}
+
+// target 一次尝试使用的服务商和模型
+type target struct {
+	provider ProviderConfig
+	model    string
+}
+
+// failoverChain 构造尝试顺序：先使用指定的服务商和模型，再依次使用已配置密钥的备用服务商的默认模型
+func (c *Client) failoverChain(provider ModelProvider, model string, failover bool) ([]target, error) {
+	registry := c.Registry()
+	requested, ok := registry.Lookup(string(provider))
+	if !ok {
+		return nil, fmt.Errorf("不支持的服务商: %s", provider)
+	}
+	if model == "" {
+		model = requested.DefaultModel
+	}
+	chain := []target{{provider: requested, model: model}}
+	if !failover {
+		return chain, nil
+	}
+	for _, fallback := range registry.Fallback() {
+		if fallback.Name == requested.Name || !fallback.Configured() {
+			continue
+		}
+		chain = append(chain, target{provider: fallback, model: fallback.DefaultModel})
+	}
+	return chain, nil
+}
+
+// breakerFor 获取服务商的熔断器
+func (c *Client) breakerFor(name string) *breaker {
+	c.breakersMu.Lock()
+	defer c.breakersMu.Unlock()
+	b, ok := c.breakers[name]
+	if !ok {
+		b = &breaker{}
+		c.breakers[name] = b
+	}
+	return b
+}
+
+// execute 按尝试顺序调用fn，遇到429、5xx、超时或网络错误时按退避策略重试或切换到下一个服务商
+func (c *Client) execute(ctx context.Context, chain []target, fn func(ctx context.Context, client *openai.Client, t target) error) (ServedBy, error) {
+	policy := c.Registry().RetryPolicy()
+	c.budget.deposit(policy)
+
+	served := ServedBy{}
+	var lastErr error
+	retries := 0
+	for i, t := range chain {
+		b := c.breakerFor(t.provider.Name)
+		if !b.allow() {
+			lastErr = fmt.Errorf("服务商%s已熔断", t.provider.Name)
+			continue
+		}
+		client, _, err := c.GetClient(ModelProvider(t.provider.Name))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		for attempt := 1; attempt <= policy.MaxAttempts; attempt++ {
+			// 首次请求之外的尝试都需要消耗重试预算
+			if served.Attempts > 0 {
+				if !c.budget.withdraw() {
+					return served, fmt.Errorf("重试预算已耗尽: %v", lastErr)
+				}
+				retries++
+				if err := sleepContext(ctx, policy.backoff(retries)); err != nil {
+					return served, err
+				}
+			}
+
+			served.Attempts++
+			err := fn(ctx, client, t)
+			if err == nil {
+				b.success()
+				served.Provider = ModelProvider(t.provider.Name)
+				served.Model = t.model
+				served.Failover = i > 0
+				return served, nil
+			}
+			lastErr = fmt.Errorf("%s: %v", t.provider.Name, err)
+
+			// 调用方取消或不可重试的错误直接返回
+			if ctx.Err() != nil {
+				return served, ctx.Err()
+			}
+			if !isRetryable(err) {
+				return served, err
+			}
+			b.failure(policy)
+			if !b.allow() {
+				break
+			}
+		}
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("没有可用的服务商")
+	}
+	return served, fmt.Errorf("所有服务商均调用失败: %v", lastErr)
+}
+
+// isRetryable 判断错误是否可以重试或切换服务商：限流、服务端错误、超时和网络错误
+func isRetryable(err error) bool {
+	var apiErr *openai.APIError
+	if errors.As(err, &apiErr) {
+		return retryableStatus(apiErr.HTTPStatusCode)
+	}
+	var reqErr *openai.RequestError
+	if errors.As(err, &reqErr) {
+		return retryableStatus(reqErr.HTTPStatusCode)
+	}
+	if errors.Is(err, errAttemptTimeout) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryableStatus 429和5xx可以重试
+func retryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// sleepContext 等待指定时间，ctx结束时提前返回
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Stream 流式响应，已经预先读取了第一个数据块用于判断服务商是否可用
+type Stream struct {
+	stream *openai.ChatCompletionStream
+	first  *openai.ChatCompletionStreamResponse
+	err    error
+	stop   func()
+}
+
+// Recv 读取下一个数据块
+func (s *Stream) Recv() (openai.ChatCompletionStreamResponse, error) {
+	if s.first != nil {
+		first := *s.first
+		s.first = nil
+		return first, nil
+	}
+	if s.err != nil {
+		return openai.ChatCompletionStreamResponse{}, s.err
+	}
+	return s.stream.Recv()
+}
+
+// Close 关闭流
+func (s *Stream) Close() error {
+	if s.stop != nil {
+		s.stop()
+	}
+	return s.stream.Close()
+}
+
+// openStream 打开流并等待第一个数据块，超时或出错时关闭流并返回错误
+func openStream(ctx context.Context, client *openai.Client, req openai.ChatCompletionRequest, timeout time.Duration) (*Stream, error) {
+	// 流的生命周期超过本次调用，使用可取消的ctx，只在等待首个数据块时计时
+	streamCtx, cancel := context.WithCancel(ctx)
+	var timedOut bool
+	var mu sync.Mutex
+	timer := time.AfterFunc(timeout, func() {
+		mu.Lock()
+		timedOut = true
+		mu.Unlock()
+		cancel()
+	})
+	fail := func(err error) (*Stream, error) {
+		timer.Stop()
+		cancel()
+		mu.Lock()
+		defer mu.Unlock()
+		if timedOut {
+			return nil, errAttemptTimeout
+		}
+		return nil, err
+	}
+
+	stream, err := client.CreateChatCompletionStream(streamCtx, req)
+	if err != nil {
+		return fail(err)
+	}
+	first, err := stream.Recv()
+	if err != nil && !errors.Is(err, io.EOF) {
+		stream.Close()
+		return fail(err)
+	}
+	if !timer.Stop() {
+		// 计时器已经触发，流已被取消
+		stream.Close()
+		return fail(errAttemptTimeout)
+	}
+
+	s := &Stream{stream: stream, stop: cancel}
+	if err != nil {
+		s.err = err
+	} else {
+		s.first = &first
+	}
+	return s, nil
+}
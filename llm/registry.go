@@ -0,0 +1,382 @@
+package llm
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultProvidersFile 未设置LLM_PROVIDERS_FILE时尝试读取的配置文件
+const defaultProvidersFile = "providers.yaml"
+
+// ProviderConfig 大模型服务商配置，所有服务商都使用OpenAI兼容接口
+type ProviderConfig struct {
+	Name         string        `yaml:"name" json:"name"`                         // 服务商名称，同时作为"服务商/模型"格式中的前缀
+	Aliases      []string      `yaml:"aliases" json:"aliases,omitempty"`         // 服务商别名，用于解析模型字符串
+	BaseURL      string        `yaml:"base_url" json:"base_url,omitempty"`       // OpenAI兼容接口地址，为空时使用OpenAI官方地址
+	APIKeyEnv    string        `yaml:"api_key_env" json:"api_key_env,omitempty"` // 保存API密钥的环境变量名，为空表示不需要密钥（例如本地Ollama）
+	DefaultModel string        `yaml:"default_model" json:"default_model"`       // 未指定模型时使用的模型
+	Models       []ModelConfig `yaml:"models" json:"models,omitempty"`           // 服务商提供的模型列表
+}
+
+// ModelCapabilities 模型支持的能力
+type ModelCapabilities struct {
+	Streaming bool `yaml:"streaming" json:"streaming"` // 流式输出
+	Tools     bool `yaml:"tools" json:"tools"`         // 工具调用
+	Vision    bool `yaml:"vision" json:"vision"`       // 图片输入
+	JSONMode  bool `yaml:"json_mode" json:"json_mode"` // JSON输出模式
+}
+
+// ModelConfig 模型的静态元数据。配置文件中可以只写模型ID，此时只声明支持流式输出
+type ModelConfig struct {
+	ID            string            `yaml:"id" json:"id"`                                   // 模型ID，即调用接口时的model参数
+	Aliases       []string          `yaml:"aliases" json:"aliases,omitempty"`               // 模型别名
+	ContextWindow int               `yaml:"context_window" json:"context_window,omitempty"` // 上下文窗口大小（token数）
+	Capabilities  ModelCapabilities `yaml:"capabilities" json:"capabilities"`               // 模型能力，未配置streaming时默认支持
+}
+
+// UnmarshalYAML 支持字符串和对象两种写法
+func (m *ModelConfig) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*m = ModelConfig{ID: value.Value, Capabilities: ModelCapabilities{Streaming: true}}
+		return nil
+	}
+	type plain ModelConfig
+	config := plain{Capabilities: ModelCapabilities{Streaming: true}}
+	if err := value.Decode(&config); err != nil {
+		return err
+	}
+	*m = ModelConfig(config)
+	return nil
+}
+
+// Matches 判断名称是否为该模型的ID或别名，不区分大小写
+func (m ModelConfig) Matches(name string) bool {
+	if strings.EqualFold(m.ID, name) {
+		return true
+	}
+	for _, alias := range m.Aliases {
+		if strings.EqualFold(alias, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FindModel 按ID或别名查找服务商的模型
+func (p ProviderConfig) FindModel(name string) (ModelConfig, bool) {
+	for _, model := range p.Models {
+		if model.Matches(name) {
+			return model, true
+		}
+	}
+	return ModelConfig{}, false
+}
+
+// APIKey 从环境变量读取API密钥
+func (p ProviderConfig) APIKey() string {
+	if p.APIKeyEnv == "" {
+		return ""
+	}
+	return os.Getenv(p.APIKeyEnv)
+}
+
+// Configured 判断服务商是否可用：不需要密钥或已配置密钥
+func (p ProviderConfig) Configured() bool {
+	return p.APIKeyEnv == "" || p.APIKey() != ""
+}
+
+// RegistryConfig 服务商配置文件结构
+type RegistryConfig struct {
+	Default   string           `yaml:"default" json:"default"`     // 默认服务商
+	Fallback  []string         `yaml:"fallback" json:"fallback"`   // 服务商不可用时依次尝试的备用服务商
+	Retry     RetryPolicy      `yaml:"retry" json:"retry"`         // 重试与熔断策略，未配置的字段使用默认值
+	Providers []ProviderConfig `yaml:"providers" json:"providers"` // 服务商列表，与内置服务商同名时覆盖内置配置
+}
+
+// DefaultRegistryConfig 内置的服务商配置
+func DefaultRegistryConfig() RegistryConfig {
+	return RegistryConfig{
+		Default:  string(Qwen),
+		Fallback: []string{string(Qwen), string(DeepSeek), string(Kimi)},
+		Retry:    DefaultRetryPolicy(),
+		Providers: []ProviderConfig{
+			{
+				Name:         string(Qwen),
+				Aliases:      []string{"通义千问", "通义"},
+				BaseURL:      "https://dashscope.aliyuncs.com/compatible-mode/v1",
+				APIKeyEnv:    "QWEN_API_KEY",
+				DefaultModel: "qwen-turbo-latest",
+				Models: []ModelConfig{
+					{ID: "qwen-turbo-latest", Aliases: []string{"qwen-turbo"}, ContextWindow: 1000000, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "qwen-plus", Aliases: []string{"qwen-plus-latest"}, ContextWindow: 131072, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "qwen-max", Aliases: []string{"qwen-max-latest"}, ContextWindow: 32768, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "qwen-long", ContextWindow: 10000000, Capabilities: ModelCapabilities{Streaming: true}},
+					{ID: "qwen-vl-max", ContextWindow: 131072, Capabilities: ModelCapabilities{Streaming: true, Vision: true}},
+				},
+			},
+			{
+				Name:         string(DeepSeek),
+				Aliases:      []string{"深度求索"},
+				BaseURL:      "https://api.deepseek.com/v1",
+				APIKeyEnv:    "DEEPSEEK_API_KEY",
+				DefaultModel: "deepseek-chat",
+				Models: []ModelConfig{
+					{ID: "deepseek-chat", Aliases: []string{"deepseek-v3"}, ContextWindow: 65536, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "deepseek-reasoner", Aliases: []string{"deepseek-r1"}, ContextWindow: 65536, Capabilities: ModelCapabilities{Streaming: true, JSONMode: true}},
+				},
+			},
+			{
+				Name:         string(OpenAI),
+				Aliases:      []string{"open ai", "gpt"},
+				APIKeyEnv:    "OPENAI_API_KEY",
+				DefaultModel: "gpt-4o",
+				Models: []ModelConfig{
+					{ID: "gpt-4o", ContextWindow: 128000, Capabilities: ModelCapabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true}},
+					{ID: "gpt-4o-mini", ContextWindow: 128000, Capabilities: ModelCapabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true}},
+					{ID: "gpt-4.1", ContextWindow: 1047576, Capabilities: ModelCapabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true}},
+					{ID: "gpt-4.1-mini", ContextWindow: 1047576, Capabilities: ModelCapabilities{Streaming: true, Tools: true, Vision: true, JSONMode: true}},
+				},
+			},
+			{
+				Name:         string(Kimi),
+				Aliases:      []string{"moonshot", "月之暗面", "月之"},
+				BaseURL:      "https://api.moonshot.cn/v1",
+				APIKeyEnv:    "KIMI_API_KEY",
+				DefaultModel: "kimi-k2-0711-preview",
+				Models: []ModelConfig{
+					{ID: "kimi-k2-0711-preview", Aliases: []string{"kimi-k2"}, ContextWindow: 131072, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "moonshot-v1-8k", ContextWindow: 8192, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "moonshot-v1-32k", ContextWindow: 32768, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+					{ID: "moonshot-v1-128k", ContextWindow: 131072, Capabilities: ModelCapabilities{Streaming: true, Tools: true, JSONMode: true}},
+				},
+			},
+		},
+	}
+}
+
+// LoadRegistryConfig 加载服务商配置：先使用内置配置，再合并配置文件（YAML或JSON），最后应用环境变量覆盖。
+// path为空时尝试读取当前目录下的providers.yaml，文件不存在时只使用内置配置
+func LoadRegistryConfig(path string) (RegistryConfig, error) {
+	config := DefaultRegistryConfig()
+
+	explicit := path != ""
+	if !explicit {
+		path = defaultProvidersFile
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		// JSON是YAML的子集，两种格式都用YAML解析
+		var fileConfig RegistryConfig
+		if err := yaml.Unmarshal(data, &fileConfig); err != nil {
+			return config, fmt.Errorf("解析服务商配置文件%s失败: %v", path, err)
+		}
+		config = mergeRegistryConfig(config, fileConfig)
+	case explicit || !os.IsNotExist(err):
+		return config, fmt.Errorf("读取服务商配置文件%s失败: %v", path, err)
+	}
+
+	// 环境变量覆盖默认服务商、备用服务商和各服务商的接口地址
+	if provider := os.Getenv("LLM_DEFAULT_PROVIDER"); provider != "" {
+		config.Default = provider
+	}
+	if fallback, ok := os.LookupEnv("LLM_FALLBACK"); ok {
+		config.Fallback = nil
+		for _, name := range strings.Split(fallback, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				config.Fallback = append(config.Fallback, name)
+			}
+		}
+	}
+	for i := range config.Providers {
+		if baseURL := os.Getenv(envPrefix(config.Providers[i].Name) + "_BASE_URL"); baseURL != "" {
+			config.Providers[i].BaseURL = baseURL
+		}
+	}
+	return config, nil
+}
+
+// mergeRegistryConfig 合并配置，同名服务商整体替换，新服务商追加到末尾
+func mergeRegistryConfig(base, override RegistryConfig) RegistryConfig {
+	if override.Default != "" {
+		base.Default = override.Default
+	}
+	if override.Fallback != nil {
+		base.Fallback = override.Fallback
+	}
+	base.Retry = mergeRetryPolicy(base.Retry, override.Retry)
+	for _, provider := range override.Providers {
+		replaced := false
+		for i := range base.Providers {
+			if strings.EqualFold(base.Providers[i].Name, provider.Name) {
+				base.Providers[i] = provider
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			base.Providers = append(base.Providers, provider)
+		}
+	}
+	return base
+}
+
+// mergeRetryPolicy 配置文件中设置了的字段覆盖默认策略
+func mergeRetryPolicy(base, override RetryPolicy) RetryPolicy {
+	if override.MaxAttempts > 0 {
+		base.MaxAttempts = override.MaxAttempts
+	}
+	if override.BaseDelay > 0 {
+		base.BaseDelay = override.BaseDelay
+	}
+	if override.MaxDelay > 0 {
+		base.MaxDelay = override.MaxDelay
+	}
+	if override.AttemptTimeout > 0 {
+		base.AttemptTimeout = override.AttemptTimeout
+	}
+	if override.FirstTokenTimeout > 0 {
+		base.FirstTokenTimeout = override.FirstTokenTimeout
+	}
+	if override.BudgetRatio > 0 {
+		base.BudgetRatio = override.BudgetRatio
+	}
+	if override.BudgetMax > 0 {
+		base.BudgetMax = override.BudgetMax
+	}
+	if override.BreakerThreshold > 0 {
+		base.BreakerThreshold = override.BreakerThreshold
+	}
+	if override.BreakerCooldown > 0 {
+		base.BreakerCooldown = override.BreakerCooldown
+	}
+	return base
+}
+
+// envPrefix 将服务商名称转换为环境变量前缀，例如 zhipu -> ZHIPU
+func envPrefix(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9'):
+			return r
+		default:
+			return '_'
+		}
+	}, name)
+}
+
+// Registry 服务商注册表，按名称和别名查找服务商
+type Registry struct {
+	defaultProvider string
+	fallback        []string
+	policy          RetryPolicy
+	providers       []ProviderConfig
+	index           map[string]int
+}
+
+// NewRegistry 根据配置创建服务商注册表
+func NewRegistry(config RegistryConfig) (*Registry, error) {
+	r := &Registry{
+		policy: config.Retry.withDefaults(),
+		index:  make(map[string]int),
+	}
+	for _, provider := range config.Providers {
+		provider.Name = strings.TrimSpace(provider.Name)
+		if provider.Name == "" {
+			return nil, fmt.Errorf("服务商名称不能为空")
+		}
+		if provider.DefaultModel == "" {
+			return nil, fmt.Errorf("服务商%s未配置default_model", provider.Name)
+		}
+		// 默认模型未出现在模型列表中时补充进去，保证可以按名称解析
+		if _, ok := provider.FindModel(provider.DefaultModel); !ok {
+			provider.Models = append(provider.Models[:len(provider.Models):len(provider.Models)], ModelConfig{ID: provider.DefaultModel, Capabilities: ModelCapabilities{Streaming: true}})
+		}
+
+		i := len(r.providers)
+		for _, key := range append([]string{provider.Name}, provider.Aliases...) {
+			key = strings.ToLower(strings.TrimSpace(key))
+			if key == "" {
+				continue
+			}
+			if other, exists := r.index[key]; exists && other != i {
+				return nil, fmt.Errorf("服务商%s的名称或别名%s与%s重复", provider.Name, key, r.providers[other].Name)
+			}
+			r.index[key] = i
+		}
+		r.providers = append(r.providers, provider)
+	}
+	if len(r.providers) == 0 {
+		return nil, fmt.Errorf("未配置任何大模型服务商")
+	}
+
+	r.defaultProvider = r.providers[0].Name
+	if config.Default != "" {
+		provider, ok := r.Lookup(config.Default)
+		if !ok {
+			return nil, fmt.Errorf("默认服务商%s不存在", config.Default)
+		}
+		r.defaultProvider = provider.Name
+	}
+
+	for _, name := range config.Fallback {
+		provider, ok := r.Lookup(name)
+		if !ok {
+			return nil, fmt.Errorf("备用服务商%s不存在", name)
+		}
+		r.fallback = append(r.fallback, provider.Name)
+	}
+	return r, nil
+}
+
+// Fallback 按顺序返回备用服务商
+func (r *Registry) Fallback() []ProviderConfig {
+	providers := make([]ProviderConfig, 0, len(r.fallback))
+	for _, name := range r.fallback {
+		provider, _ := r.Lookup(name)
+		providers = append(providers, provider)
+	}
+	return providers
+}
+
+// RetryPolicy 返回重试与熔断策略
+func (r *Registry) RetryPolicy() RetryPolicy {
+	return r.policy
+}
+
+// Lookup 按名称或别名查找服务商，不区分大小写
+func (r *Registry) Lookup(name string) (ProviderConfig, bool) {
+	i, ok := r.index[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return ProviderConfig{}, false
+	}
+	return r.providers[i], true
+}
+
+// Default 返回默认服务商
+func (r *Registry) Default() ProviderConfig {
+	provider, _ := r.Lookup(r.defaultProvider)
+	return provider
+}
+
+// Providers 按配置顺序返回全部服务商
+func (r *Registry) Providers() []ProviderConfig {
+	providers := make([]ProviderConfig, len(r.providers))
+	copy(providers, r.providers)
+	return providers
+}
+
+// FindByModel 按ID或别名查找模型，返回第一个包含该模型的服务商
+func (r *Registry) FindByModel(name string) (ProviderConfig, ModelConfig, bool) {
+	for _, provider := range r.providers {
+		if model, ok := provider.FindModel(name); ok {
+			return provider, model, true
+		}
+	}
+	return ProviderConfig{}, ModelConfig{}, false
+}
@@ -0,0 +1,227 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// newStandInProvider 启动OpenAI兼容的替身服务商，/chat/completions回显请求的模型，/models返回remote-model
+func newStandInProvider(t testing.TB, apiKey string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+apiKey {
+			http.Error(w, `{"error":{"message":"invalid api key"}}`, http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/v1/chat/completions":
+			var req openai.ChatCompletionRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+				ID:    "chatcmpl-test",
+				Model: req.Model,
+				Choices: []openai.ChatCompletionChoice{{
+					Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: "reply from " + req.Model},
+					FinishReason: openai.FinishReasonStop,
+				}},
+			})
+		case "/v1/models":
+			json.NewEncoder(w).Encode(openai.ModelsList{Models: []openai.Model{{ID: "remote-model"}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestLoadRegistryConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "providers.yaml")
+	config := `
+default: local
+fallback: [local, qwen]
+retry:
+  max_attempts: 4
+providers:
+  - name: local
+    aliases: [本地]
+    base_url: http://localhost:11434/v1
+    default_model: llama3
+    models:
+      - llama3
+      - id: qwen2.5
+        aliases: [qwen2.5-local]
+        context_window: 32768
+        capabilities: {streaming: true, tools: true}
+  - name: deepseek
+    base_url: https://proxy.example.com/v1
+    api_key_env: DEEPSEEK_API_KEY
+    default_model: deepseek-chat
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("LLM_DEFAULT_PROVIDER", "")
+	t.Setenv("LLM_FALLBACK", "local, deepseek")
+	t.Setenv("LOCAL_BASE_URL", "http://127.0.0.1:8000/v1")
+	t.Setenv("DEEPSEEK_BASE_URL", "")
+
+	loaded, err := LoadRegistryConfig(path)
+	if err != nil {
+		t.Fatalf("LoadRegistryConfig: %v", err)
+	}
+	if loaded.Default != "local" {
+		t.Errorf("Default = %q, want local", loaded.Default)
+	}
+	if strings.Join(loaded.Fallback, ",") != "local,deepseek" {
+		t.Errorf("Fallback = %v, want the LLM_FALLBACK override", loaded.Fallback)
+	}
+	if loaded.Retry.MaxAttempts != 4 || loaded.Retry.BreakerThreshold != DefaultRetryPolicy().BreakerThreshold {
+		t.Errorf("Retry = %+v, want max_attempts merged over the defaults", loaded.Retry)
+	}
+
+	registry, err := NewRegistry(loaded)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	local, ok := registry.Lookup("本地")
+	if !ok || local.Name != "local" || local.BaseURL != "http://127.0.0.1:8000/v1" {
+		t.Errorf("Lookup(本地) = %+v, %v", local, ok)
+	}
+	if !local.Configured() {
+		t.Error("a provider without api_key_env should be configured")
+	}
+	// 只写ID的模型默认支持流式输出
+	if model, ok := local.FindModel("LLAMA3"); !ok || !model.Capabilities.Streaming || model.Capabilities.Tools {
+		t.Errorf("FindModel(LLAMA3) = %+v, %v", model, ok)
+	}
+	if provider, model, ok := registry.FindByModel("qwen2.5-local"); !ok || provider.Name != "local" || model.ContextWindow != 32768 {
+		t.Errorf("FindByModel(qwen2.5-local) = %s %+v %v", provider.Name, model, ok)
+	}
+	// 同名服务商整体替换内置配置，其他内置服务商保留
+	if deepseek, _ := registry.Lookup("deepseek"); deepseek.BaseURL != "https://proxy.example.com/v1" || len(deepseek.Models) != 1 {
+		t.Errorf("deepseek = %+v, want the file entry to replace the builtin one", deepseek)
+	}
+	if _, ok := registry.Lookup("kimi"); !ok {
+		t.Error("builtin kimi provider missing after merge")
+	}
+
+	if _, err := LoadRegistryConfig(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("an explicitly configured file that does not exist should be an error")
+	}
+}
+
+func TestNewRegistryErrors(t *testing.T) {
+	provider := func(name string, aliases ...string) ProviderConfig {
+		return ProviderConfig{Name: name, Aliases: aliases, DefaultModel: "m"}
+	}
+	tests := []struct {
+		name   string
+		config RegistryConfig
+		want   string
+	}{
+		{"empty", RegistryConfig{}, "未配置任何大模型服务商"},
+		{"missing name", RegistryConfig{Providers: []ProviderConfig{{DefaultModel: "m"}}}, "服务商名称不能为空"},
+		{"missing default model", RegistryConfig{Providers: []ProviderConfig{{Name: "a"}}}, "未配置default_model"},
+		{"duplicate alias", RegistryConfig{Providers: []ProviderConfig{provider("a", "x"), provider("b", "X")}}, "重复"},
+		{"unknown default", RegistryConfig{Default: "c", Providers: []ProviderConfig{provider("a")}}, "默认服务商c不存在"},
+		{"unknown fallback", RegistryConfig{Fallback: []string{"c"}, Providers: []ProviderConfig{provider("a")}}, "备用服务商c不存在"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewRegistry(tt.config); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("NewRegistry error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientWithStandInProvider(t *testing.T) {
+	t.Setenv("STANDIN_API_KEY", "test-key")
+	server := newStandInProvider(t, "test-key")
+
+	registry, err := NewRegistry(RegistryConfig{
+		Providers: []ProviderConfig{{
+			Name:         "standin",
+			Aliases:      []string{"替身"},
+			BaseURL:      server.URL + "/v1/",
+			APIKeyEnv:    "STANDIN_API_KEY",
+			DefaultModel: "standin-chat",
+			Models:       []ModelConfig{{ID: "standin-chat", Aliases: []string{"chat"}}},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	client, err := NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatalf("NewClientWithRegistry: %v", err)
+	}
+
+	// 别名、"服务商/模型"格式和/models接口返回的模型都能解析
+	tests := []struct {
+		model string
+		want  string
+	}{
+		{"", "standin-chat"},
+		{"chat", "standin-chat"},
+		{"替身/chat", "standin-chat"},
+		{"standin/remote-model", "remote-model"},
+		{"remote-model", "remote-model"},
+	}
+	for _, tt := range tests {
+		provider, model, err := client.ParseModel(tt.model)
+		if err != nil || provider != "standin" || model != tt.want {
+			t.Errorf("ParseModel(%q) = %s, %s, %v, want standin, %s", tt.model, provider, model, err, tt.want)
+		}
+	}
+	if _, _, err := client.ParseModel("standin/unknown"); err == nil {
+		t.Error("ParseModel(standin/unknown): want error")
+	}
+
+	resp, served, err := client.CreateChatCompletion(context.Background(), "standin", "standin-chat", openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{{Role: openai.ChatMessageRoleUser, Content: "hi"}},
+	})
+	if err != nil {
+		t.Fatalf("CreateChatCompletion: %v", err)
+	}
+	if resp.Choices[0].Message.Content != "reply from standin-chat" || served.Provider != "standin" || served.Failover {
+		t.Errorf("CreateChatCompletion = %+v, served = %+v", resp, served)
+	}
+
+	// 密钥变化后客户端按新配置重建
+	first, _, _ := client.GetClient("standin")
+	again, _, _ := client.GetClient("standin")
+	if first != again {
+		t.Error("GetClient should reuse the cached client while the key is unchanged")
+	}
+	t.Setenv("STANDIN_API_KEY", "rotated")
+	if rebuilt, _, _ := client.GetClient("standin"); rebuilt == first {
+		t.Error("GetClient should rebuild the client after the key changes")
+	}
+}
+
+func TestNewClientWithRegistryRequiresKey(t *testing.T) {
+	t.Setenv("MISSING_API_KEY", "")
+	registry, err := NewRegistry(RegistryConfig{
+		Providers: []ProviderConfig{{Name: "remote", APIKeyEnv: "MISSING_API_KEY", DefaultModel: "m"}},
+	})
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	if _, err := NewClientWithRegistry(registry); err == nil {
+		t.Error("NewClientWithRegistry: want error when no provider has an API key")
+	}
+}
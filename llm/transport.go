@@ -0,0 +1,99 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// sharedTransport 所有服务商共用的HTTP传输层，复用连接并支持HTTP/2
+var sharedTransport = newTransport()
+
+// newTransport 创建针对大模型接口调优的HTTP传输层
+func newTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          200,
+		MaxIdleConnsPerHost:   32,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+}
+
+// Sampling 显式指定的采样参数，nil表示使用服务商默认值，不为nil时原样发送（包括0）。
+// go-openai会省略请求中值为0的temperature和top_p，无法表示"温度为0"，需要通过WithSampling指定
+type Sampling struct {
+	Temperature *float32
+	TopP        *float32
+}
+
+// samplingKey context中保存Sampling的键
+type samplingKey struct{}
+
+// WithSampling 返回携带采样参数的context，使用该context发起的对话请求按sampling设置请求体中的采样参数
+func WithSampling(ctx context.Context, sampling Sampling) context.Context {
+	return context.WithValue(ctx, samplingKey{}, sampling)
+}
+
+// samplingTransport 按请求context中的Sampling改写JSON请求体中的采样参数，其他请求原样发送
+type samplingTransport struct {
+	base http.RoundTripper
+}
+
+func (t samplingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sampling, ok := req.Context().Value(samplingKey{}).(Sampling)
+	if !ok || req.Body == nil || (sampling.Temperature == nil && sampling.TopP == nil) {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err == nil {
+		if sampling.Temperature != nil {
+			fields["temperature"], _ = json.Marshal(*sampling.Temperature)
+		}
+		if sampling.TopP != nil {
+			fields["top_p"], _ = json.Marshal(*sampling.TopP)
+		}
+		if data, err := json.Marshal(fields); err == nil {
+			body = data
+		}
+	}
+
+	rewritten := req.Clone(req.Context())
+	rewritten.Body = io.NopCloser(bytes.NewReader(body))
+	rewritten.ContentLength = int64(len(body))
+	rewritten.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return t.base.RoundTrip(rewritten)
+}
+
+// cachedClient 已创建的服务商客户端，密钥或接口地址变化时需要重建
+type cachedClient struct {
+	client  *openai.Client
+	apiKey  string
+	baseURL string
+}
+
+// matches 判断缓存的客户端是否与当前配置一致
+func (c *cachedClient) matches(apiKey, baseURL string) bool {
+	return c != nil && c.apiKey == apiKey && c.baseURL == baseURL
+}
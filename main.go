@@ -2,6 +2,9 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/aimmetal-tech/wistrans-backend/api"
 	"github.com/aimmetal-tech/wistrans-backend/db"
@@ -21,11 +24,43 @@ func main() {
 	// 创建会话存储实例
 	sessionStore := store.NewSessionStore(db.DB)
 
+	// 创建MCP工具调用审批存储实例
+	approvalStore := store.NewApprovalStore(db.DB)
+
+	// 创建MCP服务器配置存储实例
+	mcpServerStore := store.NewMCPServerStore(db.DB)
+
+	// 创建翻译记忆存储实例
+	memoryStore := store.NewTranslationMemoryStore(db.DB)
+
+	// 创建术语表存储实例
+	glossaryStore := store.NewGlossaryStore(db.DB)
+
+	// 创建翻译质量分数存储实例
+	qualityStore := store.NewQualityStore(db.DB)
+
+	// 创建翻译任务存储实例
+	jobStore := store.NewTranslationJobStore(db.DB)
+
 	// 创建API处理函数实例
-	handlers, err := api.NewHandlers(sessionStore)
+	handlers, err := api.NewHandlers(sessionStore, approvalStore, mcpServerStore, memoryStore, glossaryStore, qualityStore, jobStore)
 	if err != nil {
 		log.Fatal("API处理器初始化失败: ", err)
 	}
+	defer handlers.Close()
+
+	// 收到SIGHUP时重新加载大模型服务商配置和API密钥
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := handlers.LLMClient.Reload(); err != nil {
+				log.Printf("重新加载大模型配置失败: %v", err)
+				continue
+			}
+			log.Println("大模型配置已重新加载")
+		}
+	}()
 
 	// 设置Gin路由
 	app := gin.Default()
@@ -40,11 +75,46 @@ func main() {
 	app.GET("/conversations/history", handlers.GetConversationHistory) // 获取会话历史记录
 	app.GET("/conversations/stream", handlers.StreamConversation)      // 流式对话接口
 
+	// 模型目录接口
+	app.GET("/models", handlers.ListModels) // 可用模型及能力列表
+
 	// 翻译接口
-	app.POST("/translate", handlers.Translate) // 网页翻译接口
+	app.POST("/translate", handlers.Translate)              // 网页翻译接口
+	app.POST("/translate/stream", handlers.TranslateStream) // 流式翻译接口
+
+	// 翻译记忆接口
+	app.GET("/translate/memory", handlers.ListTranslationMemory)           // 翻译记忆列表
+	app.DELETE("/translate/memory", handlers.PurgeTranslationMemory)       // 按条件清理翻译记忆
+	app.GET("/translate/memory/:key", handlers.GetTranslationMemory)       // 获取翻译记忆条目
+	app.DELETE("/translate/memory/:key", handlers.DeleteTranslationMemory) // 删除翻译记忆条目
+	app.GET("/translate/quality", handlers.TranslationQualityStats)        // 按模型统计翻译质量
+
+	// 异步翻译任务接口
+	app.POST("/translate/jobs", handlers.CreateTranslationJob)           // 创建翻译任务
+	app.GET("/translate/jobs/:id", handlers.GetTranslationJob)           // 获取翻译任务进度和结果
+	app.GET("/translate/jobs/:id/file", handlers.DownloadTranslationJob) // 下载翻译后的文件
+	app.DELETE("/translate/jobs/:id", handlers.CancelTranslationJob)     // 取消翻译任务
+
+	// 术语表接口
+	app.GET("/glossaries", handlers.ListGlossaries)                            // 术语表列表
+	app.DELETE("/glossaries/:glossary", handlers.DeleteGlossary)               // 删除术语表
+	app.GET("/glossaries/:glossary/terms", handlers.ListGlossaryTerms)         // 术语列表
+	app.POST("/glossaries/:glossary/terms", handlers.CreateGlossaryTerm)       // 新增术语
+	app.GET("/glossaries/:glossary/terms/:id", handlers.GetGlossaryTerm)       // 获取术语
+	app.PATCH("/glossaries/:glossary/terms/:id", handlers.UpdateGlossaryTerm)  // 修改术语
+	app.DELETE("/glossaries/:glossary/terms/:id", handlers.DeleteGlossaryTerm) // 删除术语
 
 	// MCP接口
-	app.POST("/mcp", handlers.MCP) // MCP服务接口
+	app.POST("/mcp", handlers.MCP)                                   // MCP服务接口
+	app.GET("/mcp/servers", handlers.ListMCPServers)                 // MCP服务器及工具列表
+	app.POST("/mcp/servers", handlers.CreateMCPServer)               // 新增MCP服务器
+	app.GET("/mcp/servers/:name", handlers.GetMCPServer)             // 获取MCP服务器配置
+	app.PATCH("/mcp/servers/:name", handlers.UpdateMCPServer)        // 修改MCP服务器配置
+	app.DELETE("/mcp/servers/:name", handlers.DeleteMCPServer)       // 删除MCP服务器
+	app.GET("/mcp/servers/:name/tools", handlers.ListMCPServerTools) // 指定MCP服务器的工具列表
+	app.GET("/mcp/approvals", handlers.ListMCPApprovals)             // 工具调用审批列表
+	app.GET("/mcp/approvals/:id", handlers.GetMCPApproval)           // 工具调用审批详情
+	app.POST("/mcp/approvals/:id", handlers.DecideMCPApproval)       // 批准或拒绝工具调用
 
 	// Fetch接口
 	app.POST("/fetch", handlers.Fetch) // 网页内容抓取接口
@@ -0,0 +1,257 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Transport MCP传输层，负责收发单条JSON-RPC消息
+type Transport interface {
+	// Start 建立连接（启动进程或打开远程会话）
+	Start(ctx context.Context) error
+	// Send 发送一条JSON-RPC消息
+	Send(ctx context.Context, msg json.RawMessage) error
+	// Messages 返回服务端发来的消息，传输结束时关闭
+	Messages() <-chan json.RawMessage
+	// Close 关闭连接并释放资源
+	Close() error
+}
+
+// Client MCP客户端，在传输层之上实现请求与响应的关联
+type Client struct {
+	name      string
+	transport Transport
+	nextID    atomic.Int64
+
+	mu      sync.Mutex
+	pending map[int64]chan *Response
+	closed  bool
+	done    chan struct{}
+
+	initResult *InitializeResult
+}
+
+// NewClient 创建MCP客户端，name用于日志
+func NewClient(name string, transport Transport) *Client {
+	return &Client{
+		name:      name,
+		transport: transport,
+		pending:   make(map[int64]chan *Response),
+		done:      make(chan struct{}),
+	}
+}
+
+// Start 启动传输层并完成initialize握手
+func (c *Client) Start(ctx context.Context) error {
+	if err := c.transport.Start(ctx); err != nil {
+		return err
+	}
+	go c.readLoop()
+
+	params := InitializeParams{
+		ProtocolVersion: ProtocolVersion,
+		Capabilities:    map[string]interface{}{},
+		ClientInfo:      Implementation{Name: "wistrans-backend", Version: "1.0.0"},
+	}
+	var result InitializeResult
+	if err := c.Call(ctx, "initialize", params, &result); err != nil {
+		c.Close()
+		return fmt.Errorf("MCP初始化失败: %v", err)
+	}
+	c.initResult = &result
+
+	if err := c.Notify(ctx, "notifications/initialized", nil); err != nil {
+		c.Close()
+		return fmt.Errorf("发送initialized通知失败: %v", err)
+	}
+	return nil
+}
+
+// ServerInfo 返回服务端在握手时声明的信息
+func (c *Client) ServerInfo() *InitializeResult {
+	return c.initResult
+}
+
+// Done 在连接结束（进程退出或远程断开）时关闭
+func (c *Client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Alive 判断连接是否仍然可用
+func (c *Client) Alive() bool {
+	select {
+	case <-c.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Call 发送请求并等待响应，result为nil时忽略结果
+func (c *Client) Call(ctx context.Context, method string, params interface{}, result interface{}) error {
+	id := c.nextID.Add(1)
+	ch := make(chan *Response, 1)
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return fmt.Errorf("MCP连接已关闭")
+	}
+	c.pending[id] = ch
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, id)
+		c.mu.Unlock()
+	}()
+
+	data, err := json.Marshal(Request{JSONRPC: "2.0", ID: id, Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("序列化请求失败: %v", err)
+	}
+	if err := c.transport.Send(ctx, data); err != nil {
+		return fmt.Errorf("发送请求失败: %v", err)
+	}
+
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("MCP连接已断开")
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		if result != nil && len(resp.Result) > 0 {
+			if err := json.Unmarshal(resp.Result, result); err != nil {
+				return fmt.Errorf("解析响应失败: %v", err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		// 通知服务端取消请求
+		_ = c.Notify(context.Background(), "notifications/cancelled", map[string]interface{}{
+			"requestId": id,
+			"reason":    ctx.Err().Error(),
+		})
+		return ctx.Err()
+	}
+}
+
+// Notify 发送通知
+func (c *Client) Notify(ctx context.Context, method string, params interface{}) error {
+	data, err := json.Marshal(Notification{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return fmt.Errorf("序列化通知失败: %v", err)
+	}
+	return c.transport.Send(ctx, data)
+}
+
+// ListTools 获取服务端提供的全部工具，自动处理分页
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var params interface{}
+		if cursor != "" {
+			params = map[string]string{"cursor": cursor}
+		}
+		var result ListToolsResult
+		if err := c.Call(ctx, "tools/list", params, &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool 调用工具
+func (c *Client) CallTool(ctx context.Context, name string, arguments map[string]interface{}) (*CallToolResult, error) {
+	var result CallToolResult
+	if err := c.Call(ctx, "tools/call", CallToolParams{Name: name, Arguments: arguments}, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Close 关闭连接
+func (c *Client) Close() error {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.closed = true
+	c.mu.Unlock()
+	return c.transport.Close()
+}
+
+// readLoop 读取服务端消息并分发给等待中的请求
+func (c *Client) readLoop() {
+	defer func() {
+		c.mu.Lock()
+		c.closed = true
+		for id, ch := range c.pending {
+			close(ch)
+			delete(c.pending, id)
+		}
+		c.mu.Unlock()
+		close(c.done)
+	}()
+
+	for data := range c.transport.Messages() {
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			log.Printf("[MCP %s] 无法解析消息: %v", c.name, err)
+			continue
+		}
+
+		switch {
+		case msg.Method != "" && len(msg.ID) > 0:
+			// 服务端发起的请求。回复可能把新的消息投递到消息通道，在读取循环中同步发送会阻塞读取循环
+			go c.handleServerRequest(msg)
+		case msg.Method != "":
+			// 服务端通知，目前只记录日志消息
+			if msg.Method == "notifications/message" {
+				log.Printf("[MCP %s] %s", c.name, string(msg.Params))
+			}
+		default:
+			id, err := strconv.ParseInt(string(msg.ID), 10, 64)
+			if err != nil {
+				continue
+			}
+			c.mu.Lock()
+			ch, ok := c.pending[id]
+			delete(c.pending, id)
+			c.mu.Unlock()
+			if ok {
+				ch <- &Response{JSONRPC: msg.JSONRPC, ID: msg.ID, Result: msg.Result, Error: msg.Error}
+			}
+		}
+	}
+}
+
+// handleServerRequest 响应服务端发起的请求，仅支持ping
+func (c *Client) handleServerRequest(msg message) {
+	resp := Response{JSONRPC: "2.0", ID: msg.ID}
+	if msg.Method == "ping" {
+		resp.Result = json.RawMessage("{}")
+	} else {
+		resp.Error = &RPCError{Code: ErrCodeMethodNotFound, Message: "不支持的方法: " + msg.Method}
+	}
+	data, err := json.Marshal(resp)
+	if err != nil {
+		return
+	}
+	if err := c.transport.Send(context.Background(), data); err != nil {
+		log.Printf("[MCP %s] 响应服务端请求失败: %v", c.name, err)
+	}
+}
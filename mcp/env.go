@@ -0,0 +1,79 @@
+package mcp
+
+import (
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// placeholderPattern 匹配${VAR}形式的占位符
+var placeholderPattern = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ExpandPlaceholders 展开字符串中的${VAR}，只使用vars中的值，未定义的变量展开为空字符串
+func ExpandPlaceholders(value string, vars map[string]string) string {
+	return expand(value, func(name string) string { return vars[name] })
+}
+
+// expandSystemEnv 展开字符串中的${VAR}为系统环境变量的值
+func expandSystemEnv(value string) string {
+	return expand(value, os.Getenv)
+}
+
+// expand 用lookup返回的值替换字符串中的占位符
+func expand(value string, lookup func(name string) string) string {
+	return placeholderPattern.ReplaceAllStringFunc(value, func(match string) string {
+		return lookup(placeholderPattern.FindStringSubmatch(match)[1])
+	})
+}
+
+// ResolvedServer 展开占位符后可直接启动的服务器配置
+type ResolvedServer struct {
+	Command string
+	Args    []string
+	Env     map[string]string
+	URL     string
+	Headers map[string]string
+}
+
+// EnvList 将环境变量转换为按键排序的KEY=VALUE列表
+func (r ResolvedServer) EnvList() []string {
+	keys := make([]string, 0, len(r.Env))
+	for key := range r.Env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+r.Env[key])
+	}
+	return env
+}
+
+// ResolveServer 展开服务器配置中的占位符：只有Env中的值可以引用系统环境变量，
+// Command、Args、URL和Headers只能引用Env中定义的变量，避免通过占位符读取任意的系统环境变量
+func ResolveServer(server models.MCPServer) ResolvedServer {
+	env := make(map[string]string, len(server.Env))
+	for key, value := range server.Env {
+		env[key] = expandSystemEnv(value)
+	}
+
+	args := make([]string, len(server.Args))
+	for i, arg := range server.Args {
+		args[i] = ExpandPlaceholders(arg, env)
+	}
+
+	headers := make(map[string]string, len(server.Headers))
+	for key, value := range server.Headers {
+		headers[key] = ExpandPlaceholders(value, env)
+	}
+
+	return ResolvedServer{
+		Command: ExpandPlaceholders(server.Command, env),
+		Args:    args,
+		Env:     env,
+		URL:     ExpandPlaceholders(server.URL, env),
+		Headers: headers,
+	}
+}
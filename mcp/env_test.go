@@ -0,0 +1,36 @@
+package mcp
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+func TestResolveServer(t *testing.T) {
+	t.Setenv("MCP_TEST_KEY", "secret")
+	t.Setenv("MCP_TEST_OTHER", "leaked")
+
+	resolved := ResolveServer(models.MCPServer{
+		Command: "${BIN}",
+		Args:    []string{"--token=${TOKEN}", "--other=${MCP_TEST_OTHER}"},
+		Env:     map[string]string{"TOKEN": "Bearer ${MCP_TEST_KEY}", "BIN": "server"},
+		URL:     "https://example.com/${MCP_TEST_OTHER}",
+		Headers: map[string]string{"Authorization": "${TOKEN}", "X-Leak": "${MCP_TEST_OTHER}"},
+	})
+
+	// 只有env中的值引用系统环境变量，其他字段引用未在env中定义的变量时展开为空字符串
+	want := ResolvedServer{
+		Command: "server",
+		Args:    []string{"--token=Bearer secret", "--other="},
+		Env:     map[string]string{"TOKEN": "Bearer secret", "BIN": "server"},
+		URL:     "https://example.com/",
+		Headers: map[string]string{"Authorization": "Bearer secret", "X-Leak": ""},
+	}
+	if !reflect.DeepEqual(resolved, want) {
+		t.Errorf("ResolveServer = %+v, want %+v", resolved, want)
+	}
+	if env := resolved.EnvList(); !reflect.DeepEqual(env, []string{"BIN=server", "TOKEN=Bearer secret"}) {
+		t.Errorf("EnvList = %v", env)
+	}
+}
@@ -0,0 +1,175 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// StreamableHTTPTransport MCP的Streamable HTTP传输：每条消息POST到同一个端点，
+// 响应体可能是JSON，也可能是携带一条或多条消息的SSE流
+type StreamableHTTPTransport struct {
+	endpoint   string
+	headers    map[string]string
+	httpClient *http.Client
+
+	mu        sync.RWMutex
+	sessionID string
+	closed    bool
+	inflight  sync.WaitGroup
+	messages  chan json.RawMessage
+	closeOnce sync.Once
+}
+
+// NewStreamableHTTPTransport 创建Streamable HTTP传输，headers会附加到所有请求上
+func NewStreamableHTTPTransport(endpoint string, headers map[string]string, httpClient *http.Client) *StreamableHTTPTransport {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &StreamableHTTPTransport{
+		endpoint:   endpoint,
+		headers:    headers,
+		httpClient: httpClient,
+		messages:   make(chan json.RawMessage, 16),
+	}
+}
+
+// Start Streamable HTTP不需要预先建立连接
+func (t *StreamableHTTPTransport) Start(ctx context.Context) error {
+	return nil
+}
+
+// Send 发送消息，并把同步返回的响应投递到消息通道
+func (t *StreamableHTTPTransport) Send(ctx context.Context, msg json.RawMessage) error {
+	t.mu.RLock()
+	if t.closed {
+		t.mu.RUnlock()
+		return fmt.Errorf("MCP连接已关闭")
+	}
+	sessionID := t.sessionID
+	t.inflight.Add(1)
+	t.mu.RUnlock()
+	defer t.inflight.Done()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(msg))
+	if err != nil {
+		return fmt.Errorf("创建消息请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set("Mcp-Session-Id", sessionID)
+	}
+	setHeaders(req, t.headers)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送消息失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		io.Copy(io.Discard, resp.Body)
+		return fmt.Errorf("MCP端点返回异常状态码: %d", resp.StatusCode)
+	}
+
+	// initialize的响应中会下发会话ID
+	if id := resp.Header.Get("Mcp-Session-Id"); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+
+	if resp.StatusCode == http.StatusAccepted {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+
+	contentType := strings.ToLower(resp.Header.Get("Content-Type"))
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return readSSE(resp.Body, func(event sseEvent) bool {
+			if event.Event == "message" {
+				t.deliver([]byte(event.Data))
+			}
+			return true
+		})
+	case strings.HasPrefix(contentType, "application/json"):
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxMessageSize))
+		if err != nil {
+			return fmt.Errorf("读取响应失败: %v", err)
+		}
+		t.deliver(body)
+	}
+	return nil
+}
+
+// Messages 返回服务端响应的消息
+func (t *StreamableHTTPTransport) Messages() <-chan json.RawMessage {
+	return t.messages
+}
+
+// Close 结束会话，等待进行中的请求返回后关闭消息通道
+func (t *StreamableHTTPTransport) Close() error {
+	t.closeOnce.Do(func() {
+		t.mu.Lock()
+		t.closed = true
+		sessionID := t.sessionID
+		t.mu.Unlock()
+
+		if sessionID != "" {
+			// 通知服务端释放会话，失败时忽略
+			if req, err := http.NewRequest(http.MethodDelete, t.endpoint, nil); err == nil {
+				req.Header.Set("Mcp-Session-Id", sessionID)
+				setHeaders(req, t.headers)
+				if resp, err := t.httpClient.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}
+
+		t.inflight.Wait()
+		close(t.messages)
+	})
+	return nil
+}
+
+// deliver 投递消息，支持JSON-RPC批量响应
+func (t *StreamableHTTPTransport) deliver(data []byte) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || !json.Valid(data) {
+		return
+	}
+	if data[0] == '[' {
+		var batch []json.RawMessage
+		if err := json.Unmarshal(data, &batch); err == nil {
+			for _, item := range batch {
+				t.messages <- item
+			}
+		}
+		return
+	}
+	t.messages <- json.RawMessage(data)
+}
+
+// NewRemoteTransport 根据transport类型创建远程传输，未指定时以/sse结尾的地址使用SSE传输
+func NewRemoteTransport(endpoint, transport string, headers map[string]string, httpClient *http.Client) (Transport, error) {
+	switch transport {
+	case "sse":
+		return NewSSETransport(endpoint, headers, httpClient), nil
+	case "streamable-http", "http":
+		return NewStreamableHTTPTransport(endpoint, headers, httpClient), nil
+	case "":
+		if strings.HasSuffix(strings.TrimRight(endpoint, "/"), "/sse") {
+			return NewSSETransport(endpoint, headers, httpClient), nil
+		}
+		return NewStreamableHTTPTransport(endpoint, headers, httpClient), nil
+	default:
+		return nil, fmt.Errorf("不支持的MCP传输类型: %s", transport)
+	}
+}
@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestStreamableHTTPServerRequest(t *testing.T) {
+	// tools/list的响应流中先发起ping，收到回复后才返回结果；ping回复的响应流中的通知超过消息通道的容量
+	pinged := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		var msg message
+		json.Unmarshal(body, &msg)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch {
+		case msg.Method == "tools/list":
+			fmt.Fprint(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"id\":\"srv-1\",\"method\":\"ping\"}\n\n")
+			w.(http.Flusher).Flush()
+			select {
+			case <-pinged:
+			case <-r.Context().Done():
+				return
+			}
+		case msg.Method == "" && string(msg.ID) == `"srv-1"`:
+			close(pinged)
+			for i := 0; i < 32; i++ {
+				fmt.Fprintf(w, "event: message\ndata: {\"jsonrpc\":\"2.0\",\"method\":\"notifications/progress\",\"params\":{\"progress\":%d}}\n\n", i)
+			}
+			return
+		}
+		if resp := fakeServerHandle(body); resp != nil {
+			data, _ := json.Marshal(resp)
+			fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	client := NewClient("fake-http", NewStreamableHTTPTransport(server.URL, nil, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer client.Close()
+
+	var result ListToolsResult
+	if err := client.Call(ctx, "tools/list", nil, &result); err != nil {
+		t.Fatalf("tools/list: %v", err)
+	}
+	if len(result.Tools) != 1 || result.Tools[0].Name != "echo" {
+		t.Errorf("tools/list = %+v", result)
+	}
+}
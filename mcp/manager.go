@@ -0,0 +1,368 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+const (
+	// DefaultStartTimeout 启动服务器并完成握手的超时时间
+	DefaultStartTimeout = 60 * time.Second
+	// minRestartDelay 首次重启前的等待时间
+	minRestartDelay = time.Second
+	// maxRestartDelay 重启等待时间上限
+	maxRestartDelay = 30 * time.Second
+	// stableDuration 连续运行超过该时间后重置重启退避
+	stableDuration = time.Minute
+)
+
+// session 单个MCP服务器的运行状态
+type session struct {
+	mu           sync.Mutex
+	client       *Client
+	tools        []Tool
+	startedAt    time.Time
+	failures     int
+	nextAttempt  time.Time
+	lastError    error
+	restartCount int
+	starting     chan struct{} // 正在启动时非nil，启动结束后关闭
+	closed       bool          // 配置变更或管理器关闭后不再保存新启动的客户端
+}
+
+// ServerStatus MCP服务器的运行状态
+type ServerStatus struct {
+	Name      string `json:"name"`                 // 服务器名称
+	Running   bool   `json:"running"`              // 是否正在运行
+	Restarts  int    `json:"restarts"`             // 重启次数
+	LastError string `json:"last_error,omitempty"` // 最近一次错误
+}
+
+// Manager 按需启动MCP服务器，并在进程退出后按退避策略重启
+type Manager struct {
+	StartTimeout time.Duration
+
+	mu       sync.Mutex
+	servers  map[string]models.MCPServer
+	sessions map[string]*session
+}
+
+// NewManager 创建MCP服务器管理器
+func NewManager(servers map[string]models.MCPServer) *Manager {
+	m := &Manager{
+		StartTimeout: DefaultStartTimeout,
+		sessions:     make(map[string]*session),
+	}
+	m.SetServers(servers)
+	return m
+}
+
+// SetServers 替换服务器配置，配置发生变化的服务器会被关闭并在下次使用时重新启动
+func (m *Manager) SetServers(servers map[string]models.MCPServer) {
+	m.mu.Lock()
+	var stale []*session
+	for name, s := range m.sessions {
+		if newServer, ok := servers[name]; !ok || !sameServer(m.servers[name], newServer) {
+			stale = append(stale, s)
+			delete(m.sessions, name)
+		}
+	}
+
+	m.servers = make(map[string]models.MCPServer, len(servers))
+	for name, server := range servers {
+		m.servers[name] = server
+	}
+	m.mu.Unlock()
+
+	// 关闭连接可能需要等待进行中的请求，在释放锁之后进行
+	for _, s := range stale {
+		s.close()
+	}
+}
+
+// Servers 返回当前的服务器配置
+func (m *Manager) Servers() map[string]models.MCPServer {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	servers := make(map[string]models.MCPServer, len(m.servers))
+	for name, server := range m.servers {
+		servers[name] = server
+	}
+	return servers
+}
+
+// Server 获取单个服务器配置
+func (m *Manager) Server(name string) (models.MCPServer, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	server, ok := m.servers[name]
+	return server, ok
+}
+
+// Names 返回按名称排序的服务器列表
+func (m *Manager) Names() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	names := make([]string, 0, len(m.servers))
+	for name := range m.servers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client 获取已连接的客户端，服务器未启动或已退出时自动(重新)启动
+func (m *Manager) Client(ctx context.Context, name string) (*Client, error) {
+	m.mu.Lock()
+	server, ok := m.servers[name]
+	if !ok {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("未配置MCP服务器: %s", name)
+	}
+	if server.Disabled {
+		m.mu.Unlock()
+		return nil, fmt.Errorf("MCP服务器已禁用: %s", name)
+	}
+	s, ok := m.sessions[name]
+	if !ok {
+		s = &session{}
+		m.sessions[name] = s
+	}
+	m.mu.Unlock()
+
+	// 启动过程不持有锁，同一服务器的并发调用等待正在进行的启动完成
+	for {
+		s.mu.Lock()
+		if s.starting == nil {
+			break
+		}
+		starting := s.starting
+		s.mu.Unlock()
+		select {
+		case <-starting:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	if s.closed {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("MCP服务器%s的配置已变更", name)
+	}
+	if s.client != nil && s.client.Alive() {
+		client := s.client
+		s.mu.Unlock()
+		return client, nil
+	}
+
+	if s.client != nil {
+		// 进程已退出或远程连接已断开，根据运行时长决定是否重置退避
+		if time.Since(s.startedAt) > stableDuration {
+			s.failures = 0
+			s.nextAttempt = time.Time{}
+		}
+		s.client = nil
+		s.tools = nil
+		s.restartCount++
+	}
+
+	if wait := time.Until(s.nextAttempt); wait > 0 {
+		err := fmt.Errorf("MCP服务器%s启动失败，%v后重试: %v", name, wait.Round(time.Second), s.lastError)
+		s.mu.Unlock()
+		return nil, err
+	}
+
+	starting := make(chan struct{})
+	s.starting = starting
+	s.mu.Unlock()
+
+	client, err := m.startClient(ctx, name, server)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.starting = nil
+	close(starting)
+
+	if err != nil {
+		s.failures++
+		s.lastError = err
+		s.nextAttempt = time.Now().Add(restartDelay(s.failures))
+		return nil, err
+	}
+	if s.closed {
+		// 启动期间配置已变更或管理器已关闭
+		client.Close()
+		return nil, fmt.Errorf("MCP服务器%s的配置已变更", name)
+	}
+
+	s.client = client
+	s.startedAt = time.Now()
+	s.lastError = nil
+	return client, nil
+}
+
+// close 关闭会话的客户端，正在进行的启动完成后也会被关闭
+func (s *session) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.client != nil {
+		s.client.Close()
+	}
+}
+
+// Tools 获取服务器提供的工具列表，结果在连接存活期间缓存
+func (m *Manager) Tools(ctx context.Context, name string) ([]Tool, error) {
+	client, err := m.Client(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	s := m.sessions[name]
+	m.mu.Unlock()
+	if s == nil {
+		return nil, fmt.Errorf("MCP服务器%s已被移除", name)
+	}
+
+	s.mu.Lock()
+	if s.client == client && s.tools != nil {
+		tools := s.tools
+		s.mu.Unlock()
+		return tools, nil
+	}
+	s.mu.Unlock()
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("获取MCP服务器%s的工具列表失败: %v", name, err)
+	}
+	if tools == nil {
+		tools = []Tool{}
+	}
+
+	s.mu.Lock()
+	if s.client == client {
+		s.tools = tools
+	}
+	s.mu.Unlock()
+	return tools, nil
+}
+
+// CallTool 调用指定服务器上的工具
+func (m *Manager) CallTool(ctx context.Context, server, tool string, arguments map[string]interface{}) (*CallToolResult, error) {
+	client, err := m.Client(ctx, server)
+	if err != nil {
+		return nil, err
+	}
+	return client.CallTool(ctx, tool, arguments)
+}
+
+// Status 返回所有服务器的运行状态
+func (m *Manager) Status() []ServerStatus {
+	names := m.Names()
+	statuses := make([]ServerStatus, 0, len(names))
+	for _, name := range names {
+		status := ServerStatus{Name: name}
+		m.mu.Lock()
+		s, ok := m.sessions[name]
+		m.mu.Unlock()
+		if ok {
+			s.mu.Lock()
+			status.Running = s.client != nil && s.client.Alive()
+			status.Restarts = s.restartCount
+			if s.lastError != nil {
+				status.LastError = s.lastError.Error()
+			}
+			s.mu.Unlock()
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// Close 关闭所有服务器，在释放锁之后关闭连接
+func (m *Manager) Close() {
+	m.mu.Lock()
+	sessions := m.sessions
+	m.sessions = make(map[string]*session)
+	m.mu.Unlock()
+
+	for _, s := range sessions {
+		s.close()
+	}
+}
+
+// startClient 根据配置创建传输层并完成握手
+func (m *Manager) startClient(ctx context.Context, name string, server models.MCPServer) (*Client, error) {
+	resolved := ResolveServer(server)
+
+	var transport Transport
+	switch {
+	case resolved.URL != "":
+		remote, err := NewRemoteTransport(resolved.URL, server.Transport, resolved.Headers, nil)
+		if err != nil {
+			return nil, err
+		}
+		transport = remote
+	case resolved.Command != "":
+		transport = NewStdioTransport(name, resolved.Command, resolved.Args, resolved.EnvList())
+	default:
+		return nil, fmt.Errorf("MCP服务器%s未配置启动命令或URL", name)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, m.StartTimeout)
+	defer cancel()
+
+	client := NewClient(name, transport)
+	if err := client.Start(ctx); err != nil {
+		return nil, fmt.Errorf("启动MCP服务器%s失败: %v", name, err)
+	}
+	return client, nil
+}
+
+// restartDelay 计算指数退避的重启等待时间
+func restartDelay(failures int) time.Duration {
+	delay := minRestartDelay
+	for i := 1; i < failures && delay < maxRestartDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxRestartDelay {
+		delay = maxRestartDelay
+	}
+	return delay
+}
+
+// sameServer 判断两份配置的连接参数是否一致
+func sameServer(a, b models.MCPServer) bool {
+	if a.Command != b.Command || a.URL != b.URL || a.Transport != b.Transport || a.Disabled != b.Disabled {
+		return false
+	}
+	if len(a.Args) != len(b.Args) {
+		return false
+	}
+	for i := range a.Args {
+		if a.Args[i] != b.Args[i] {
+			return false
+		}
+	}
+	return sameStringMap(a.Env, b.Env) && sameStringMap(a.Headers, b.Headers)
+}
+
+// sameStringMap 判断两个字符串映射是否相同
+func sameStringMap(a, b map[string]string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for key, value := range a {
+		if other, ok := b[key]; !ok || other != value {
+			return false
+		}
+	}
+	return true
+}
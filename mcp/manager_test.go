@@ -0,0 +1,68 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+func TestManagerStartDoesNotHoldLock(t *testing.T) {
+	// 握手一直挂起直到测试结束
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	m := NewManager(map[string]models.MCPServer{
+		"slow": {Name: "slow", URL: server.URL + "/sse", Transport: "sse"},
+	})
+	defer m.Close()
+
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := m.Client(context.Background(), "slow")
+			errs <- err
+		}()
+	}
+
+	// 启动期间查询状态和修改配置不会被阻塞
+	time.Sleep(100 * time.Millisecond)
+	done := make(chan struct{})
+	go func() {
+		m.Status()
+		m.SetServers(map[string]models.MCPServer{})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Status/SetServers blocked while the server was starting")
+	}
+
+	// 配置变更后正在进行的启动结果被丢弃
+	server.CloseClientConnections()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err == nil {
+				t.Error("Client: want error after the configuration changed")
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("Client did not return")
+		}
+	}
+	if _, err := m.Client(context.Background(), "slow"); err == nil || !strings.Contains(err.Error(), "未配置MCP服务器") {
+		t.Errorf("Client after removal error = %v", err)
+	}
+}
@@ -0,0 +1,133 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion 客户端使用的MCP协议版本
+const ProtocolVersion = "2024-11-05"
+
+// JSON-RPC 2.0 标准错误码
+const (
+	ErrCodeParse          = -32700
+	ErrCodeInvalidRequest = -32600
+	ErrCodeMethodNotFound = -32601
+	ErrCodeInvalidParams  = -32602
+	ErrCodeInternal       = -32603
+)
+
+// Request JSON-RPC请求
+type Request struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      int64       `json:"id"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Notification JSON-RPC通知（没有id，不需要响应）
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// Response JSON-RPC响应
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// RPCError JSON-RPC错误
+type RPCError struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Error 实现error接口
+func (e *RPCError) Error() string {
+	return fmt.Sprintf("MCP错误 %d: %s", e.Code, e.Message)
+}
+
+// message 用于区分请求、通知和响应的通用消息结构
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *RPCError       `json:"error,omitempty"`
+}
+
+// Implementation 客户端或服务端的实现信息
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// InitializeParams initialize请求参数
+type InitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// InitializeResult initialize响应结果
+type InitializeResult struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ServerInfo      Implementation         `json:"serverInfo"`
+	Instructions    string                 `json:"instructions,omitempty"`
+}
+
+// Tool MCP工具定义
+type Tool struct {
+	Name        string          `json:"name"`                  // 工具名称
+	Description string          `json:"description,omitempty"` // 工具描述
+	InputSchema json.RawMessage `json:"inputSchema,omitempty"` // 参数的JSON Schema
+}
+
+// ListToolsResult tools/list响应结果
+type ListToolsResult struct {
+	Tools      []Tool `json:"tools"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// CallToolParams tools/call请求参数
+type CallToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// Content 工具返回的内容块
+type Content struct {
+	Type     string          `json:"type"`               // text, image, resource等
+	Text     string          `json:"text,omitempty"`     // 文本内容
+	Data     string          `json:"data,omitempty"`     // base64编码的数据
+	MimeType string          `json:"mimeType,omitempty"` // 数据的MIME类型
+	Resource json.RawMessage `json:"resource,omitempty"` // 嵌入的资源
+}
+
+// CallToolResult tools/call响应结果
+type CallToolResult struct {
+	Content []Content `json:"content"`
+	IsError bool      `json:"isError,omitempty"`
+}
+
+// Text 拼接结果中的全部文本内容
+func (r *CallToolResult) Text() string {
+	var text string
+	for _, content := range r.Content {
+		if content.Type != "text" || content.Text == "" {
+			continue
+		}
+		if text != "" {
+			text += "\n"
+		}
+		text += content.Text
+	}
+	return text
+}
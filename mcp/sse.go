@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// sseEvent 一条Server-Sent Events事件
+type sseEvent struct {
+	Event string
+	Data  string
+	ID    string
+}
+
+// readSSE 逐条解析SSE事件，fn返回false时停止读取
+func readSSE(r io.Reader, fn func(sseEvent) bool) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+
+	var event sseEvent
+	var data []string
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if line == "" {
+			// 空行表示一条事件结束
+			if len(data) > 0 {
+				event.Data = strings.Join(data, "\n")
+				if event.Event == "" {
+					event.Event = "message"
+				}
+				if !fn(event) {
+					return nil
+				}
+			}
+			event, data = sseEvent{}, nil
+			continue
+		}
+		if strings.HasPrefix(line, ":") {
+			// 注释行，通常是心跳
+			continue
+		}
+		field, value, _ := strings.Cut(line, ":")
+		value = strings.TrimPrefix(value, " ")
+		switch field {
+		case "event":
+			event.Event = value
+		case "data":
+			data = append(data, value)
+		case "id":
+			event.ID = value
+		}
+	}
+	return scanner.Err()
+}
+
+// SSETransport MCP的HTTP+SSE传输：GET建立事件流并获得消息端点，
+// 请求通过POST发送到消息端点，响应从事件流中返回
+type SSETransport struct {
+	endpoint   string
+	headers    map[string]string
+	httpClient *http.Client
+
+	messageURL string
+	ready      chan struct{}
+	done       chan struct{}
+	messages   chan json.RawMessage
+	cancel     context.CancelFunc
+	closeOnce  sync.Once
+	errMu      sync.Mutex
+	err        error
+}
+
+// NewSSETransport 创建SSE传输，headers会附加到所有请求上
+func NewSSETransport(endpoint string, headers map[string]string, httpClient *http.Client) *SSETransport {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &SSETransport{
+		endpoint:   endpoint,
+		headers:    headers,
+		httpClient: httpClient,
+		ready:      make(chan struct{}),
+		done:       make(chan struct{}),
+		messages:   make(chan json.RawMessage, 16),
+	}
+}
+
+// Start 打开事件流并等待服务端下发消息端点
+func (t *SSETransport) Start(ctx context.Context) error {
+	// 事件流需要在整个会话期间保持，不能使用调用方的ctx
+	streamCtx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+
+	req, err := http.NewRequestWithContext(streamCtx, http.MethodGet, t.endpoint, nil)
+	if err != nil {
+		cancel()
+		return fmt.Errorf("创建SSE请求失败: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Cache-Control", "no-cache")
+	setHeaders(req, t.headers)
+
+	// 连接失败时要能被ctx中断
+	stop := context.AfterFunc(ctx, cancel)
+	resp, err := t.httpClient.Do(req)
+	stop()
+	if err != nil {
+		cancel()
+		return fmt.Errorf("连接SSE端点失败: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		cancel()
+		return fmt.Errorf("SSE端点返回异常状态码: %d", resp.StatusCode)
+	}
+
+	go t.readStream(resp.Body)
+
+	select {
+	case <-t.ready:
+		return nil
+	case <-t.done:
+		return fmt.Errorf("SSE事件流在下发消息端点前已结束: %v", t.Err())
+	case <-ctx.Done():
+		t.Close()
+		return fmt.Errorf("等待消息端点超时: %v", ctx.Err())
+	}
+}
+
+// Send 将消息POST到服务端下发的消息端点
+func (t *SSETransport) Send(ctx context.Context, msg json.RawMessage) error {
+	select {
+	case <-t.done:
+		return fmt.Errorf("SSE事件流已断开")
+	default:
+	}
+	select {
+	case <-t.ready:
+	case <-t.done:
+		return fmt.Errorf("SSE事件流已断开")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.messageURL, bytes.NewReader(msg))
+	if err != nil {
+		return fmt.Errorf("创建消息请求失败: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	setHeaders(req, t.headers)
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("发送消息失败: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("消息端点返回异常状态码: %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Messages 返回事件流中的JSON-RPC消息
+func (t *SSETransport) Messages() <-chan json.RawMessage {
+	return t.messages
+}
+
+// Close 断开事件流
+func (t *SSETransport) Close() error {
+	t.closeOnce.Do(func() {
+		if t.cancel != nil {
+			t.cancel()
+		} else {
+			close(t.messages)
+		}
+	})
+	return nil
+}
+
+// Err 返回事件流异常结束的原因
+func (t *SSETransport) Err() error {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	return t.err
+}
+
+// readStream 读取事件流：endpoint事件给出消息端点，message事件携带JSON-RPC消息
+func (t *SSETransport) readStream(body io.ReadCloser) {
+	defer close(t.messages)
+	defer close(t.done)
+	defer body.Close()
+
+	endpointKnown := false
+	err := readSSE(body, func(event sseEvent) bool {
+		switch event.Event {
+		case "endpoint":
+			if endpointKnown {
+				return true
+			}
+			messageURL, err := resolveEndpoint(t.endpoint, event.Data)
+			if err != nil {
+				t.setErr(err)
+				return false
+			}
+			t.messageURL = messageURL
+			endpointKnown = true
+			close(t.ready)
+		case "message":
+			data := strings.TrimSpace(event.Data)
+			if json.Valid([]byte(data)) {
+				t.messages <- json.RawMessage(data)
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.setErr(err)
+	}
+}
+
+func (t *SSETransport) setErr(err error) {
+	t.errMu.Lock()
+	defer t.errMu.Unlock()
+	if t.err == nil {
+		t.err = err
+	}
+}
+
+// resolveEndpoint 将服务端下发的(可能是相对路径的)消息端点解析为绝对URL
+func resolveEndpoint(base, ref string) (string, error) {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("SSE端点格式错误: %v", err)
+	}
+	refURL, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return "", fmt.Errorf("消息端点格式错误: %v", err)
+	}
+	resolved := baseURL.ResolveReference(refURL)
+	if resolved.Host != baseURL.Host || resolved.Scheme != baseURL.Scheme {
+		return "", fmt.Errorf("消息端点与SSE端点不同源: %s", resolved)
+	}
+	return resolved.String(), nil
+}
+
+// setHeaders 设置自定义请求头
+func setHeaders(req *http.Request, headers map[string]string) {
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+}
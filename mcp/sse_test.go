@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeSSEServer 假的HTTP+SSE MCP服务器：GET /sse建立事件流并下发endpoint事件，
+// POST到消息端点的请求通过事件流返回响应
+type fakeSSEServer struct {
+	endpoint string // endpoint事件的内容
+	token    string // 要求的Authorization请求头
+
+	mu      sync.Mutex
+	streams map[string]chan []byte // 会话ID到事件流
+}
+
+func (s *fakeSSEServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Authorization") != s.token {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/sse":
+		session := fmt.Sprintf("s%d", time.Now().UnixNano())
+		stream := make(chan []byte, 16)
+		s.mu.Lock()
+		s.streams[session] = stream
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, ": connected\n\n")
+		fmt.Fprintf(w, "event: endpoint\ndata: %s\n\n", strings.ReplaceAll(s.endpoint, "{session}", session))
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case data := <-stream:
+				fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case r.Method == http.MethodPost && r.URL.Path == "/messages":
+		s.mu.Lock()
+		stream, ok := s.streams[r.URL.Query().Get("session")]
+		s.mu.Unlock()
+		if !ok {
+			http.Error(w, "unknown session", http.StatusNotFound)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if resp := fakeServerHandle(body); resp != nil {
+			data, _ := json.Marshal(resp)
+			stream <- data
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newFakeSSEServer 启动假的SSE服务器，endpoint中的{session}替换为会话ID
+func newFakeSSEServer(t *testing.T, endpoint string) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(&fakeSSEServer{endpoint: endpoint, token: "Bearer secret", streams: make(map[string]chan []byte)})
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSSEClient(t *testing.T) {
+	server := newFakeSSEServer(t, "/messages?session={session}")
+	transport := NewSSETransport(server.URL+"/sse", map[string]string{"Authorization": "Bearer secret"}, nil)
+	client := NewClient("fake-sse", transport)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	defer client.Close()
+
+	// 相对路径的消息端点按SSE端点解析，保留服务端下发的会话参数
+	if !strings.HasPrefix(transport.messageURL, server.URL+"/messages?session=s") {
+		t.Errorf("messageURL = %q", transport.messageURL)
+	}
+	if info := client.ServerInfo(); info == nil || info.ServerInfo.Name != "fake" {
+		t.Fatalf("ServerInfo = %+v", info)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 2 {
+		t.Errorf("ListTools = %+v, want 2 tools", tools)
+	}
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "hello"})
+	if err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if result.Text() != "hello" {
+		t.Errorf("CallTool = %+v", result)
+	}
+
+	client.Close()
+	select {
+	case <-client.Done():
+	case <-ctx.Done():
+		t.Fatal("Done was not closed after Close")
+	}
+}
+
+func TestSSETransportHandshakeErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		endpoint string
+		token    string
+		want     string
+	}{
+		{"cross origin endpoint", "http://example.com/messages?session={session}", "Bearer secret", "不同源"},
+		{"unauthorized", "/messages?session={session}", "Bearer wrong", "异常状态码: 401"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeSSEServer(t, tt.endpoint)
+			transport := NewSSETransport(server.URL+"/sse", map[string]string{"Authorization": tt.token}, nil)
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			err := transport.Start(ctx)
+			transport.Close()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Start error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSSETransportStreamEndsBeforeEndpoint(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message\ndata: {}\n\n")
+	}))
+	defer server.Close()
+
+	transport := NewSSETransport(server.URL+"/sse", nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := transport.Start(ctx); err == nil || !strings.Contains(err.Error(), "下发消息端点前已结束") {
+		t.Errorf("Start error = %v", err)
+	}
+}
@@ -0,0 +1,156 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// maxMessageSize 单条stdio消息的最大字节数
+const maxMessageSize = 16 << 20
+
+// StdioTransport 通过子进程的标准输入输出传输换行分隔的JSON-RPC消息
+type StdioTransport struct {
+	name    string
+	command string
+	args    []string
+	env     []string
+
+	cmd        *exec.Cmd
+	stdin      io.WriteCloser
+	writeMu    sync.Mutex
+	messages   chan json.RawMessage
+	stderrDone chan struct{}
+	exited     chan struct{}
+	once       sync.Once
+}
+
+// NewStdioTransport 创建stdio传输，env为追加到当前进程环境变量之后的KEY=VALUE列表
+func NewStdioTransport(name, command string, args, env []string) *StdioTransport {
+	return &StdioTransport{
+		name:       name,
+		command:    command,
+		args:       args,
+		env:        env,
+		messages:   make(chan json.RawMessage, 16),
+		stderrDone: make(chan struct{}),
+		exited:     make(chan struct{}),
+	}
+}
+
+// Start 启动子进程
+func (t *StdioTransport) Start(ctx context.Context) error {
+	// 子进程的生命周期由Close控制，不跟随调用方的ctx
+	cmd := exec.Command(t.command, t.args...)
+	cmd.Env = append(os.Environ(), t.env...)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建标准输入管道失败: %v", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建标准输出管道失败: %v", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("创建标准错误管道失败: %v", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动MCP服务器进程失败: %v", err)
+	}
+	t.cmd = cmd
+	t.stdin = stdin
+
+	go t.logStderr(stderr)
+	go t.readStdout(stdout)
+
+	return nil
+}
+
+// Send 写入一行JSON消息
+func (t *StdioTransport) Send(ctx context.Context, msg json.RawMessage) error {
+	select {
+	case <-t.exited:
+		return fmt.Errorf("MCP服务器进程已退出")
+	default:
+	}
+
+	t.writeMu.Lock()
+	defer t.writeMu.Unlock()
+	if _, err := t.stdin.Write(append(bytes.TrimSpace(msg), '\n')); err != nil {
+		return fmt.Errorf("写入MCP服务器失败: %v", err)
+	}
+	return nil
+}
+
+// Messages 返回从标准输出读取到的消息
+func (t *StdioTransport) Messages() <-chan json.RawMessage {
+	return t.messages
+}
+
+// Close 关闭标准输入让进程自行退出，超时后强制结束
+func (t *StdioTransport) Close() error {
+	t.once.Do(func() {
+		if t.cmd == nil {
+			close(t.messages)
+			return
+		}
+		t.stdin.Close()
+		select {
+		case <-t.exited:
+		case <-time.After(3 * time.Second):
+			t.cmd.Process.Kill()
+			<-t.exited
+		}
+	})
+	return nil
+}
+
+// readStdout 按行读取标准输出，进程退出后关闭消息通道
+func (t *StdioTransport) readStdout(stdout io.Reader) {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if !json.Valid(line) {
+			// 部分服务器会把日志写到标准输出，忽略非JSON行
+			log.Printf("[MCP %s] 忽略非JSON输出: %s", t.name, line)
+			continue
+		}
+		t.messages <- json.RawMessage(append([]byte(nil), line...))
+	}
+	if err := scanner.Err(); err != nil {
+		log.Printf("[MCP %s] 读取标准输出失败: %v", t.name, err)
+	}
+
+	// Wait会关闭管道，必须等标准错误读取完毕后再调用
+	<-t.stderrDone
+	err := t.cmd.Wait()
+	if err != nil {
+		log.Printf("[MCP %s] 进程退出: %v", t.name, err)
+	}
+	close(t.exited)
+	close(t.messages)
+}
+
+// logStderr 将子进程的标准错误输出写入日志
+func (t *StdioTransport) logStderr(stderr io.Reader) {
+	defer close(t.stderrDone)
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		log.Printf("[MCP %s] %s", t.name, scanner.Text())
+	}
+}
@@ -0,0 +1,157 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeServerEnv 设置后测试进程作为假的stdio MCP服务器运行
+const fakeServerEnv = "MCP_FAKE_STDIO_SERVER"
+
+// fakeServerHandle 假MCP服务器处理一条请求，通知返回nil
+func fakeServerHandle(data []byte) *Response {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return &Response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &RPCError{Code: ErrCodeParse, Message: err.Error()}}
+	}
+	if len(msg.ID) == 0 {
+		return nil
+	}
+
+	resp := &Response{JSONRPC: "2.0", ID: msg.ID}
+	var result interface{}
+	switch msg.Method {
+	case "initialize":
+		result = InitializeResult{
+			ProtocolVersion: ProtocolVersion,
+			Capabilities:    map[string]interface{}{"tools": map[string]interface{}{}},
+			ServerInfo:      Implementation{Name: "fake", Version: "0.1.0"},
+		}
+	case "tools/list":
+		// 分两页返回工具列表
+		var params struct {
+			Cursor string `json:"cursor"`
+		}
+		json.Unmarshal(msg.Params, &params)
+		if params.Cursor == "" {
+			result = ListToolsResult{Tools: []Tool{{Name: "echo", Description: "原样返回text参数"}}, NextCursor: "page2"}
+		} else {
+			result = ListToolsResult{Tools: []Tool{{Name: "fail"}}}
+		}
+	case "tools/call":
+		var params CallToolParams
+		json.Unmarshal(msg.Params, &params)
+		switch params.Name {
+		case "echo":
+			result = CallToolResult{Content: []Content{{Type: "text", Text: fmt.Sprint(params.Arguments["text"])}}}
+		case "fail":
+			result = CallToolResult{Content: []Content{{Type: "text", Text: "工具执行失败"}}, IsError: true}
+		default:
+			resp.Error = &RPCError{Code: ErrCodeInvalidParams, Message: "未知工具: " + params.Name}
+		}
+	default:
+		resp.Error = &RPCError{Code: ErrCodeMethodNotFound, Message: "不支持的方法: " + msg.Method}
+	}
+	if result != nil {
+		resp.Result, _ = json.Marshal(result)
+	}
+	return resp
+}
+
+// TestMain 设置fakeServerEnv时作为假的stdio MCP服务器运行：从标准输入逐行读取请求，向标准输出写入响应
+func TestMain(m *testing.M) {
+	if os.Getenv(fakeServerEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	// 标准输出中的日志行和标准错误输出不影响协议
+	fmt.Println("fake server starting")
+	fmt.Fprintln(os.Stderr, "fake server ready")
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), `"method":"tools/call"`) && strings.Contains(scanner.Text(), `"exit"`) {
+			os.Exit(3)
+		}
+		if resp := fakeServerHandle(scanner.Bytes()); resp != nil {
+			data, _ := json.Marshal(resp)
+			fmt.Println(string(data))
+		}
+	}
+	os.Exit(0)
+}
+
+// startFakeStdio 启动假的stdio MCP服务器并完成握手
+func startFakeStdio(t *testing.T) *Client {
+	t.Helper()
+	transport := NewStdioTransport("fake", os.Args[0], []string{"-test.run=^$"}, []string{fakeServerEnv + "=1"})
+	client := NewClient("fake", transport)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := client.Start(ctx); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+func TestStdioClient(t *testing.T) {
+	client := startFakeStdio(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if info := client.ServerInfo(); info == nil || info.ServerInfo.Name != "fake" {
+		t.Fatalf("ServerInfo = %+v", info)
+	}
+
+	tools, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(tools) != 2 || tools[0].Name != "echo" || tools[1].Name != "fail" {
+		t.Errorf("ListTools = %+v, want echo and fail from two pages", tools)
+	}
+
+	result, err := client.CallTool(ctx, "echo", map[string]interface{}{"text": "你好"})
+	if err != nil {
+		t.Fatalf("CallTool echo: %v", err)
+	}
+	if result.IsError || result.Text() != "你好" {
+		t.Errorf("CallTool echo = %+v", result)
+	}
+
+	result, err = client.CallTool(ctx, "fail", nil)
+	if err != nil || !result.IsError {
+		t.Errorf("CallTool fail = %+v, %v, want IsError", result, err)
+	}
+
+	if _, err := client.CallTool(ctx, "missing", nil); err == nil || !strings.Contains(err.Error(), "未知工具") {
+		t.Errorf("CallTool missing error = %v", err)
+	}
+}
+
+func TestStdioClientProcessExit(t *testing.T) {
+	client := startFakeStdio(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := client.CallTool(ctx, "exit", nil); err == nil {
+		t.Fatal("CallTool exit: want error after the server process exits")
+	}
+	select {
+	case <-client.Done():
+	case <-ctx.Done():
+		t.Fatal("Done was not closed after the server process exited")
+	}
+	if client.Alive() {
+		t.Error("Alive = true after the server process exited")
+	}
+	if err := client.Call(ctx, "ping", nil, nil); err == nil {
+		t.Error("Call after exit: want error")
+	}
+}
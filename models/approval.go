@@ -0,0 +1,39 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 工具调用审批状态
+const (
+	ApprovalPending   = "pending"   // 等待审批
+	ApprovalApproved  = "approved"  // 已批准，正在执行
+	ApprovalDenied    = "denied"    // 已拒绝
+	ApprovalCompleted = "completed" // 已批准并执行成功
+	ApprovalFailed    = "failed"    // 已批准但执行失败
+)
+
+// MCPApproval 需要人工审批的MCP工具调用
+type MCPApproval struct {
+	ApprovalID     string                 `json:"approval_id"`               // 审批单ID
+	Server         string                 `json:"server"`                    // MCP服务器名称
+	Tool           string                 `json:"tool"`                      // 工具名称
+	Arguments      map[string]interface{} `json:"arguments"`                 // 工具调用参数
+	ConversationID string                 `json:"conversation_id,omitempty"` // 发起调用的会话ID
+	Model          string                 `json:"model,omitempty"`           // 发起调用的模型
+	Status         string                 `json:"status"`                    // 审批状态
+	Reason         string                 `json:"reason,omitempty"`          // 审批意见
+	DecidedBy      string                 `json:"decided_by,omitempty"`      // 审批人
+	Result         json.RawMessage        `json:"result,omitempty"`          // 工具执行结果
+	Error          string                 `json:"error,omitempty"`           // 工具执行错误
+	CreatedAt      time.Time              `json:"created_at"`                // 创建时间
+	DecidedAt      *time.Time             `json:"decided_at,omitempty"`      // 审批时间
+}
+
+// MCPApprovalDecision 审批请求结构体
+type MCPApprovalDecision struct {
+	Action    string `json:"action" binding:"required,oneof=approve deny"` // approve 或 deny
+	Reason    string `json:"reason,omitempty"`                             // 审批意见
+	DecidedBy string `json:"decided_by,omitempty"`                         // 审批人
+}
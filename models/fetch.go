@@ -20,8 +20,11 @@ type FetchResponse struct {
 	ExtractedData map[string]interface{} `json:"extracted_data,omitempty"` // 提取的结构化数据
 	Language      string                 `json:"language"`                 // 检测的语言
 	FetchTime     time.Time              `json:"fetch_time"`               // 抓取时间
+	News          *NewsItem              `json:"news,omitempty"`           // 新闻条目（content_type为news时返回）
 	Status        string                 `json:"status"`                   // 状态 (success, error)
 	Error         string                 `json:"error,omitempty"`          // 错误信息
+	Provider      string                 `json:"provider,omitempty"`       // 补充字段时实际使用的大模型服务商
+	Model         string                 `json:"model,omitempty"`          // 补充字段时实际使用的模型
 }
 
 // NewsItem 新闻条目结构体
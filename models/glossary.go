@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// GlossaryTerm 术语表中的一条术语，规定源术语在指定语言对下的译法
+type GlossaryTerm struct {
+	ID            string    `json:"id"`                             // 术语ID
+	Glossary      string    `json:"glossary"`                       // 所属术语表，按用户或项目命名
+	SourceLang    string    `json:"source_lang,omitempty"`          // 源语言，为空时适用于任意源语言
+	TargetLang    string    `json:"target_lang" binding:"required"` // 目标语言
+	SourceTerm    string    `json:"source_term" binding:"required"` // 源术语
+	TargetTerm    string    `json:"target_term" binding:"required"` // 要求的译法
+	CaseSensitive bool      `json:"case_sensitive,omitempty"`       // 匹配源术语和检查译法时是否区分大小写
+	Note          string    `json:"note,omitempty"`                 // 备注，会一并提供给大模型
+	CreatedAt     time.Time `json:"created_at"`                     // 创建时间
+	UpdatedAt     time.Time `json:"updated_at"`                     // 更新时间
+}
+
+// GlossaryTermPatch 术语的部分更新，未提供的字段保持不变
+type GlossaryTermPatch struct {
+	SourceLang    *string `json:"source_lang,omitempty"`    // 源语言
+	TargetLang    *string `json:"target_lang,omitempty"`    // 目标语言
+	SourceTerm    *string `json:"source_term,omitempty"`    // 源术语
+	TargetTerm    *string `json:"target_term,omitempty"`    // 要求的译法
+	CaseSensitive *bool   `json:"case_sensitive,omitempty"` // 是否区分大小写
+	Note          *string `json:"note,omitempty"`           // 备注
+}
+
+// Apply 将部分更新应用到术语上
+func (p GlossaryTermPatch) Apply(term GlossaryTerm) GlossaryTerm {
+	if p.SourceLang != nil {
+		term.SourceLang = *p.SourceLang
+	}
+	if p.TargetLang != nil {
+		term.TargetLang = *p.TargetLang
+	}
+	if p.SourceTerm != nil {
+		term.SourceTerm = *p.SourceTerm
+	}
+	if p.TargetTerm != nil {
+		term.TargetTerm = *p.TargetTerm
+	}
+	if p.CaseSensitive != nil {
+		term.CaseSensitive = *p.CaseSensitive
+	}
+	if p.Note != nil {
+		term.Note = *p.Note
+	}
+	return term
+}
+
+// GlossaryInfo 术语表概况
+type GlossaryInfo struct {
+	Name      string    `json:"name"`       // 术语表名称
+	Terms     int       `json:"terms"`      // 术语数
+	UpdatedAt time.Time `json:"updated_at"` // 最近修改时间
+}
@@ -1,24 +1,89 @@
 package models
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // MCPServer MCP服务器配置结构体
 type MCPServer struct {
-	Name        string            `json:"name" binding:"required"`    // 服务器名称
-	Command     string            `json:"command" binding:"required"` // 执行命令
-	Args        []string          `json:"args,omitempty"`             // 命令参数
-	Env         map[string]string `json:"env,omitempty"`              // 环境变量
-	Disabled    bool              `json:"disabled,omitempty"`         // 是否禁用
-	AutoApprove []string          `json:"autoApprove,omitempty"`      // 自动批准的操作
+	Name        string            `json:"name" binding:"required"` // 服务器名称
+	Command     string            `json:"command,omitempty"`       // 执行命令（本地stdio服务器）
+	Args        []string          `json:"args,omitempty"`          // 命令参数
+	Env         map[string]string `json:"env,omitempty"`           // 环境变量
+	URL         string            `json:"url,omitempty"`           // 远程服务器地址，填写后直接通过HTTP连接而不启动命令
+	Transport   string            `json:"transport,omitempty"`     // 远程传输类型 (sse, streamable-http)，为空时根据URL判断
+	Headers     map[string]string `json:"headers,omitempty"`       // 远程请求附加的请求头
+	Disabled    bool              `json:"disabled,omitempty"`      // 是否禁用
+	AutoApprove []string          `json:"autoApprove,omitempty"`   // 自动批准的操作
+}
+
+// IsAutoApproved 判断工具是否在自动批准列表中，"*"表示批准该服务器的全部工具
+func (s MCPServer) IsAutoApproved(tool string) bool {
+	for _, name := range s.AutoApprove {
+		if name == tool || name == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// MCP服务器配置来源
+const (
+	MCPServerBuiltin  = "builtin"  // 内置的默认配置
+	MCPServerCustom   = "custom"   // 用户新增的配置
+	MCPServerOverride = "override" // 用户修改过的内置配置
+)
+
+// MCPServerPatch MCP服务器配置的部分更新，未提供的字段保持不变
+type MCPServerPatch struct {
+	Command     *string            `json:"command,omitempty"`     // 执行命令
+	Args        *[]string          `json:"args,omitempty"`        // 命令参数
+	Env         *map[string]string `json:"env,omitempty"`         // 环境变量
+	URL         *string            `json:"url,omitempty"`         // 远程服务器地址
+	Transport   *string            `json:"transport,omitempty"`   // 远程传输类型
+	Headers     *map[string]string `json:"headers,omitempty"`     // 远程请求附加的请求头
+	Disabled    *bool              `json:"disabled,omitempty"`    // 是否禁用
+	AutoApprove *[]string          `json:"autoApprove,omitempty"` // 自动批准的工具
+}
+
+// Apply 将部分更新应用到服务器配置上
+func (p MCPServerPatch) Apply(server MCPServer) MCPServer {
+	if p.Command != nil {
+		server.Command = *p.Command
+	}
+	if p.Args != nil {
+		server.Args = *p.Args
+	}
+	if p.Env != nil {
+		server.Env = *p.Env
+	}
+	if p.URL != nil {
+		server.URL = *p.URL
+	}
+	if p.Transport != nil {
+		server.Transport = *p.Transport
+	}
+	if p.Headers != nil {
+		server.Headers = *p.Headers
+	}
+	if p.Disabled != nil {
+		server.Disabled = *p.Disabled
+	}
+	if p.AutoApprove != nil {
+		server.AutoApprove = *p.AutoApprove
+	}
+	return server
 }
 
 // MCPRequest MCP请求结构体
 type MCPRequest struct {
-	Model      string                 `json:"model" binding:"required"`      // 模型名称
-	MCPServers []string               `json:"mcpServers" binding:"required"` // MCP服务器名称列表
-	Query      string                 `json:"query,omitempty"`               // 查询内容（用于工具调用）
-	Tool       string                 `json:"tool,omitempty"`                // 要调用的工具名称
-	Params     map[string]interface{} `json:"params,omitempty"`              // 工具调用参数
+	Model          string                 `json:"model" binding:"required"`      // 模型名称
+	MCPServers     []string               `json:"mcpServers" binding:"required"` // MCP服务器名称列表
+	Query          string                 `json:"query,omitempty"`               // 查询内容（用于工具调用）
+	Tool           string                 `json:"tool,omitempty"`                // 要调用的工具名称
+	Params         map[string]interface{} `json:"params,omitempty"`              // 工具调用参数
+	ConversationID string                 `json:"conversation_id,omitempty"`     // 发起调用的会话ID
 }
 
 // MCPResponse MCP响应结构体
@@ -29,6 +94,25 @@ type MCPResponse struct {
 	ToolResult interface{}          `json:"tool_result,omitempty"` // 工具调用结果
 	Query      string               `json:"query,omitempty"`       // 查询内容
 	Tool       string               `json:"tool,omitempty"`        // 调用的工具
+	Server     string               `json:"server,omitempty"`      // 实际处理工具调用的MCP服务器，内置工具为空
+	Approval   *MCPApproval         `json:"approval,omitempty"`    // 工具调用需要人工审批时返回的审批单
+}
+
+// MCPTool MCP工具定义
+type MCPTool struct {
+	Name        string          `json:"name"`                   // 工具名称
+	Description string          `json:"description,omitempty"`  // 工具描述
+	InputSchema json.RawMessage `json:"input_schema,omitempty"` // 参数的JSON Schema
+}
+
+// MCPServerInfo MCP服务器及其实时工具列表
+type MCPServerInfo struct {
+	Server   MCPServer `json:"server"`          // 服务器配置
+	Source   string    `json:"source"`          // 配置来源 (builtin, custom, override)
+	Running  bool      `json:"running"`         // 是否已连接
+	Restarts int       `json:"restarts"`        // 重启次数
+	Tools    []MCPTool `json:"tools,omitempty"` // 工具列表
+	Error    string    `json:"error,omitempty"` // 获取工具列表失败的原因
 }
 
 // GetDefaultMCPServers 获取默认的MCP服务器配置
@@ -47,17 +131,15 @@ func GetDefaultMCPServers() map[string]MCPServer {
 			AutoApprove: []string{},
 		},
 		"阿里云百炼_联网搜索": {
-			Name:    "阿里云百炼_联网搜索",
-			Command: "npx",
-			Args: []string{
-				"mcp-remote",
-				"https://dashscope.aliyuncs.com/api/v1/mcps/WebSearch/sse",
-				"--header",
-				"Authorization:${AUTH_HEADER}",
-			},
+			Name: "阿里云百炼_联网搜索",
 			Env: map[string]string{
 				"AUTH_HEADER": "Bearer ${QWEN_API_KEY}",
 			},
+			URL:       "https://dashscope.aliyuncs.com/api/v1/mcps/WebSearch/sse",
+			Transport: "sse",
+			Headers: map[string]string{
+				"Authorization": "${AUTH_HEADER}",
+			},
 			Disabled:    false,
 			AutoApprove: []string{},
 		},
@@ -0,0 +1,64 @@
+package models
+
+import "time"
+
+// 译文质量评估方式
+const (
+	QualityJudge           = "judge"            // 由大模型按评分标准打分
+	QualityBackTranslation = "back_translation" // 将译文回译为源语言，按与原文的相似度打分
+	QualityBoth            = "both"             // 回译后由大模型参考回译结果打分
+)
+
+// 译文质量问题类型
+const (
+	IssueOmission       = "omission"       // 遗漏原文内容
+	IssueMistranslation = "mistranslation" // 意思错误
+	IssueUntranslated   = "untranslated"   // 应翻译的内容未翻译
+)
+
+// QualityIssue 译文中发现的问题
+type QualityIssue struct {
+	Type   string `json:"type"`             // 问题类型 (omission, mistranslation, untranslated)
+	Detail string `json:"detail,omitempty"` // 问题说明
+}
+
+// SegmentQuality 单个片段的质量评估结果
+type SegmentQuality struct {
+	Score           int            `json:"score"`                      // 质量分数，0-100
+	Issues          []QualityIssue `json:"issues,omitempty"`           // 发现的问题
+	BackTranslation string         `json:"back_translation,omitempty"` // 回译结果
+}
+
+// QualityReport 一次翻译请求的质量评估汇总
+type QualityReport struct {
+	Method  string         `json:"method"`           // 评估方式
+	Judge   string         `json:"judge,omitempty"`  // 打分使用的"服务商/模型"，只使用回译时为空
+	Scored  int            `json:"scored"`           // 完成评估的片段数
+	Average float64        `json:"average"`          // 平均分
+	Issues  map[string]int `json:"issues,omitempty"` // 各类问题的数量
+}
+
+// QualityScore 保存的片段质量分数，用于按模型跟踪翻译质量
+type QualityScore struct {
+	Provider  string         // 翻译的服务商
+	Model     string         // 翻译的模型
+	Judge     string         // 打分使用的"服务商/模型"
+	Method    string         // 评估方式
+	Source    string         // 源语言，未指定且无法识别时为空
+	Target    string         // 目标语言
+	Score     int            // 质量分数
+	Issues    []QualityIssue // 发现的问题
+	CreatedAt time.Time      // 评估时间
+}
+
+// QualityStats 按模型和日期汇总的翻译质量
+type QualityStats struct {
+	Provider       string  `json:"provider"`       // 翻译的服务商
+	Model          string  `json:"model"`          // 翻译的模型
+	Day            string  `json:"day"`            // 日期，格式为2006-01-02
+	Segments       int     `json:"segments"`       // 评估的片段数
+	Average        float64 `json:"average"`        // 平均分
+	Omission       int     `json:"omission"`       // 遗漏的数量
+	Mistranslation int     `json:"mistranslation"` // 意思错误的数量
+	Untranslated   int     `json:"untranslated"`   // 未翻译的数量
+}
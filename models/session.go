@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/sashabaranov/go-openai"
@@ -17,11 +18,14 @@ type Conversation struct {
 
 // Message 消息结构
 type Message struct {
-	MessageID      int       `json:"message_id"`
-	ConversationID string    `json:"conversation_id"`
-	Role           string    `json:"role"`
-	Content        string    `json:"content"`
-	CreatedAt      time.Time `json:"created_at"`
+	MessageID      int               `json:"message_id"`
+	ConversationID string            `json:"conversation_id"`
+	Role           string            `json:"role"`
+	Content        string            `json:"content"`
+	Name           string            `json:"name,omitempty"`         // 工具消息对应的函数名
+	ToolCalls      []openai.ToolCall `json:"tool_calls,omitempty"`   // 助手消息发起的工具调用
+	ToolCallID     string            `json:"tool_call_id,omitempty"` // 工具消息对应的调用ID
+	CreatedAt      time.Time         `json:"created_at"`
 }
 
 // Session 会话结构
@@ -35,8 +39,11 @@ type Session struct {
 // ToChatMessage 转换为OpenAI聊天消息格式
 func (m *Message) ToChatMessage() openai.ChatCompletionMessage {
 	return openai.ChatCompletionMessage{
-		Role:    m.Role,
-		Content: m.Content,
+		Role:       m.Role,
+		Content:    m.Content,
+		Name:       m.Name,
+		ToolCalls:  m.ToolCalls,
+		ToolCallID: m.ToolCallID,
 	}
 }
 
@@ -46,6 +53,21 @@ func FromChatMessage(conversationID string, msg openai.ChatCompletionMessage) *M
 		ConversationID: conversationID,
 		Role:           msg.Role,
 		Content:        msg.Content,
+		Name:           msg.Name,
+		ToolCalls:      msg.ToolCalls,
+		ToolCallID:     msg.ToolCallID,
 		CreatedAt:      time.Now(),
 	}
 }
+
+// ToolCallsJSON 将工具调用序列化为JSON文本，没有工具调用时返回空字符串
+func (m *Message) ToolCallsJSON() (string, error) {
+	if len(m.ToolCalls) == 0 {
+		return "", nil
+	}
+	data, err := json.Marshal(m.ToolCalls)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// 翻译结果中片段的状态
+const (
+	SegmentTranslated = "translated" // 翻译成功
+	SegmentFailed     = "failed"     // 重试后仍未得到有效译文，text为原文
+	SegmentSkipped    = "skipped"    // 已经是目标语言或不需要翻译，text为原文
+)
+
+// TranslateSegment 翻译片段结构体
+type TranslateSegment struct {
+	ID     string `json:"id" binding:"required"`   // 片段ID，用于标识片段以便后续返回到前端相应位置
+	Text   string `json:"text" binding:"required"` // 要翻译的文本
+	Status string `json:"status,omitempty"`        // 翻译结果中片段的状态 (translated, failed, skipped)
+	Error  string `json:"error,omitempty"`         // 翻译失败的原因
+	Cached bool   `json:"cached,omitempty"`        // 译文是否来自翻译记忆
+
+	GlossaryMisses []string `json:"glossary_misses,omitempty"` // 原文中出现但译文未使用要求译法的术语
+	DetectedLang   string   `json:"detected_lang,omitempty"`   // 识别出的原文语言，无法识别时为空
+	SkipReason     string   `json:"skip_reason,omitempty"`     // 跳过翻译的原因 (target_language, untranslatable)
+	Provider       string   `json:"provider,omitempty"`        // 实际翻译该片段的服务商，来自翻译记忆时为请求的服务商
+	Model          string   `json:"model,omitempty"`           // 实际翻译该片段的模型
+
+	Quality *SegmentQuality `json:"quality,omitempty"` // 译文质量评估结果，请求设置quality时返回
+}
+
+// TranslateRequest 翻译请求结构体
+type TranslateRequest struct {
+	Target        string             `json:"target,omitempty"`            // 目标语言，与targets二选一
+	Targets       []string           `json:"targets,omitempty"`           // 多个目标语言，同时翻译并按目标语言分别返回结果
+	Source        string             `json:"source,omitempty"`            // 源语言，为空时由模型自动识别
+	Segments      []TranslateSegment `json:"segments" binding:"required"` // 要翻译的文本片段
+	Format        string             `json:"format,omitempty"`            // 片段格式 (text, html, markdown)，为空时按纯文本处理
+	Options       *TranslateOptions  `json:"options,omitempty"`           // 翻译选项，例如风格、领域和目标读者
+	ExtraArgs     *ExtraArgs         `json:"extra_args,omitempty"`        // 已废弃，旧版本的翻译选项，不能与options同时使用
+	Model         string             `json:"model,omitempty"`             // 模型名称，格式与对话接口相同，为空时使用默认模型
+	Temperature   *float32           `json:"temperature,omitempty"`       // 采样温度，范围0-2
+	TopP          *float32           `json:"top_p,omitempty"`             // 核采样概率，范围(0, 1]
+	MaxTokens     int                `json:"max_tokens,omitempty"`        // 最大输出token数，为0时使用服务商默认值
+	SkipMemory    bool               `json:"skip_memory,omitempty"`       // 不使用翻译记忆，全部片段都调用大模型翻译
+	Glossary      string             `json:"glossary,omitempty"`          // 使用的术语表名称
+	TranslateAll  bool               `json:"translate_all,omitempty"`     // 不跳过已经是目标语言或不需要翻译的片段
+	Quality       bool               `json:"quality,omitempty"`           // 翻译后评估每个片段的译文质量
+	QualityMethod string             `json:"quality_method,omitempty"`    // 质量评估方式 (judge, back_translation, both)，默认judge
+	QualityModel  string             `json:"quality_model,omitempty"`     // 评估使用的模型，为空时使用翻译的模型
+}
+
+// 翻译风格
+const (
+	StyleFormal    = "formal"    // 正式、书面
+	StyleCasual    = "casual"    // 轻松、口语化
+	StyleTechnical = "technical" // 专业的技术文档
+)
+
+// TranslateOptions 翻译选项，按固定的模板加入提示词
+type TranslateOptions struct {
+	Style                         string `json:"style,omitempty"`                            // 翻译风格 (formal, casual, technical)
+	Domain                        string `json:"domain,omitempty"`                           // 内容所属领域，例如医疗、法律
+	Audience                      string `json:"audience,omitempty"`                         // 目标读者，例如儿童、开发者
+	PreserveFormatting            bool   `json:"preserve_formatting,omitempty"`              // 保留原文的换行、空格等格式
+	KeepOriginalForUntranslatable bool   `json:"keep_original_for_untranslatable,omitempty"` // 无法翻译的专有名词、代码等保留原文
+}
+
+// ExtraArgs 旧版本的extra_args参数，可以是表示风格的字符串，也可以是与TranslateOptions字段相同的对象
+type ExtraArgs struct {
+	TranslateOptions
+}
+
+// UnmarshalJSON 解析字符串或对象形式的extra_args。旧版本的客户端可能传入任意内容，
+// 对象中TranslateOptions以外的字段、类型不对的字段以及其他类型的值只记录日志后忽略
+func (a *ExtraArgs) UnmarshalJSON(data []byte) error {
+	a.TranslateOptions = TranslateOptions{}
+	var style string
+	if err := json.Unmarshal(data, &style); err == nil {
+		a.Style = style
+		return nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		log.Printf("忽略无法识别的extra_args: %s", data)
+		return nil
+	}
+	for key, value := range fields {
+		var target interface{}
+		switch key {
+		case "style":
+			target = &a.Style
+		case "domain":
+			target = &a.Domain
+		case "audience":
+			target = &a.Audience
+		case "preserve_formatting":
+			target = &a.PreserveFormatting
+		case "keep_original_for_untranslatable":
+			target = &a.KeepOriginalForUntranslatable
+		default:
+			log.Printf("忽略extra_args中不支持的字段: %s", key)
+			continue
+		}
+		if err := json.Unmarshal(value, target); err != nil {
+			log.Printf("忽略extra_args中类型错误的字段%s: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// EffectiveOptions 返回请求的翻译选项，未指定options时使用extra_args
+func (r TranslateRequest) EffectiveOptions() TranslateOptions {
+	switch {
+	case r.Options != nil:
+		return *r.Options
+	case r.ExtraArgs != nil:
+		return r.ExtraArgs.TranslateOptions
+	}
+	return TranslateOptions{}
+}
+
+// TranslateResponse 翻译响应结构体
+type TranslateResponse struct {
+	Target   string                  `json:"target"`             // 目标语言
+	Source   string                  `json:"source,omitempty"`   // 请求中指定的源语言
+	Segments []TranslateSegment      `json:"segments"`           // 翻译后的文本片段
+	Provider string                  `json:"provider,omitempty"` // 实际处理请求的服务商
+	Model    string                  `json:"model,omitempty"`    // 实际使用的模型
+	Failed   []string                `json:"failed,omitempty"`   // 重试后仍未翻译的片段ID
+	Errors   []string                `json:"errors,omitempty"`   // 翻译失败的批次及原因
+	Memory   *TranslationMemoryStats `json:"memory,omitempty"`   // 翻译记忆命中情况，未启用翻译记忆时为空
+	Skipped  []string                `json:"skipped,omitempty"`  // 跳过翻译的片段ID
+	Detected map[string]int          `json:"detected,omitempty"` // 识别出的原文语言及其片段数
+	Quality  *QualityReport          `json:"quality,omitempty"`  // 译文质量评估汇总，请求设置quality时返回
+}
+
+// MultiTranslateResponse 多目标语言翻译的响应，translations按目标语言返回与单目标语言相同的翻译结果
+type MultiTranslateResponse struct {
+	Source       string                        `json:"source,omitempty"` // 请求中指定的源语言
+	Targets      []string                      `json:"targets"`          // 请求的目标语言
+	Translations map[string]*TranslateResponse `json:"translations"`     // 目标语言到该语言的翻译结果
+	Failed       []string                      `json:"failed,omitempty"` // 全部片段都翻译失败的目标语言
+}
+
+// TranslationMemoryStats 翻译记忆命中统计
+type TranslationMemoryStats struct {
+	Hits   int `json:"hits"`   // 命中的片段数
+	Misses int `json:"misses"` // 未命中、需要调用大模型的片段数
+}
+
+// TranslationMemoryEntry 翻译记忆条目
+type TranslationMemoryEntry struct {
+	Key         string    `json:"key"`              // 条目键，由规范化后的原文、源语言、目标语言、模型、风格和格式计算
+	SourceText  string    `json:"source_text"`      // 原文
+	Target      string    `json:"target"`           // 目标语言
+	Model       string    `json:"model"`            // 翻译使用的"服务商/模型"
+	Style       string    `json:"style,omitempty"`  // 翻译风格等额外要求
+	Format      string    `json:"format,omitempty"` // 片段格式
+	Translation string    `json:"translation"`      // 译文
+	Hits        int       `json:"hits"`             // 命中次数
+	CreatedAt   time.Time `json:"created_at"`       // 创建时间
+	LastUsedAt  time.Time `json:"last_used_at"`     // 最近命中时间
+}
+
+// TranslateSummary 流式翻译结束时推送的汇总信息
+type TranslateSummary struct {
+	Target     string   `json:"target"`             // 目标语言
+	Total      int      `json:"total"`              // 请求的片段数
+	Translated int      `json:"translated"`         // 已翻译的片段数
+	Cached     int      `json:"cached,omitempty"`   // 来自翻译记忆的片段数
+	Skipped    int      `json:"skipped,omitempty"`  // 跳过翻译的片段数
+	Failed     []string `json:"failed,omitempty"`   // 重试后仍未翻译的片段ID
+	Errors     []string `json:"errors,omitempty"`   // 翻译失败的批次及原因
+	Provider   string   `json:"provider,omitempty"` // 实际处理请求的服务商
+	Model      string   `json:"model,omitempty"`    // 实际使用的模型
+
+	Detected map[string]int `json:"detected,omitempty"` // 识别出的原文语言及其片段数
+	Quality  *QualityReport `json:"quality,omitempty"`  // 译文质量评估汇总
+}
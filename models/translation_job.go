@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// 翻译任务的状态
+const (
+	JobQueued    = "queued"    // 等待处理
+	JobRunning   = "running"   // 正在翻译
+	JobCompleted = "completed" // 全部片段处理完成，其中可能有翻译失败的片段
+	JobFailed    = "failed"    // 任务无法继续，例如模型已不存在
+	JobCancelled = "cancelled" // 已取消
+)
+
+// TranslationJob 异步翻译任务，进度保存在数据库中，服务重启后继续翻译未处理的片段
+type TranslationJob struct {
+	ID         string             `json:"id"`                    // 任务ID
+	Status     string             `json:"status"`                // 任务状态 (queued, running, completed, failed, cancelled)
+	Target     string             `json:"target"`                // 目标语言
+	Source     string             `json:"source,omitempty"`      // 源语言
+	Filename   string             `json:"filename,omitempty"`    // 上传的文件名，直接提交片段时为空
+	Provider   string             `json:"provider,omitempty"`    // 最近一批片段实际使用的服务商
+	Model      string             `json:"model,omitempty"`       // 最近一批片段实际使用的模型
+	Total      int                `json:"total"`                 // 片段总数
+	Processed  int                `json:"processed"`             // 已处理的片段数
+	Translated int                `json:"translated"`            // 已翻译的片段数
+	Cached     int                `json:"cached"`                // 来自翻译记忆的片段数
+	Skipped    int                `json:"skipped"`               // 跳过翻译的片段数
+	Failed     int                `json:"failed"`                // 翻译失败的片段数
+	Progress   float64            `json:"progress"`              // 处理进度，0-100
+	Error      string             `json:"error,omitempty"`       // 任务失败的原因
+	Errors     []string           `json:"errors,omitempty"`      // 翻译失败的批次及原因
+	CreatedAt  time.Time          `json:"created_at"`            // 创建时间
+	UpdatedAt  time.Time          `json:"updated_at"`            // 最近更新时间
+	StartedAt  *time.Time         `json:"started_at,omitempty"`  // 开始翻译的时间
+	FinishedAt *time.Time         `json:"finished_at,omitempty"` // 完成、失败或取消的时间
+	Segments   []TranslateSegment `json:"segments,omitempty"`    // 已处理的片段，按提交的顺序排列
+
+	Request  TranslateRequest `json:"-"` // 翻译参数，不包含片段
+	Document []byte           `json:"-"` // 上传的原始文件，用于组装译文文件
+}
+
+// Finished 判断任务是否已经结束
+func (j *TranslationJob) Finished() bool {
+	return j.Status == JobCompleted || j.Status == JobFailed || j.Status == JobCancelled
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// ApprovalStore MCP工具调用审批存储
+type ApprovalStore struct {
+	DB *sql.DB
+}
+
+// NewApprovalStore 创建新的审批存储实例
+func NewApprovalStore(db *sql.DB) *ApprovalStore {
+	return &ApprovalStore{DB: db}
+}
+
+// CreateApproval 创建审批单
+func (s *ApprovalStore) CreateApproval(approval *models.MCPApproval) error {
+	arguments, err := json.Marshal(approval.Arguments)
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(`
+		INSERT INTO mcp_approvals (approval_id, server, tool, arguments, conversation_id, model, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, approval.ApprovalID, approval.Server, approval.Tool, string(arguments), approval.ConversationID, approval.Model, approval.Status, approval.CreatedAt)
+	return err
+}
+
+// GetApproval 获取审批单
+func (s *ApprovalStore) GetApproval(id string) (*models.MCPApproval, error) {
+	row := s.DB.QueryRow(`
+		SELECT approval_id, server, tool, arguments, conversation_id, model, status, reason, decided_by, result, error, created_at, decided_at
+		FROM mcp_approvals
+		WHERE approval_id = $1
+	`, id)
+	return scanApproval(row)
+}
+
+// ListApprovals 按状态获取审批单，status为空时返回全部
+func (s *ApprovalStore) ListApprovals(status string) ([]*models.MCPApproval, error) {
+	rows, err := s.DB.Query(`
+		SELECT approval_id, server, tool, arguments, conversation_id, model, status, reason, decided_by, result, error, created_at, decided_at
+		FROM mcp_approvals
+		WHERE $1::text = '' OR status = $1
+		ORDER BY created_at DESC
+	`, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	approvals := []*models.MCPApproval{}
+	for rows.Next() {
+		approval, err := scanApproval(rows)
+		if err != nil {
+			return nil, err
+		}
+		approvals = append(approvals, approval)
+	}
+	return approvals, rows.Err()
+}
+
+// DecideApproval 将待审批的审批单更新为批准或拒绝，审批单已被处理时返回false
+func (s *ApprovalStore) DecideApproval(id, status, reason, decidedBy string) (bool, error) {
+	result, err := s.DB.Exec(`
+		UPDATE mcp_approvals
+		SET status = $2, reason = $3, decided_by = $4, decided_at = $5
+		WHERE approval_id = $1 AND status = $6
+	`, id, status, reason, decidedBy, time.Now(), models.ApprovalPending)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// CompleteApproval 记录批准后的工具执行结果
+func (s *ApprovalStore) CompleteApproval(id, status string, result json.RawMessage, errMsg string) error {
+	_, err := s.DB.Exec(`
+		UPDATE mcp_approvals
+		SET status = $2, result = $3, error = $4
+		WHERE approval_id = $1
+	`, id, status, string(result), errMsg)
+	return err
+}
+
+// rowScanner 兼容sql.Row和sql.Rows的扫描接口
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanApproval 扫描一行审批记录
+func scanApproval(row rowScanner) (*models.MCPApproval, error) {
+	approval := &models.MCPApproval{}
+	var arguments, conversationID, model, reason, decidedBy, result, errMsg sql.NullString
+	var decidedAt sql.NullTime
+	err := row.Scan(&approval.ApprovalID, &approval.Server, &approval.Tool, &arguments, &conversationID, &model,
+		&approval.Status, &reason, &decidedBy, &result, &errMsg, &approval.CreatedAt, &decidedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if arguments.String != "" {
+		if err := json.Unmarshal([]byte(arguments.String), &approval.Arguments); err != nil {
+			return nil, err
+		}
+	}
+	approval.ConversationID = conversationID.String
+	approval.Model = model.String
+	approval.Reason = reason.String
+	approval.DecidedBy = decidedBy.String
+	if result.String != "" {
+		approval.Result = json.RawMessage(result.String)
+	}
+	approval.Error = errMsg.String
+	if decidedAt.Valid {
+		approval.DecidedAt = &decidedAt.Time
+	}
+	return approval, nil
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// ErrDuplicateTerm 同一术语表的同一语言对中已存在相同的源术语
+var ErrDuplicateTerm = errors.New("术语已存在")
+
+// GlossaryStore 术语表存储
+type GlossaryStore struct {
+	DB *sql.DB
+}
+
+// NewGlossaryStore 创建新的术语表存储实例
+func NewGlossaryStore(db *sql.DB) *GlossaryStore {
+	return &GlossaryStore{DB: db}
+}
+
+// ListGlossaries 获取全部术语表及其术语数
+func (s *GlossaryStore) ListGlossaries() ([]models.GlossaryInfo, error) {
+	rows, err := s.DB.Query(`
+		SELECT glossary, COUNT(*), MAX(updated_at)
+		FROM glossary_terms
+		GROUP BY glossary
+		ORDER BY glossary
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	glossaries := []models.GlossaryInfo{}
+	for rows.Next() {
+		var info models.GlossaryInfo
+		if err := rows.Scan(&info.Name, &info.Terms, &info.UpdatedAt); err != nil {
+			return nil, err
+		}
+		glossaries = append(glossaries, info)
+	}
+	return glossaries, rows.Err()
+}
+
+// ListTerms 获取术语表中的术语，sourceLang、targetLang为空时不按语言过滤
+func (s *GlossaryStore) ListTerms(glossary, sourceLang, targetLang string) ([]models.GlossaryTerm, error) {
+	return s.queryTerms(`
+		SELECT term_id, glossary, source_lang, target_lang, source_term, target_term, case_sensitive, note, created_at, updated_at
+		FROM glossary_terms
+		WHERE glossary = $1
+			AND ($2::text = '' OR source_lang = $2)
+			AND ($3::text = '' OR target_lang = $3)
+		ORDER BY target_lang, source_term
+	`, glossary, sourceLang, targetLang)
+}
+
+// MatchTerms 获取翻译时适用的术语：目标语言是targetLangs之一，且未限定源语言、请求未指定源语言或源语言一致
+func (s *GlossaryStore) MatchTerms(glossary, sourceLang string, targetLangs []string) ([]models.GlossaryTerm, error) {
+	return s.queryTerms(`
+		SELECT term_id, glossary, source_lang, target_lang, source_term, target_term, case_sensitive, note, created_at, updated_at
+		FROM glossary_terms
+		WHERE glossary = $1
+			AND target_lang = ANY($3)
+			AND ($2::text = '' OR source_lang = '' OR source_lang = $2)
+		ORDER BY target_lang, source_term
+	`, glossary, sourceLang, pq.Array(targetLangs))
+}
+
+// GetTerm 获取术语，不存在时返回sql.ErrNoRows
+func (s *GlossaryStore) GetTerm(glossary, id string) (*models.GlossaryTerm, error) {
+	row := s.DB.QueryRow(`
+		SELECT term_id, glossary, source_lang, target_lang, source_term, target_term, case_sensitive, note, created_at, updated_at
+		FROM glossary_terms
+		WHERE glossary = $1 AND term_id = $2
+	`, glossary, id)
+	return scanGlossaryTerm(row)
+}
+
+// CreateTerm 新增术语，源术语重复时返回ErrDuplicateTerm
+func (s *GlossaryStore) CreateTerm(term models.GlossaryTerm) error {
+	_, err := s.DB.Exec(`
+		INSERT INTO glossary_terms (term_id, glossary, source_lang, target_lang, source_term, target_term, case_sensitive, note, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`, term.ID, term.Glossary, term.SourceLang, term.TargetLang, term.SourceTerm, term.TargetTerm, term.CaseSensitive, term.Note, term.CreatedAt, term.UpdatedAt)
+	return duplicateTermError(err)
+}
+
+// UpdateTerm 更新术语，不存在时返回false，源术语重复时返回ErrDuplicateTerm
+func (s *GlossaryStore) UpdateTerm(term models.GlossaryTerm) (bool, error) {
+	result, err := s.DB.Exec(`
+		UPDATE glossary_terms
+		SET source_lang = $3, target_lang = $4, source_term = $5, target_term = $6, case_sensitive = $7, note = $8, updated_at = $9
+		WHERE glossary = $1 AND term_id = $2
+	`, term.Glossary, term.ID, term.SourceLang, term.TargetLang, term.SourceTerm, term.TargetTerm, term.CaseSensitive, term.Note, term.UpdatedAt)
+	if err != nil {
+		return false, duplicateTermError(err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// DeleteTerm 删除术语，不存在时返回false
+func (s *GlossaryStore) DeleteTerm(glossary, id string) (bool, error) {
+	result, err := s.DB.Exec(`DELETE FROM glossary_terms WHERE glossary = $1 AND term_id = $2`, glossary, id)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// DeleteGlossary 删除术语表中的全部术语，返回删除的术语数
+func (s *GlossaryStore) DeleteGlossary(glossary string) (int64, error) {
+	result, err := s.DB.Exec(`DELETE FROM glossary_terms WHERE glossary = $1`, glossary)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// queryTerms 执行术语查询
+func (s *GlossaryStore) queryTerms(query string, args ...interface{}) ([]models.GlossaryTerm, error) {
+	rows, err := s.DB.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	terms := []models.GlossaryTerm{}
+	for rows.Next() {
+		term, err := scanGlossaryTerm(rows)
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, *term)
+	}
+	return terms, rows.Err()
+}
+
+// duplicateTermError 将唯一索引冲突转换为ErrDuplicateTerm
+func duplicateTermError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+		return ErrDuplicateTerm
+	}
+	return err
+}
+
+// scanGlossaryTerm 扫描一行术语记录
+func scanGlossaryTerm(row rowScanner) (*models.GlossaryTerm, error) {
+	term := &models.GlossaryTerm{}
+	var note sql.NullString
+	var createdAt, updatedAt sql.NullTime
+	err := row.Scan(&term.ID, &term.Glossary, &term.SourceLang, &term.TargetLang, &term.SourceTerm, &term.TargetTerm,
+		&term.CaseSensitive, &note, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	term.Note = note.String
+	term.CreatedAt = createdAt.Time
+	term.UpdatedAt = updatedAt.Time
+	return term, nil
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// MCPServerStore 用户配置的MCP服务器存储
+type MCPServerStore struct {
+	DB *sql.DB
+}
+
+// NewMCPServerStore 创建新的MCP服务器存储实例
+func NewMCPServerStore(db *sql.DB) *MCPServerStore {
+	return &MCPServerStore{DB: db}
+}
+
+// ListServers 获取全部用户配置的MCP服务器
+func (s *MCPServerStore) ListServers() ([]models.MCPServer, error) {
+	rows, err := s.DB.Query(`
+		SELECT name, command, args, env, url, transport, headers, disabled, auto_approve
+		FROM mcp_servers
+		ORDER BY name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	servers := []models.MCPServer{}
+	for rows.Next() {
+		server, err := scanMCPServer(rows)
+		if err != nil {
+			return nil, err
+		}
+		servers = append(servers, *server)
+	}
+	return servers, rows.Err()
+}
+
+// GetServer 获取单个MCP服务器配置，不存在时返回sql.ErrNoRows
+func (s *MCPServerStore) GetServer(name string) (*models.MCPServer, error) {
+	row := s.DB.QueryRow(`
+		SELECT name, command, args, env, url, transport, headers, disabled, auto_approve
+		FROM mcp_servers
+		WHERE name = $1
+	`, name)
+	return scanMCPServer(row)
+}
+
+// CreateServer 新增MCP服务器配置
+func (s *MCPServerStore) CreateServer(server models.MCPServer) error {
+	args, env, headers, autoApprove, err := marshalMCPServerFields(server)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = s.DB.Exec(`
+		INSERT INTO mcp_servers (name, command, args, env, url, transport, headers, disabled, auto_approve, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+	`, server.Name, server.Command, args, env, server.URL, server.Transport, headers, server.Disabled, autoApprove, now)
+	return err
+}
+
+// SaveServer 保存MCP服务器配置，不存在时新增，存在时覆盖
+func (s *MCPServerStore) SaveServer(server models.MCPServer) error {
+	args, env, headers, autoApprove, err := marshalMCPServerFields(server)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	_, err = s.DB.Exec(`
+		INSERT INTO mcp_servers (name, command, args, env, url, transport, headers, disabled, auto_approve, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $10)
+		ON CONFLICT (name) DO UPDATE
+		SET command = EXCLUDED.command, args = EXCLUDED.args, env = EXCLUDED.env, url = EXCLUDED.url,
+			transport = EXCLUDED.transport, headers = EXCLUDED.headers, disabled = EXCLUDED.disabled,
+			auto_approve = EXCLUDED.auto_approve, updated_at = EXCLUDED.updated_at
+	`, server.Name, server.Command, args, env, server.URL, server.Transport, headers, server.Disabled, autoApprove, now)
+	return err
+}
+
+// DeleteServer 删除MCP服务器配置，不存在时返回false
+func (s *MCPServerStore) DeleteServer(name string) (bool, error) {
+	result, err := s.DB.Exec(`DELETE FROM mcp_servers WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// marshalMCPServerFields 将列表和映射字段序列化为JSON文本
+func marshalMCPServerFields(server models.MCPServer) (args, env, headers, autoApprove string, err error) {
+	fields := []interface{}{server.Args, server.Env, server.Headers, server.AutoApprove}
+	values := make([]string, len(fields))
+	for i, field := range fields {
+		data, err := json.Marshal(field)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		values[i] = string(data)
+	}
+	return values[0], values[1], values[2], values[3], nil
+}
+
+// scanMCPServer 扫描一行MCP服务器记录
+func scanMCPServer(row rowScanner) (*models.MCPServer, error) {
+	server := &models.MCPServer{}
+	var command, args, env, url, transport, headers, autoApprove sql.NullString
+	err := row.Scan(&server.Name, &command, &args, &env, &url, &transport, &headers, &server.Disabled, &autoApprove)
+	if err != nil {
+		return nil, err
+	}
+
+	server.Command = command.String
+	server.URL = url.String
+	server.Transport = transport.String
+	fields := []struct {
+		data   string
+		target interface{}
+	}{
+		{args.String, &server.Args},
+		{env.String, &server.Env},
+		{headers.String, &server.Headers},
+		{autoApprove.String, &server.AutoApprove},
+	}
+	for _, field := range fields {
+		if field.data == "" {
+			continue
+		}
+		if err := json.Unmarshal([]byte(field.data), field.target); err != nil {
+			return nil, err
+		}
+	}
+	return server, nil
+}
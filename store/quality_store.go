@@ -0,0 +1,88 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// QualityStore 译文质量分数存储
+type QualityStore struct {
+	DB *sql.DB
+}
+
+// QualityFilter 质量统计的查询条件，空值表示不限制
+type QualityFilter struct {
+	Provider string    // 翻译的服务商
+	Model    string    // 翻译的模型
+	Target   string    // 目标语言
+	Since    time.Time // 只统计该时间之后的分数
+}
+
+// NewQualityStore 创建新的质量分数存储实例
+func NewQualityStore(db *sql.DB) *QualityStore {
+	return &QualityStore{DB: db}
+}
+
+// SaveQualityScores 批量保存片段的质量分数
+func (s *QualityStore) SaveQualityScores(scores []models.QualityScore) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, score := range scores {
+		issues, err := json.Marshal(score.Issues)
+		if err != nil {
+			return err
+		}
+		counts := make(map[string]int)
+		for _, issue := range score.Issues {
+			counts[issue.Type]++
+		}
+		_, err = tx.Exec(`
+			INSERT INTO translation_quality (provider, model, judge, method, source_lang, target_lang, score, issues,
+				omission_count, mistranslation_count, untranslated_count, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		`, score.Provider, score.Model, score.Judge, score.Method, score.Source, score.Target, score.Score, string(issues),
+			counts[models.IssueOmission], counts[models.IssueMistranslation], counts[models.IssueUntranslated], score.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// QualityStats 按服务商、模型和日期汇总质量分数，按日期倒序返回
+func (s *QualityStore) QualityStats(filter QualityFilter) ([]models.QualityStats, error) {
+	rows, err := s.DB.Query(`
+		SELECT provider, model, TO_CHAR(DATE_TRUNC('day', created_at), 'YYYY-MM-DD') AS day,
+			COUNT(*), AVG(score), SUM(omission_count), SUM(mistranslation_count), SUM(untranslated_count)
+		FROM translation_quality
+		WHERE ($1::text = '' OR provider = $1)
+			AND ($2::text = '' OR model = $2)
+			AND ($3::text = '' OR target_lang = $3)
+			AND ($4::timestamp IS NULL OR created_at >= $4)
+		GROUP BY provider, model, day
+		ORDER BY day DESC, provider, model
+	`, filter.Provider, filter.Model, filter.Target, nullTime(filter.Since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []models.QualityStats{}
+	for rows.Next() {
+		var stat models.QualityStats
+		err := rows.Scan(&stat.Provider, &stat.Model, &stat.Day, &stat.Segments, &stat.Average,
+			&stat.Omission, &stat.Mistranslation, &stat.Untranslated)
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, stat)
+	}
+	return stats, rows.Err()
+}
@@ -2,6 +2,7 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 
 	"github.com/aimmetal-tech/wistrans-backend/models"
 	"github.com/sashabaranov/go-openai"
@@ -54,20 +55,24 @@ func (s *SessionStore) GetConversation(id string) (*models.Conversation, error)
 
 // CreateMessage 创建消息
 func (s *SessionStore) CreateMessage(message *models.Message) error {
-	_, err := s.DB.Exec(`
-		INSERT INTO messages (conversation_id, role, content, created_at)
-		VALUES ($1, $2, $3, $4)
-	`, message.ConversationID, message.Role, message.Content, message.CreatedAt)
+	toolCalls, err := message.ToolCallsJSON()
+	if err != nil {
+		return err
+	}
+	_, err = s.DB.Exec(`
+		INSERT INTO messages (conversation_id, role, content, name, tool_calls, tool_call_id, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, message.ConversationID, message.Role, message.Content, message.Name, toolCalls, message.ToolCallID, message.CreatedAt)
 	return err
 }
 
 // GetMessagesByConversationID 获取会话的所有消息
 func (s *SessionStore) GetMessagesByConversationID(conversationID string) ([]*models.Message, error) {
 	rows, err := s.DB.Query(`
-		SELECT message_id, conversation_id, role, content, created_at
+		SELECT message_id, conversation_id, role, content, name, tool_calls, tool_call_id, created_at
 		FROM messages
 		WHERE conversation_id = $1
-		ORDER BY created_at ASC
+		ORDER BY created_at ASC, message_id ASC
 	`, conversationID)
 
 	if err != nil {
@@ -79,10 +84,18 @@ func (s *SessionStore) GetMessagesByConversationID(conversationID string) ([]*mo
 	var messages []*models.Message
 	for rows.Next() {
 		message := &models.Message{}
-		err := rows.Scan(&message.MessageID, &message.ConversationID, &message.Role, &message.Content, &message.CreatedAt)
+		var name, toolCalls, toolCallID sql.NullString
+		err := rows.Scan(&message.MessageID, &message.ConversationID, &message.Role, &message.Content, &name, &toolCalls, &toolCallID, &message.CreatedAt)
 		if err != nil {
 			return nil, err
 		}
+		message.Name = name.String
+		message.ToolCallID = toolCallID.String
+		if toolCalls.String != "" {
+			if err := json.Unmarshal([]byte(toolCalls.String), &message.ToolCalls); err != nil {
+				return nil, err
+			}
+		}
 		messages = append(messages, message)
 	}
 
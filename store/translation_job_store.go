@@ -0,0 +1,297 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"math"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// maxJobErrors 每个翻译任务保留的失败原因条数，超出时只保留最近的失败原因
+const maxJobErrors = 100
+
+// TranslationJobStore 异步翻译任务存储
+type TranslationJobStore struct {
+	DB *sql.DB
+}
+
+// NewTranslationJobStore 创建新的翻译任务存储实例
+func NewTranslationJobStore(db *sql.DB) *TranslationJobStore {
+	return &TranslationJobStore{DB: db}
+}
+
+// CreateJob 创建翻译任务并保存全部待翻译的片段，请求参数中的片段不重复保存
+func (s *TranslationJobStore) CreateJob(job *models.TranslationJob, segments []models.TranslateSegment) error {
+	params := job.Request
+	params.Segments = nil
+	request, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO translation_jobs (job_id, status, target, source, filename, request, document, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+	`, job.ID, job.Status, job.Target, job.Source, job.Filename, string(request), job.Document, job.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.Prepare(`
+		INSERT INTO translation_job_segments (job_id, position, segment_id, source_text)
+		VALUES ($1, $2, $3, $4)
+	`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for i, segment := range segments {
+		if _, err := stmt.Exec(job.ID, i, segment.ID, segment.Text); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetJob 获取翻译任务及其进度，不包含片段
+func (s *TranslationJobStore) GetJob(id string) (*models.TranslationJob, error) {
+	row := s.DB.QueryRow(`
+		SELECT j.job_id, j.status, j.target, j.source, j.filename, j.request, j.provider, j.model, j.errors, j.error,
+			j.created_at, j.updated_at, j.started_at, j.finished_at,
+			COUNT(s.position),
+			COUNT(s.status),
+			COUNT(*) FILTER (WHERE s.status = $2),
+			COUNT(*) FILTER (WHERE s.cached),
+			COUNT(*) FILTER (WHERE s.status = $3),
+			COUNT(*) FILTER (WHERE s.status = $4)
+		FROM translation_jobs j
+		LEFT JOIN translation_job_segments s ON s.job_id = j.job_id
+		WHERE j.job_id = $1
+		GROUP BY j.job_id
+	`, id, models.SegmentTranslated, models.SegmentSkipped, models.SegmentFailed)
+	return scanTranslationJob(row)
+}
+
+// ListJobSegments 获取已处理的片段，按提交的顺序排列
+func (s *TranslationJobStore) ListJobSegments(id string) ([]models.TranslateSegment, error) {
+	rows, err := s.DB.Query(`
+		SELECT result
+		FROM translation_job_segments
+		WHERE job_id = $1 AND status IS NOT NULL
+		ORDER BY position
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	segments := []models.TranslateSegment{}
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return nil, err
+		}
+		var segment models.TranslateSegment
+		if err := json.Unmarshal([]byte(result), &segment); err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, rows.Err()
+}
+
+// GetJobDocument 获取任务上传的原始文件，直接提交片段的任务返回nil
+func (s *TranslationJobStore) GetJobDocument(id string) ([]byte, error) {
+	var document []byte
+	err := s.DB.QueryRow(`SELECT document FROM translation_jobs WHERE job_id = $1`, id).Scan(&document)
+	return document, err
+}
+
+// PendingJobSegments 按提交的顺序获取最多limit个尚未处理的片段
+func (s *TranslationJobStore) PendingJobSegments(id string, limit int) ([]models.TranslateSegment, error) {
+	rows, err := s.DB.Query(`
+		SELECT segment_id, source_text
+		FROM translation_job_segments
+		WHERE job_id = $1 AND status IS NULL
+		ORDER BY position
+		LIMIT $2
+	`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var segments []models.TranslateSegment
+	for rows.Next() {
+		var segment models.TranslateSegment
+		if err := rows.Scan(&segment.ID, &segment.Text); err != nil {
+			return nil, err
+		}
+		segments = append(segments, segment)
+	}
+	return segments, rows.Err()
+}
+
+// SaveJobSegments 保存一批片段的处理结果，同时记录实际使用的服务商和模型以及失败原因。
+// 任务已不属于worker（已被取消或租约过期后被其他实例接管）时不保存，返回false
+func (s *TranslationJobStore) SaveJobSegments(id, worker string, segments []models.TranslateSegment, provider, model string, errors []string) (bool, error) {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	// 先锁定任务行并确认所有权，避免与接管任务的实例同时写入片段
+	// 失败原因只保留最近的maxJobErrors条，大文件的任务不会无限增长
+	result, err := tx.Exec(`
+		UPDATE translation_jobs
+		SET provider = COALESCE(NULLIF($2, ''), provider), model = COALESCE(NULLIF($3, ''), model),
+			errors = (COALESCE(errors, '{}') || $4::text[])[GREATEST(COALESCE(cardinality(errors), 0) + cardinality($4::text[]) - $8 + 1, 1):],
+			updated_at = $5
+		WHERE job_id = $1 AND worker_id = $6 AND status = $7
+	`, id, provider, model, pq.Array(errors), time.Now(), worker, models.JobRunning, maxJobErrors)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	for _, segment := range segments {
+		result, err := json.Marshal(segment)
+		if err != nil {
+			return false, err
+		}
+		_, err = tx.Exec(`
+			UPDATE translation_job_segments
+			SET status = $3, cached = $4, result = $5
+			WHERE job_id = $1 AND segment_id = $2
+		`, id, segment.ID, segment.Status, segment.Cached, string(result))
+		if err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+// ClaimJob 为worker领取最早创建的排队任务，或租约已过期的处理中任务（处理它的实例已退出），
+// 任务的租约在lease后过期。租约按数据库的时间计算，不受各实例时钟偏差的影响。没有可领取的任务时返回空字符串
+func (s *TranslationJobStore) ClaimJob(worker string, lease time.Duration) (string, error) {
+	var id string
+	err := s.DB.QueryRow(`
+		UPDATE translation_jobs
+		SET status = $1, worker_id = $3, lease_expires_at = CURRENT_TIMESTAMP + $4 * interval '1 millisecond',
+			started_at = COALESCE(started_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+		WHERE job_id = (
+			SELECT job_id FROM translation_jobs
+			WHERE status = $2 OR (status = $1 AND (lease_expires_at IS NULL OR lease_expires_at < CURRENT_TIMESTAMP))
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING job_id
+	`, models.JobRunning, models.JobQueued, worker, lease.Milliseconds()).Scan(&id)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return id, err
+}
+
+// RenewJob 按数据库的时间延长worker处理中任务的租约，任务已被取消、已结束或被其他实例接管时返回false
+func (s *TranslationJobStore) RenewJob(id, worker string, lease time.Duration) (bool, error) {
+	result, err := s.DB.Exec(`
+		UPDATE translation_jobs
+		SET lease_expires_at = CURRENT_TIMESTAMP + $3 * interval '1 millisecond'
+		WHERE job_id = $1 AND worker_id = $2 AND status = $4
+	`, id, worker, lease.Milliseconds(), models.JobRunning)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// FinishJob 将worker处理中的任务更新为完成、失败或重新排队，任务已被取消或被其他实例接管时返回false
+func (s *TranslationJobStore) FinishJob(id, worker, status, errMsg string) (bool, error) {
+	var finishedAt sql.NullTime
+	if status != models.JobQueued {
+		finishedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	result, err := s.DB.Exec(`
+		UPDATE translation_jobs
+		SET status = $2, error = $3, finished_at = $4, updated_at = $5, worker_id = NULL, lease_expires_at = NULL
+		WHERE job_id = $1 AND worker_id = $7 AND status = $6
+	`, id, status, errMsg, finishedAt, time.Now(), models.JobRunning, worker)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// CancelJob 取消排队或处理中的任务，任务不存在或已经结束时返回false
+func (s *TranslationJobStore) CancelJob(id string) (bool, error) {
+	now := time.Now()
+	result, err := s.DB.Exec(`
+		UPDATE translation_jobs
+		SET status = $2, finished_at = $3, updated_at = $3
+		WHERE job_id = $1 AND status IN ($4, $5)
+	`, id, models.JobCancelled, now, models.JobQueued, models.JobRunning)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+// scanTranslationJob 扫描一行翻译任务及其片段统计
+func scanTranslationJob(row rowScanner) (*models.TranslationJob, error) {
+	job := &models.TranslationJob{}
+	var source, filename, provider, model, errMsg sql.NullString
+	var request string
+	var startedAt, finishedAt sql.NullTime
+	err := row.Scan(&job.ID, &job.Status, &job.Target, &source, &filename, &request, &provider, &model,
+		pq.Array(&job.Errors), &errMsg, &job.CreatedAt, &job.UpdatedAt, &startedAt, &finishedAt,
+		&job.Total, &job.Processed, &job.Translated, &job.Cached, &job.Skipped, &job.Failed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal([]byte(request), &job.Request); err != nil {
+		return nil, err
+	}
+	job.Source = source.String
+	job.Filename = filename.String
+	job.Provider = provider.String
+	job.Model = model.String
+	job.Error = errMsg.String
+	if startedAt.Valid {
+		job.StartedAt = &startedAt.Time
+	}
+	if finishedAt.Valid {
+		job.FinishedAt = &finishedAt.Time
+	}
+	if job.Total > 0 {
+		job.Progress = math.Round(float64(job.Processed)/float64(job.Total)*1000) / 10
+	}
+	return job, nil
+}
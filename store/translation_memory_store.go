@@ -0,0 +1,183 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// TranslationMemoryStore 翻译记忆存储
+type TranslationMemoryStore struct {
+	DB *sql.DB
+}
+
+// TranslationMemoryFilter 翻译记忆的查询和清理条件，空值表示不限制
+type TranslationMemoryFilter struct {
+	Target string    // 目标语言
+	Model  string    // "服务商/模型"
+	Query  string    // 原文或译文中包含的文本
+	Before time.Time // 最近命中时间早于该时间
+}
+
+// NewTranslationMemoryStore 创建新的翻译记忆存储实例
+func NewTranslationMemoryStore(db *sql.DB) *TranslationMemoryStore {
+	return &TranslationMemoryStore{DB: db}
+}
+
+// LookupTranslations 按键批量查询译文，命中的条目增加命中次数
+func (s *TranslationMemoryStore) LookupTranslations(keys []string) (map[string]string, error) {
+	rows, err := s.DB.Query(`
+		UPDATE translation_memory
+		SET hits = hits + 1, last_used_at = $2
+		WHERE key = ANY($1)
+		RETURNING key, translation
+	`, pq.Array(keys), time.Now())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	translations := make(map[string]string)
+	for rows.Next() {
+		var key, translation string
+		if err := rows.Scan(&key, &translation); err != nil {
+			return nil, err
+		}
+		translations[key] = translation
+	}
+	return translations, rows.Err()
+}
+
+// TouchTranslations 为进程内缓存命中的条目增加命中次数
+func (s *TranslationMemoryStore) TouchTranslations(keys []string) error {
+	_, err := s.DB.Exec(`
+		UPDATE translation_memory
+		SET hits = hits + 1, last_used_at = $2
+		WHERE key = ANY($1)
+	`, pq.Array(keys), time.Now())
+	return err
+}
+
+// SaveTranslations 保存译文，键已存在时更新译文
+func (s *TranslationMemoryStore) SaveTranslations(entries []models.TranslationMemoryEntry) error {
+	tx, err := s.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for _, entry := range entries {
+		_, err := tx.Exec(`
+			INSERT INTO translation_memory (key, source_text, target, model, style, format, translation, hits, created_at, last_used_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, 0, $8, $8)
+			ON CONFLICT (key) DO UPDATE
+			SET translation = EXCLUDED.translation, last_used_at = EXCLUDED.last_used_at
+		`, entry.Key, entry.SourceText, entry.Target, entry.Model, entry.Style, entry.Format, entry.Translation, now)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetTranslation 获取翻译记忆条目
+func (s *TranslationMemoryStore) GetTranslation(key string) (*models.TranslationMemoryEntry, error) {
+	row := s.DB.QueryRow(`
+		SELECT key, source_text, target, model, style, format, translation, hits, created_at, last_used_at
+		FROM translation_memory
+		WHERE key = $1
+	`, key)
+	return scanTranslationMemory(row)
+}
+
+// ListTranslations 按条件分页获取翻译记忆条目，按最近命中时间倒序，同时返回符合条件的总数
+func (s *TranslationMemoryStore) ListTranslations(filter TranslationMemoryFilter, limit, offset int) ([]*models.TranslationMemoryEntry, int, error) {
+	var total int
+	err := s.DB.QueryRow(`
+		SELECT COUNT(*)
+		FROM translation_memory
+		WHERE ($1::text = '' OR target = $1)
+			AND ($2::text = '' OR model = $2)
+			AND ($3::text = '' OR source_text ILIKE '%' || $3 || '%' OR translation ILIKE '%' || $3 || '%')
+			AND ($4::timestamp IS NULL OR last_used_at < $4)
+	`, filter.Target, filter.Model, filter.Query, nullTime(filter.Before)).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.DB.Query(`
+		SELECT key, source_text, target, model, style, format, translation, hits, created_at, last_used_at
+		FROM translation_memory
+		WHERE ($1::text = '' OR target = $1)
+			AND ($2::text = '' OR model = $2)
+			AND ($3::text = '' OR source_text ILIKE '%' || $3 || '%' OR translation ILIKE '%' || $3 || '%')
+			AND ($4::timestamp IS NULL OR last_used_at < $4)
+		ORDER BY last_used_at DESC
+		LIMIT $5 OFFSET $6
+	`, filter.Target, filter.Model, filter.Query, nullTime(filter.Before), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	entries := []*models.TranslationMemoryEntry{}
+	for rows.Next() {
+		entry, err := scanTranslationMemory(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, total, rows.Err()
+}
+
+// DeleteTranslation 删除翻译记忆条目，条目不存在时返回false
+func (s *TranslationMemoryStore) DeleteTranslation(key string) (bool, error) {
+	result, err := s.DB.Exec(`DELETE FROM translation_memory WHERE key = $1`, key)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// PurgeTranslations 按条件删除翻译记忆条目，返回删除的条目数
+func (s *TranslationMemoryStore) PurgeTranslations(filter TranslationMemoryFilter) (int64, error) {
+	result, err := s.DB.Exec(`
+		DELETE FROM translation_memory
+		WHERE ($1::text = '' OR target = $1)
+			AND ($2::text = '' OR model = $2)
+			AND ($3::text = '' OR source_text ILIKE '%' || $3 || '%' OR translation ILIKE '%' || $3 || '%')
+			AND ($4::timestamp IS NULL OR last_used_at < $4)
+	`, filter.Target, filter.Model, filter.Query, nullTime(filter.Before))
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// nullTime 零值时间转换为NULL
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// scanTranslationMemory 扫描一行翻译记忆
+func scanTranslationMemory(row rowScanner) (*models.TranslationMemoryEntry, error) {
+	entry := &models.TranslationMemoryEntry{}
+	var style, format sql.NullString
+	err := row.Scan(&entry.Key, &entry.SourceText, &entry.Target, &entry.Model, &style, &format,
+		&entry.Translation, &entry.Hits, &entry.CreatedAt, &entry.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+	entry.Style = style.String
+	entry.Format = format.String
+	return entry, nil
+}
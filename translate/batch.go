@@ -0,0 +1,49 @@
+package translate
+
+import (
+	"unicode"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// segmentOverheadTokens 每个片段在提示词和JSON结果中的额外开销（片段ID、引号等）
+const segmentOverheadTokens = 8
+
+// batch 一次大模型调用翻译的一组片段
+type batch struct {
+	index    int
+	segments []models.TranslateSegment
+}
+
+// estimateTokens 粗略估算文本的token数：中日韩字符约每字1个token，其他字符约每4个字节1个token
+func estimateTokens(text string) int {
+	cjk, other := 0, 0
+	for _, r := range text {
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+			cjk++
+		} else {
+			other += len(string(r))
+		}
+	}
+	return cjk + (other+3)/4
+}
+
+// splitBatches 按token预算将片段按顺序分组，单个片段超过预算时单独成组
+func splitBatches(segments []models.TranslateSegment, budget, maxSegments int) []batch {
+	var batches []batch
+	var current []models.TranslateSegment
+	used := 0
+	for _, segment := range segments {
+		tokens := estimateTokens(segment.Text) + segmentOverheadTokens
+		if len(current) > 0 && (used+tokens > budget || len(current) >= maxSegments) {
+			batches = append(batches, batch{index: len(batches), segments: current})
+			current, used = nil, 0
+		}
+		current = append(current, segment)
+		used += tokens
+	}
+	if len(current) > 0 {
+		batches = append(batches, batch{index: len(batches), segments: current})
+	}
+	return batches
+}
@@ -0,0 +1,74 @@
+package translate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"abcd", 1},
+		{"abcde", 2},
+		{"你好世界", 4},
+		{"こんにちは", 5},
+		{"안녕", 2},
+		{"你好 world", 2 + 2},
+		{"héllo", 2}, // é占2个字节
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.text); got != tt.want {
+			t.Errorf("estimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+// batchIDs 返回各批次的片段ID
+func batchIDs(batches []batch) [][]string {
+	ids := make([][]string, len(batches))
+	for i, b := range batches {
+		if b.index != i {
+			ids[i] = append(ids[i], fmt.Sprintf("index=%d", b.index))
+		}
+		for _, segment := range b.segments {
+			ids[i] = append(ids[i], segment.ID)
+		}
+	}
+	return ids
+}
+
+func TestSplitBatches(t *testing.T) {
+	// 每个片段按estimateTokens加上segmentOverheadTokens计算
+	segment := func(id string, tokens int) models.TranslateSegment {
+		return models.TranslateSegment{ID: id, Text: strings.Repeat("字", tokens-segmentOverheadTokens)}
+	}
+	tests := []struct {
+		name        string
+		segments    []models.TranslateSegment
+		budget      int
+		maxSegments int
+		want        [][]string
+	}{
+		{"empty", nil, 100, 10, [][]string{}},
+		{"fits in one batch", []models.TranslateSegment{segment("a", 30), segment("b", 30), segment("c", 40)}, 100, 10, [][]string{{"a", "b", "c"}}},
+		{"budget overflow", []models.TranslateSegment{segment("a", 40), segment("b", 40), segment("c", 40), segment("d", 40)}, 100, 10, [][]string{{"a", "b"}, {"c", "d"}}},
+		{"oversized segment alone", []models.TranslateSegment{segment("a", 20), segment("big", 500), segment("b", 20)}, 100, 10, [][]string{{"a"}, {"big"}, {"b"}}},
+		{"oversized segment first", []models.TranslateSegment{segment("big", 500), segment("a", 20)}, 100, 10, [][]string{{"big"}, {"a"}}},
+		{"max segments", []models.TranslateSegment{segment("a", 10), segment("b", 10), segment("c", 10), segment("d", 10), segment("e", 10)}, 1000, 2, [][]string{{"a", "b"}, {"c", "d"}, {"e"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := batchIDs(splitBatches(tt.segments, tt.budget, tt.maxSegments))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitBatches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package translate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// 不需要翻译的片段的跳过原因
+const (
+	SkipTargetLanguage = "target_language" // 片段已经是目标语言
+	SkipUntranslatable = "untranslatable"  // 片段只包含数字、符号、代码、网址等
+)
+
+var (
+	// detectNoisePattern 语言识别前去掉的内容：保护标记、网址、邮箱和包含数字的词
+	detectNoisePattern = regexp.MustCompile(`⟦\s*\d+\s*⟧|https?://\S+|www\.\S+|\S+@\S+\.\S+|\S*\d\S*`)
+	// identifierPattern 代码标识符和品牌名：驼峰、下划线或点号连接的名称。
+	// 全大写的单词可能是按钮文字或标题（如SAVE、WARNING），不视为标识符
+	identifierPattern = regexp.MustCompile(`^(?:[a-z]+[A-Z][A-Za-z]*|[A-Z][a-z]+[A-Z][A-Za-z]*|[A-Za-z]+(?:[_.][A-Za-z]+)+)$`)
+)
+
+// trigramProfiles 使用拉丁字母的语言的常见三元组，词首词尾用空格表示
+var trigramProfiles = map[string][]string{
+	"en": {" th", "the", "he ", "ing", "nd ", " an", "and", " of", "of ", " to", "ed ", "ion", " in", "to ", "er ", "tio", "is ", " is", " wh", "hat", "at ", "you", " yo", "ll "},
+	"fr": {" de", "es ", "de ", "le ", " le", " la", "la ", "les", "re ", " et", "et ", " qu", "que", "ue ", "des", " un", "ait", "eur", " pa", "pou", "our", " vo", "vou", "ous"},
+	"de": {"en ", "er ", " de", "der", "ie ", "ich", "ein", "sch", " di", "die", "und", " un", "nd ", "che", "ch ", "den", " ei", "cht", "gen", "ung", " zu", "ten", "ist", "ür "},
+	"es": {" de", "de ", "os ", "la ", " la", "el ", " el", "es ", " qu", "que", "ue ", " en", "en ", "as ", "ión", "do ", " lo", "los", " se", "ien", "con", " y ", "ar ", "por"},
+	"it": {" di", "di ", "la ", " la", "to ", "re ", "che", " ch", "he ", "ell", "one", " il", "il ", "per", " pe", "no ", "lla", "del", "zio", " co", "ato", "gli", "ere", " è "},
+	"pt": {" de", "de ", "os ", "do ", " qu", "que", "ue ", "ção", "ão ", " do", "da ", " da", "as ", "em ", " e ", "nte", "ado", " a ", "com", "par", "men", " nã", "não", "ões"},
+	"nl": {"en ", " de", "de ", "an ", "van", " va", "het", " he", "et ", "een", " ee", "ijk", "der", "aar", " in", "in ", "oor", "ver", " ve", "lij", "nde", " ge", "zij", "ij "},
+}
+
+// detection 片段的语言识别结果
+type detection struct {
+	lang    string // 识别出的语言代码，无法识别时为空
+	variant string // 中文的简繁体 (hans, hant)，无法区分时为空
+	skip    string // 不需要翻译的原因，需要翻译时为空
+}
+
+// detectLanguage 根据文字的书写系统和拉丁字母的三元组识别文本的语言，不需要网络
+func detectLanguage(text string) detection {
+	cleaned := detectNoisePattern.ReplaceAllString(text, " ")
+
+	// 没有字母的片段（数字、符号）和代码标识符不需要翻译
+	words := strings.FieldsFunc(cleaned, func(r rune) bool { return !unicode.IsLetter(r) && r != '_' && r != '.' })
+	if len(words) == 0 {
+		return detection{skip: SkipUntranslatable}
+	}
+	if len(words) == 1 && identifierPattern.MatchString(words[0]) {
+		return detection{skip: SkipUntranslatable}
+	}
+
+	counts := make(map[string]int)
+	letters := 0
+	for _, r := range cleaned {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			counts["kana"]++
+		case unicode.Is(unicode.Han, r):
+			counts["han"]++
+		case unicode.Is(unicode.Hangul, r):
+			counts["ko"]++
+		case unicode.Is(unicode.Cyrillic, r):
+			counts["ru"]++
+		case unicode.Is(unicode.Arabic, r):
+			counts["ar"]++
+		case unicode.Is(unicode.Hebrew, r):
+			counts["he"]++
+		case unicode.Is(unicode.Thai, r):
+			counts["th"]++
+		case unicode.Is(unicode.Devanagari, r):
+			counts["hi"]++
+		case unicode.Is(unicode.Greek, r):
+			counts["el"]++
+		case unicode.Is(unicode.Latin, r):
+			counts["latin"]++
+		}
+	}
+
+	// 日文中混有汉字，出现假名即认为是日文
+	if counts["kana"] > 0 && counts["kana"]*5 >= counts["han"] {
+		return detection{lang: "ja"}
+	}
+
+	script, best := "", 0
+	for name, count := range counts {
+		if count > best || (count == best && name < script) {
+			script, best = name, count
+		}
+	}
+	// 主要书写系统不足字母的一半时视为混合语言，不做判断
+	if best*2 < letters {
+		return detection{}
+	}
+
+	switch script {
+	case "han":
+		return detection{lang: "zh", variant: chineseVariant(cleaned)}
+	case "kana":
+		return detection{lang: "ja"}
+	case "latin":
+		return detection{lang: detectLatin(words)}
+	}
+	return detection{lang: script}
+}
+
+// detectLatin 按三元组识别拉丁字母的语言，证据不足时返回空
+func detectLatin(words []string) string {
+	padded := " " + strings.ToLower(strings.Join(words, " ")) + " "
+
+	scores := make(map[string]int)
+	for lang, trigrams := range trigramProfiles {
+		for _, trigram := range trigrams {
+			scores[lang] += strings.Count(padded, trigram)
+		}
+	}
+
+	lang, best := "", 0
+	for name, score := range scores {
+		if score > best || (score == best && name < lang) {
+			lang, best = name, score
+		}
+	}
+	second := 0
+	for name, score := range scores {
+		if name != lang && score > second {
+			second = score
+		}
+	}
+	// 至少命中两个三元组，且明显多于第二名
+	if best < 2 || best*2 < second*3 {
+		return ""
+	}
+	return lang
+}
+
+// 只在简体或繁体中文中使用的常见汉字
+const (
+	simplifiedChars  = "们个来说时会这为国学还对后开关体长问点从当发么经过进动现实于无业员热门际话认让语设请读写买卖选项览载"
+	traditionalChars = "們個來說時會這為國學還對後開關體長問點從當發麼經過進動現實於無業員熱門際話認讓語設請讀寫買賣選項覽載"
+)
+
+// chineseVariant 根据简繁体专用字判断中文是简体还是繁体，无法区分时返回空
+func chineseVariant(text string) string {
+	simplified, traditional := 0, 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(simplifiedChars, r):
+			simplified++
+		case strings.ContainsRune(traditionalChars, r):
+			traditional++
+		}
+	}
+	switch {
+	case simplified > traditional:
+		return "hans"
+	case traditional > simplified:
+		return "hant"
+	}
+	return ""
+}
+
+// languageAliases 语言的常见写法
+var languageAliases = map[string]string{
+	"english": "en", "英语": "en", "英文": "en",
+	"chinese": "zh", "中文": "zh", "汉语": "zh", "简体中文": "zh-hans", "繁体中文": "zh-hant", "繁體中文": "zh-hant",
+	"japanese": "ja", "日语": "ja", "日文": "ja",
+	"korean": "ko", "韩语": "ko", "韩文": "ko",
+	"french": "fr", "法语": "fr",
+	"german": "de", "德语": "de",
+	"spanish": "es", "西班牙语": "es",
+	"italian": "it", "意大利语": "it",
+	"portuguese": "pt", "葡萄牙语": "pt",
+	"dutch": "nl", "荷兰语": "nl",
+	"russian": "ru", "俄语": "ru",
+	"arabic": "ar", "阿拉伯语": "ar",
+	"hebrew": "he", "希伯来语": "he",
+	"thai": "th", "泰语": "th",
+	"hindi": "hi", "印地语": "hi",
+	"greek": "el", "希腊语": "el",
+}
+
+// CanonicalLang 将语言名称或代码统一为小写的语言代码，常见写法按languageAliases转换，例如"English"、"英语"转换为en，
+// "zh_TW"转换为zh-tw
+func CanonicalLang(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if alias, ok := languageAliases[lang]; ok {
+		return alias
+	}
+	return strings.ReplaceAll(lang, "_", "-")
+}
+
+// parseTarget 将目标语言转换为语言代码和中文的简繁体，例如"zh-TW"转换为zh和hant
+func parseTarget(target string) (string, string) {
+	target = strings.ToLower(strings.TrimSpace(target))
+	if alias, ok := languageAliases[target]; ok {
+		target = alias
+	}
+	parts := strings.FieldsFunc(target, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return "", ""
+	}
+
+	lang, variant := parts[0], ""
+	if lang == "zh" {
+		for _, part := range parts[1:] {
+			switch part {
+			case "hans", "cn", "sg", "my":
+				variant = "hans"
+			case "hant", "tw", "hk", "mo":
+				variant = "hant"
+			}
+		}
+	}
+	return lang, variant
+}
+
+// isTarget 判断识别结果是否已经是目标语言。中文的简繁体与目标不一致时仍需要翻译
+func (d detection) isTarget(lang, variant string) bool {
+	if d.lang == "" || d.lang != lang {
+		return false
+	}
+	return variant == "" || d.variant == "" || d.variant == variant
+}
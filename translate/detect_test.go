@@ -0,0 +1,158 @@
+package translate
+
+import "testing"
+
+func TestDetectLanguage(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want detection
+	}{
+		// 书写系统
+		{"simplified chinese", "我们这个问题现在还没有解决", detection{lang: "zh", variant: "hans"}},
+		{"traditional chinese", "我們這個問題現在還沒有解決", detection{lang: "zh", variant: "hant"}},
+		{"chinese without variant chars", "你好世界", detection{lang: "zh"}},
+		{"japanese with kanji", "これは日本語の文章です", detection{lang: "ja"}},
+		{"katakana only", "コンピューター", detection{lang: "ja"}},
+		{"korean", "안녕하세요 반갑습니다", detection{lang: "ko"}},
+		{"russian", "Привет, как дела?", detection{lang: "ru"}},
+		{"arabic", "مرحبا بالعالم", detection{lang: "ar"}},
+		{"hebrew", "שלום עולם", detection{lang: "he"}},
+		{"thai", "สวัสดีครับ", detection{lang: "th"}},
+		{"hindi", "नमस्ते दुनिया", detection{lang: "hi"}},
+		{"greek", "Γειά σου κόσμε", detection{lang: "el"}},
+		{"mixed scripts", "Hello 你好", detection{}},
+
+		// 拉丁字母按三元组识别
+		{"english", "The quick brown fox jumps over the lazy dog and then runs to the house.", detection{lang: "en"}},
+		{"french", "Je pense que les enfants de la ville vont à l'école pour apprendre.", detection{lang: "fr"}},
+		{"german", "Ich glaube, dass die Kinder in der Schule und zu Hause lernen.", detection{lang: "de"}},
+		{"spanish", "Los niños de la ciudad van a la escuela con sus amigos por la mañana.", detection{lang: "es"}},
+		{"short latin", "Hello", detection{}},
+
+		// 不需要翻译的内容
+		{"numbers and symbols", "42 + 3.14 = ?", detection{skip: SkipUntranslatable}},
+		{"url", "https://example.com/path", detection{skip: SkipUntranslatable}},
+		{"email", "support@example.com", detection{skip: SkipUntranslatable}},
+		{"camel case", "getUserName", detection{skip: SkipUntranslatable}},
+		{"pascal case", "WisTrans", detection{skip: SkipUntranslatable}},
+		{"snake case", "max_tokens", detection{skip: SkipUntranslatable}},
+		{"dotted name", "os.Getenv", detection{skip: SkipUntranslatable}},
+		{"only markers", "⟦1⟧ ⟦2⟧", detection{skip: SkipUntranslatable}},
+
+		// 全大写的单词是按钮文字或标题，需要翻译
+		{"all caps word", "SAVE", detection{}},
+		{"all caps warning", "WARNING", detection{}},
+		{"all caps sentence", "THE FILE IS SAVED TO THE DISK AND THEN", detection{lang: "en"}},
+		{"capitalized word", "Save", detection{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectLanguage(tt.text); got != tt.want {
+				t.Errorf("detectLanguage(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectLatin(t *testing.T) {
+	tests := []struct {
+		words []string
+		want  string
+	}{
+		{[]string{"the", "cat", "and", "the", "dog"}, "en"},
+		{[]string{"het", "huis", "van", "een", "vriend"}, "nl"},
+		{[]string{"xyz"}, ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := detectLatin(tt.words); got != tt.want {
+			t.Errorf("detectLatin(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		target, lang, variant string
+	}{
+		{"en", "en", ""},
+		{"EN-us", "en", ""},
+		{"zh", "zh", ""},
+		{"zh-CN", "zh", "hans"},
+		{"zh_Hans", "zh", "hans"},
+		{"zh-Hant-TW", "zh", "hant"},
+		{"zh-HK", "zh", "hant"},
+		{"简体中文", "zh", "hans"},
+		{"繁體中文", "zh", "hant"},
+		{"English", "en", ""},
+		{" 日语 ", "ja", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		if lang, variant := parseTarget(tt.target); lang != tt.lang || variant != tt.variant {
+			t.Errorf("parseTarget(%q) = %q, %q, want %q, %q", tt.target, lang, variant, tt.lang, tt.variant)
+		}
+	}
+}
+
+func TestCanonicalLang(t *testing.T) {
+	tests := map[string]string{
+		"English": "en",
+		"英语":      "en",
+		" zh_TW ": "zh-tw",
+		"简体中文":    "zh-hans",
+		"pt-BR":   "pt-br",
+	}
+	for lang, want := range tests {
+		if got := CanonicalLang(lang); got != want {
+			t.Errorf("CanonicalLang(%q) = %q, want %q", lang, got, want)
+		}
+	}
+}
+
+func TestDetectionIsTarget(t *testing.T) {
+	tests := []struct {
+		d             detection
+		lang, variant string
+		want          bool
+	}{
+		{detection{lang: "en"}, "en", "", true},
+		{detection{lang: "en"}, "fr", "", false},
+		{detection{}, "en", "", false},
+		{detection{lang: "zh", variant: "hans"}, "zh", "hans", true},
+		{detection{lang: "zh", variant: "hans"}, "zh", "hant", false},
+		{detection{lang: "zh"}, "zh", "hant", true},
+		{detection{lang: "zh", variant: "hant"}, "zh", "", true},
+	}
+	for _, tt := range tests {
+		if got := tt.d.isTarget(tt.lang, tt.variant); got != tt.want {
+			t.Errorf("%+v.isTarget(%q, %q) = %v, want %v", tt.d, tt.lang, tt.variant, got, tt.want)
+		}
+	}
+}
+
+func TestSkipTarget(t *testing.T) {
+	languages := map[string]detection{
+		"en":   {lang: "en"},
+		"hans": {lang: "zh", variant: "hans"},
+		"hant": {lang: "zh", variant: "hant"},
+		"num":  {skip: SkipUntranslatable},
+		"caps": {},
+	}
+
+	skipped := skipTarget(languages, "zh-CN", false)
+	want := map[string]string{"en": "", "hans": SkipTargetLanguage, "hant": "", "num": SkipUntranslatable, "caps": ""}
+	for id, skip := range want {
+		if skipped[id].skip != skip {
+			t.Errorf("skipTarget(zh-CN)[%s].skip = %q, want %q", id, skipped[id].skip, skip)
+		}
+	}
+
+	// translate_all时只标注语言，不跳过任何片段
+	for id, d := range skipTarget(languages, "en", true) {
+		if d.skip != "" || d.lang != languages[id].lang {
+			t.Errorf("skipTarget(en, translateAll)[%s] = %+v", id, d)
+		}
+	}
+}
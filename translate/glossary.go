@@ -0,0 +1,167 @@
+package translate
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// Glossary 术语表存储，由store.GlossaryStore实现
+type Glossary interface {
+	// MatchTerms 获取翻译为各目标语言时适用的术语
+	MatchTerms(glossary, sourceLang string, targetLangs []string) ([]models.GlossaryTerm, error)
+}
+
+// glossaryTerm 编译后的术语，source匹配原文中的源术语，target匹配译文中要求的译法
+type glossaryTerm struct {
+	models.GlossaryTerm
+	source *regexp.Regexp
+	target *regexp.Regexp
+}
+
+// compileGlossary 编译术语的匹配规则，较长的源术语排在前面，避免被其中包含的短术语覆盖
+func compileGlossary(terms []models.GlossaryTerm) []glossaryTerm {
+	compiled := make([]glossaryTerm, 0, len(terms))
+	for _, term := range terms {
+		if strings.TrimSpace(term.SourceTerm) == "" || strings.TrimSpace(term.TargetTerm) == "" {
+			continue
+		}
+		compiled = append(compiled, glossaryTerm{
+			GlossaryTerm: term,
+			source:       termPattern(term.SourceTerm, term.CaseSensitive),
+			target:       termPattern(term.TargetTerm, term.CaseSensitive),
+		})
+	}
+	for i := 1; i < len(compiled); i++ {
+		for j := i; j > 0 && utf8.RuneCountInString(compiled[j].SourceTerm) > utf8.RuneCountInString(compiled[j-1].SourceTerm); j-- {
+			compiled[j], compiled[j-1] = compiled[j-1], compiled[j]
+		}
+	}
+	return compiled
+}
+
+// termPattern 构造术语的匹配规则：术语中的空白匹配任意空白，以字母或数字开头、结尾的术语要求在单词边界上，
+// 避免"go"匹配到"good"；中日韩文字没有单词边界，直接按子串匹配
+func termPattern(term string, caseSensitive bool) *regexp.Regexp {
+	term = strings.TrimSpace(term)
+	parts := strings.Fields(term)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	pattern := strings.Join(parts, `\s+`)
+
+	first, _ := utf8.DecodeRuneInString(term)
+	last, _ := utf8.DecodeLastRuneInString(term)
+	if isWordRune(first) {
+		pattern = `(?:^|[^\p{L}\p{N}_])` + pattern
+	}
+	if isWordRune(last) {
+		pattern += `(?:$|[^\p{L}\p{N}_])`
+	}
+	if !caseSensitive {
+		pattern = `(?i)` + pattern
+	}
+	return regexp.MustCompile(pattern)
+}
+
+// isWordRune 判断字符是否属于有单词边界的文字
+func isWordRune(r rune) bool {
+	if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai) {
+		return false
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// matchTerms 返回原文中出现的术语。已被更长术语覆盖的位置不再匹配其中的短术语
+func matchTerms(terms []glossaryTerm, text string) []glossaryTerm {
+	var matched []glossaryTerm
+	var covered [][]int
+	for _, term := range terms {
+		found := false
+		for _, loc := range term.source.FindAllStringIndex(text, -1) {
+			inside := false
+			for _, c := range covered {
+				if loc[0] >= c[0] && loc[1] <= c[1] {
+					inside = true
+					break
+				}
+			}
+			if !inside {
+				covered = append(covered, loc)
+				found = true
+			}
+		}
+		if found {
+			matched = append(matched, term)
+		}
+	}
+	return matched
+}
+
+// missingTerms 检查原文中出现的术语是否都按要求的译法出现在译文中，返回缺失译法的源术语
+func missingTerms(terms []glossaryTerm, source, translated string) []string {
+	var missing []string
+	for _, term := range matchTerms(terms, source) {
+		if !term.target.MatchString(translated) {
+			missing = append(missing, term.SourceTerm)
+		}
+	}
+	return missing
+}
+
+// loadGlossaries 一次加载请求的术语表中适用于各目标语言的术语，返回小写的目标语言到术语，未指定术语表时返回空。
+// 术语按CanonicalLang统一后的语言代码保存和查询，"English"和"en"使用同一组术语
+func (t *Translator) loadGlossaries(req models.TranslateRequest, targets []string) (map[string][]glossaryTerm, error) {
+	if req.Glossary == "" || t.Glossary == nil {
+		return nil, nil
+	}
+	langs := make([]string, 0, len(targets))
+	for _, target := range targets {
+		langs = append(langs, CanonicalLang(target))
+	}
+	terms, err := t.Glossary.MatchTerms(req.Glossary, CanonicalLang(req.Source), langs)
+	if err != nil {
+		return nil, err
+	}
+
+	grouped := make(map[string][]models.GlossaryTerm)
+	for _, term := range terms {
+		grouped[term.TargetLang] = append(grouped[term.TargetLang], term)
+	}
+	compiled := make(map[string][]glossaryTerm, len(targets))
+	for i, target := range targets {
+		if terms, ok := grouped[langs[i]]; ok {
+			compiled[strings.ToLower(target)] = compileGlossary(terms)
+		}
+	}
+	return compiled, nil
+}
+
+// glossaryMisses 按格式保护原文和译文后检查术语，标签和占位符中的内容不参与检查
+func glossaryMisses(format string, terms []glossaryTerm, source, translated string) []string {
+	if len(terms) == 0 {
+		return nil
+	}
+	return missingTerms(terms, maskSegment(format, source).text, maskSegment(format, translated).text)
+}
+
+// batchTerms 返回一组片段中出现的术语，每条术语只出现一次
+func batchTerms(terms []glossaryTerm, segments []models.TranslateSegment) []glossaryTerm {
+	if len(terms) == 0 {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var matched []glossaryTerm
+	for _, segment := range segments {
+		for _, term := range matchTerms(terms, segment.Text) {
+			if !seen[term.ID] {
+				seen[term.ID] = true
+				matched = append(matched, term)
+			}
+		}
+	}
+	return matched
+}
@@ -0,0 +1,263 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultJobWorkers 默认同时处理的翻译任务数
+	defaultJobWorkers = 2
+	// jobChunkSegments 翻译任务每次取出并保存进度的片段数
+	jobChunkSegments = 100
+	// jobPollInterval 没有排队的任务时检查数据库的间隔
+	jobPollInterval = 5 * time.Second
+	// jobLease 领取任务后的租约时长，实例退出后任务在租约过期时被其他实例接管
+	jobLease = 2 * time.Minute
+	// jobHeartbeatInterval 处理任务期间延长租约的间隔
+	jobHeartbeatInterval = 30 * time.Second
+)
+
+// JobStore 异步翻译任务的持久化存储。worker为领取任务的实例，只有持有任务的实例可以保存进度和更新状态
+type JobStore interface {
+	// GetJob 获取翻译任务及其进度
+	GetJob(id string) (*models.TranslationJob, error)
+	// ClaimJob 为worker领取最早的排队任务或租约已过期的处理中任务，没有可领取的任务时返回空字符串
+	ClaimJob(worker string, lease time.Duration) (string, error)
+	// RenewJob 延长任务的租约，任务已被取消、已结束或被其他实例接管时返回false
+	RenewJob(id, worker string, lease time.Duration) (bool, error)
+	// PendingJobSegments 按提交的顺序获取最多limit个尚未处理的片段
+	PendingJobSegments(id string, limit int) ([]models.TranslateSegment, error)
+	// SaveJobSegments 保存一批片段的处理结果，任务已不属于worker时不保存并返回false
+	SaveJobSegments(id, worker string, segments []models.TranslateSegment, provider, model string, errors []string) (bool, error)
+	// FinishJob 将处理中的任务更新为指定状态，任务已被取消或被其他实例接管时返回false
+	FinishJob(id, worker, status, errMsg string) (bool, error)
+}
+
+// JobQueue 异步翻译任务队列。任务和进度保存在数据库中，后台工作协程每次取出一部分未处理的片段翻译并保存结果。
+// 多个服务实例可以共用同一个数据库：领取任务时记录实例ID和租约，处理期间定期延长租约，
+// 实例退出或重启后，租约过期的任务由任意实例接管，从未处理的片段继续翻译
+type JobQueue struct {
+	Translator *Translator
+	Store      JobStore
+	Workers    int    // 同时处理的任务数
+	WorkerID   string // 当前实例的ID，记录在领取的任务中
+
+	wake    chan struct{}
+	stop    chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	running map[string]context.CancelFunc // 正在处理的任务ID及其取消函数
+}
+
+// NewJobQueue 创建新的翻译任务队列，同时处理的任务数可以通过环境变量TRANSLATE_JOB_WORKERS调整，
+// 实例ID默认为主机名加随机后缀，可以通过环境变量TRANSLATE_WORKER_ID指定
+func NewJobQueue(translator *Translator, store JobStore) *JobQueue {
+	return &JobQueue{
+		Translator: translator,
+		Store:      store,
+		Workers:    envInt("TRANSLATE_JOB_WORKERS", defaultJobWorkers),
+		WorkerID:   workerID(),
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		running:    make(map[string]context.CancelFunc),
+	}
+}
+
+// workerID 生成当前实例的ID
+func workerID() string {
+	if id := strings.TrimSpace(os.Getenv("TRANSLATE_WORKER_ID")); id != "" {
+		return id
+	}
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "worker"
+	}
+	return host + "-" + uuid.New().String()[:8]
+}
+
+// Start 启动工作协程，其他实例或上次运行遗留的处理中任务在租约过期后继续翻译
+func (q *JobQueue) Start() {
+	log.Printf("翻译任务队列已启动，实例ID: %s", q.WorkerID)
+	for i := 0; i < q.Workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+}
+
+// Notify 通知工作协程有新的任务
+func (q *JobQueue) Notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Cancel 停止正在处理的任务，任务状态由调用方在数据库中更新
+func (q *JobQueue) Cancel(id string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if cancel, ok := q.running[id]; ok {
+		cancel()
+	}
+}
+
+// Close 停止工作协程，正在处理的任务重新排队，由任意实例继续翻译
+func (q *JobQueue) Close() {
+	close(q.stop)
+	q.mu.Lock()
+	for _, cancel := range q.running {
+		cancel()
+	}
+	q.mu.Unlock()
+	q.wg.Wait()
+}
+
+// work 工作协程，循环取出排队的任务处理，没有任务时等待通知或定时检查
+func (q *JobQueue) work() {
+	defer q.wg.Done()
+	for {
+		select {
+		case <-q.stop:
+			return
+		default:
+		}
+
+		id, err := q.Store.ClaimJob(q.WorkerID, jobLease)
+		if err != nil {
+			log.Printf("获取翻译任务失败: %v", err)
+		}
+		if id != "" {
+			q.run(id)
+			continue
+		}
+
+		select {
+		case <-q.stop:
+			return
+		case <-q.wake:
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// run 分多次翻译任务中未处理的片段，每次完成后保存结果。任务被取消、被其他实例接管或服务停止时丢弃正在翻译的片段
+func (q *JobQueue) run(id string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.mu.Lock()
+	q.running[id] = cancel
+	q.mu.Unlock()
+	defer func() {
+		q.mu.Lock()
+		delete(q.running, id)
+		q.mu.Unlock()
+		cancel()
+	}()
+	go q.heartbeat(ctx, id, cancel)
+
+	job, err := q.Store.GetJob(id)
+	if err != nil {
+		q.finish(id, models.JobQueued, fmt.Sprintf("获取翻译任务失败: %v", err))
+		return
+	}
+	provider, model, err := q.Translator.LLM.ParseModel(job.Request.Model)
+	if err != nil {
+		q.finish(id, models.JobFailed, err.Error())
+		return
+	}
+
+	for ctx.Err() == nil {
+		// 其他请求可能已经取消了任务，或租约过期后被其他实例接管
+		owned, err := q.Store.RenewJob(id, q.WorkerID, jobLease)
+		if err != nil {
+			q.finish(id, models.JobQueued, fmt.Sprintf("延长任务租约失败: %v", err))
+			return
+		}
+		if !owned {
+			return
+		}
+
+		segments, err := q.Store.PendingJobSegments(id, jobChunkSegments)
+		if err != nil {
+			q.finish(id, models.JobQueued, fmt.Sprintf("获取待翻译片段失败: %v", err))
+			return
+		}
+		if len(segments) == 0 {
+			q.finish(id, models.JobCompleted, "")
+			return
+		}
+
+		req := job.Request
+		req.Segments = segments
+		response := q.Translator.TranslateEach(ctx, provider, model, req, nil)
+		if ctx.Err() != nil {
+			break
+		}
+		owned, err = q.Store.SaveJobSegments(id, q.WorkerID, response.Segments, response.Provider, response.Model, response.Errors)
+		if err != nil {
+			q.finish(id, models.JobQueued, fmt.Sprintf("保存翻译结果失败: %v", err))
+			return
+		}
+		if !owned {
+			log.Printf("翻译任务%s已被取消或由其他实例接管，丢弃本批结果", id)
+			return
+		}
+	}
+
+	// 服务停止时立即重新排队，不必等待租约过期。任务已被取消或接管时不会更新
+	select {
+	case <-q.stop:
+		if _, err := q.Store.FinishJob(id, q.WorkerID, models.JobQueued, ""); err != nil {
+			log.Printf("翻译任务%s重新排队失败: %v", id, err)
+		}
+	default:
+	}
+}
+
+// heartbeat 处理任务期间定期延长租约，任务已被取消或被其他实例接管时停止翻译
+func (q *JobQueue) heartbeat(ctx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(jobHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		owned, err := q.Store.RenewJob(id, q.WorkerID, jobLease)
+		if err != nil {
+			log.Printf("延长翻译任务%s的租约失败: %v", id, err)
+			continue
+		}
+		if !owned {
+			cancel()
+			return
+		}
+	}
+}
+
+// finish 更新任务的最终状态。数据库操作失败时任务重新排队，稍后继续翻译
+func (q *JobQueue) finish(id, status, reason string) {
+	errMsg := reason
+	if status == models.JobQueued {
+		log.Printf("翻译任务%s: %s，稍后重试", id, reason)
+		errMsg = ""
+		// 避免数据库持续不可用时反复取出同一个任务
+		select {
+		case <-q.stop:
+		case <-time.After(jobPollInterval):
+		}
+	}
+	if _, err := q.Store.FinishJob(id, q.WorkerID, status, errMsg); err != nil {
+		log.Printf("更新翻译任务%s的状态失败: %v", id, err)
+	}
+}
@@ -0,0 +1,168 @@
+package translate
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 片段的文本格式
+const (
+	FormatText     = "text"     // 纯文本，只保护占位符
+	FormatHTML     = "html"     // HTML片段，保护标签、实体、代码元素和占位符
+	FormatMarkdown = "markdown" // Markdown，保护代码、链接地址、内嵌HTML和占位符
+)
+
+// ValidFormat 判断是否为支持的文本格式，为空表示纯文本
+func ValidFormat(format string) bool {
+	switch format {
+	case "", FormatText, FormatHTML, FormatMarkdown:
+		return true
+	}
+	return false
+}
+
+var (
+	// markerPattern 发送给大模型的保护标记，允许模型在标记内加入空格
+	markerPattern = regexp.MustCompile(`⟦\s*(\d+)\s*⟧`)
+
+	// placeholderPattern 格式化占位符：{0}、{name}、{{name}}、${name}、%s、%1$d
+	placeholderPattern = regexp.MustCompile(`\{\{[^{}]*\}\}|\$\{[^{}]*\}|\{[A-Za-z0-9_.:-]*\}|%(?:\d+\$)?[-+#0]?\d*(?:\.\d+)?[sdfx]`)
+
+	// htmlCodePattern 内容不需要翻译的HTML元素，整体保护
+	htmlCodePattern = regexp.MustCompile(`(?is)<code\b[^>]*>.*?</code\s*>|<pre\b[^>]*>.*?</pre\s*>|<kbd\b[^>]*>.*?</kbd\s*>|<script\b[^>]*>.*?</script\s*>|<style\b[^>]*>.*?</style\s*>`)
+	// htmlTagPattern HTML标签和注释
+	htmlTagPattern = regexp.MustCompile(`(?s)<!--.*?-->|</?[A-Za-z][A-Za-z0-9-]*(?:\s[^<>]*)?/?>`)
+	// htmlEntityPattern HTML实体
+	htmlEntityPattern = regexp.MustCompile(`&(?:[A-Za-z][A-Za-z0-9]*|#\d+|#[xX][0-9A-Fa-f]+);`)
+
+	// markdownCodePattern Markdown代码块和行内代码
+	markdownCodePattern = regexp.MustCompile("(?s)```.*?```|~~~.*?~~~|`[^`\n]+`")
+	// markdownLinkPattern 链接和图片的地址部分，链接文字仍然翻译
+	markdownLinkPattern = regexp.MustCompile(`\]\([^()\s]*(?:\s+"[^"]*")?\)|\]\[[^\]]*\]`)
+	// markdownURLPattern 自动链接和裸URL
+	markdownURLPattern = regexp.MustCompile(`<https?://[^>\s]+>|https?://[^\s)<>\]]*[^\s)<>\].,;:!?'"]`)
+
+	// tagNamePattern 从标签中取出标签名
+	tagNamePattern = regexp.MustCompile(`^<(/?)([A-Za-z][A-Za-z0-9-]*)`)
+)
+
+// voidElements 没有结束标签的HTML元素
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "source": true, "track": true, "wbr": true,
+}
+
+// maskedText 替换为保护标记后的文本，tokens[i]为标记⟦i+1⟧对应的原文
+type maskedText struct {
+	text   string
+	tokens []string
+}
+
+// maskSegment 按格式将标签、代码和占位符替换为⟦n⟧标记，避免大模型翻译或修改
+func maskSegment(format, text string) maskedText {
+	m := maskedText{text: text}
+	switch format {
+	case FormatHTML:
+		m.replace(htmlCodePattern)
+		m.replace(htmlTagPattern)
+		m.replace(htmlEntityPattern)
+	case FormatMarkdown:
+		m.replace(markdownCodePattern)
+		m.replace(markdownLinkPattern)
+		m.replace(markdownURLPattern)
+		m.replace(htmlTagPattern)
+	}
+	m.replace(placeholderPattern)
+	m.renumber()
+	return m
+}
+
+// renumber 按标记在文本中出现的顺序重新编号，方便大模型理解
+func (m *maskedText) renumber() {
+	tokens := make([]string, 0, len(m.tokens))
+	m.text = markerPattern.ReplaceAllStringFunc(m.text, func(marker string) string {
+		n, _ := strconv.Atoi(markerPattern.FindStringSubmatch(marker)[1])
+		tokens = append(tokens, m.tokens[n-1])
+		return fmt.Sprintf("⟦%d⟧", len(tokens))
+	})
+	m.tokens = tokens
+}
+
+// replace 将匹配的内容替换为新的标记
+func (m *maskedText) replace(pattern *regexp.Regexp) {
+	m.text = pattern.ReplaceAllStringFunc(m.text, func(match string) string {
+		m.tokens = append(m.tokens, match)
+		return fmt.Sprintf("⟦%d⟧", len(m.tokens))
+	})
+}
+
+// unmask 将译文中的标记还原为原文，并校验标记与原文一致：每个标记恰好出现一次，HTML标签的嵌套结构正确
+func (m maskedText) unmask(translated string) (string, error) {
+	if len(m.tokens) == 0 {
+		return translated, nil
+	}
+
+	order := m.order(translated)
+	seen := make([]int, len(m.tokens))
+	for _, i := range order {
+		if i < 0 || i >= len(m.tokens) {
+			return "", fmt.Errorf("译文中出现了原文没有的标记⟦%d⟧", i+1)
+		}
+		seen[i]++
+	}
+	for i, count := range seen {
+		switch {
+		case count == 0:
+			return "", fmt.Errorf("译文丢失了标签或占位符: %s", m.tokens[i])
+		case count > 1:
+			return "", fmt.Errorf("译文重复了标签或占位符: %s", m.tokens[i])
+		}
+	}
+
+	// 标签可以随语序移动，但开闭标签必须正确嵌套。原文本身不完整（例如只有结束标签）时不校验嵌套
+	if m.wellNested(m.order(m.text)) && !m.wellNested(order) {
+		return "", fmt.Errorf("译文的标签结构与原文不一致")
+	}
+
+	return markerPattern.ReplaceAllStringFunc(translated, func(marker string) string {
+		n, _ := strconv.Atoi(markerPattern.FindStringSubmatch(marker)[1])
+		return m.tokens[n-1]
+	}), nil
+}
+
+// order 按出现顺序返回文本中标记的序号（从0开始）
+func (m maskedText) order(text string) []int {
+	var order []int
+	for _, match := range markerPattern.FindAllStringSubmatch(text, -1) {
+		n, _ := strconv.Atoi(match[1])
+		order = append(order, n-1)
+	}
+	return order
+}
+
+// wellNested 判断按order顺序出现的HTML标签是否正确嵌套，自闭合标签、空元素和整体保护的元素不参与判断
+func (m maskedText) wellNested(order []int) bool {
+	var stack []string
+	for _, i := range order {
+		token := m.tokens[i]
+		match := tagNamePattern.FindStringSubmatch(token)
+		if match == nil || strings.HasSuffix(token, "/>") || strings.Contains(token[1:], "<") {
+			continue
+		}
+		name := strings.ToLower(match[2])
+		if voidElements[name] {
+			continue
+		}
+		if match[1] == "" {
+			stack = append(stack, name)
+			continue
+		}
+		if len(stack) == 0 || stack[len(stack)-1] != name {
+			return false
+		}
+		stack = stack[:len(stack)-1]
+	}
+	return len(stack) == 0
+}
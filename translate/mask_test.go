@@ -0,0 +1,120 @@
+package translate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaskSegment(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     string
+		text       string
+		wantText   string
+		wantTokens []string
+	}{
+		{
+			"html", FormatHTML,
+			`Click <a href="/x">here</a> &amp; <code>go run</code> for {name}<br>.`,
+			"Click ⟦1⟧here⟦2⟧ ⟦3⟧ ⟦4⟧ for ⟦5⟧⟦6⟧.",
+			[]string{`<a href="/x">`, "</a>", "&amp;", "<code>go run</code>", "{name}", "<br>"},
+		},
+		{
+			"markdown", FormatMarkdown,
+			"Run `go test` and see [docs](https://example.com \"t\") or https://example.com/a. <b>bold</b> %s",
+			"Run ⟦1⟧ and see [docs⟦2⟧ or ⟦3⟧. ⟦4⟧bold⟦5⟧ ⟦6⟧",
+			[]string{"`go test`", `](https://example.com "t")`, "https://example.com/a", "<b>", "</b>", "%s"},
+		},
+		{
+			"markdown code block", FormatMarkdown,
+			"See:\n```\nx := <b>\n```\nDone",
+			"See:\n⟦1⟧\nDone",
+			[]string{"```\nx := <b>\n```"},
+		},
+		{
+			"placeholders", FormatText,
+			"Hello {0}, you have %d messages and ${count} {{total}} %1$s.",
+			"Hello ⟦1⟧, you have ⟦2⟧ messages and ⟦3⟧ ⟦4⟧ ⟦5⟧.",
+			[]string{"{0}", "%d", "${count}", "{{total}}", "%1$s"},
+		},
+		{
+			"text keeps markup", FormatText,
+			"<b>bold</b> &amp; `code`",
+			"<b>bold</b> &amp; `code`",
+			[]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := maskSegment(tt.format, tt.text)
+			if m.text != tt.wantText || !reflect.DeepEqual(m.tokens, tt.wantTokens) {
+				t.Errorf("maskSegment = %q %q, want %q %q", m.text, m.tokens, tt.wantText, tt.wantTokens)
+			}
+			// 译文原样返回标记时还原为原文
+			if got, err := m.unmask(m.text); err != nil || got != tt.text {
+				t.Errorf("unmask(masked) = %q, %v, want the original", got, err)
+			}
+		})
+	}
+}
+
+func TestUnmask(t *testing.T) {
+	html := maskSegment(FormatHTML, `Click <a href="/x">here</a> to <b>save</b> {name}.`)
+	// ⟦1⟧=<a>, ⟦2⟧=</a>, ⟦3⟧=<b>, ⟦4⟧=</b>, ⟦5⟧={name}
+	tests := []struct {
+		name       string
+		masked     maskedText
+		translated string
+		want       string
+		wantErr    string
+	}{
+		{"reordered", html, "⟦5⟧: ⟦3⟧保存⟦4⟧请点击⟦1⟧这里⟦2⟧。", `{name}: <b>保存</b>请点击<a href="/x">这里</a>。`, ""},
+		{"spaces inside markers", html, "点击⟦ 1 ⟧这里⟦2⟧⟦3⟧保存⟦4⟧⟦5 ⟧。", `点击<a href="/x">这里</a><b>保存</b>{name}。`, ""},
+		{"nested", html, "⟦1⟧点击⟦3⟧这里⟦4⟧⟦2⟧⟦5⟧", `<a href="/x">点击<b>这里</b></a>{name}`, ""},
+		{"dropped marker", html, "点击⟦1⟧这里⟦2⟧保存⟦4⟧⟦5⟧", "", "丢失了标签或占位符: <b>"},
+		{"duplicated marker", html, "点击⟦1⟧这里⟦2⟧⟦3⟧保存⟦4⟧⟦5⟧⟦5⟧", "", "重复了标签或占位符: {name}"},
+		{"invented marker", html, "点击⟦1⟧这里⟦2⟧⟦3⟧保存⟦4⟧⟦5⟧⟦6⟧", "", "原文没有的标记⟦6⟧"},
+		{"marker zero", html, "⟦0⟧点击⟦1⟧这里⟦2⟧⟦3⟧保存⟦4⟧⟦5⟧", "", "原文没有的标记⟦0⟧"},
+		{"misnested", html, "⟦1⟧点击⟦3⟧这里⟦2⟧保存⟦4⟧⟦5⟧", "", "标签结构与原文不一致"},
+		{"closed before opened", html, "⟦2⟧点击⟦1⟧⟦3⟧保存⟦4⟧⟦5⟧", "", "标签结构与原文不一致"},
+		{"no markers", maskSegment(FormatText, "plain"), "纯文本", "纯文本", ""},
+		{"void and self-closing", maskSegment(FormatHTML, "a<br>b<img src=x/>c"), "⟦2⟧甲⟦1⟧乙", "<img src=x/>甲<br>乙", ""},
+		{"unbalanced original", maskSegment(FormatHTML, "</b>only close <i>open"), "⟦2⟧开⟦1⟧关", "<i>开</b>关", ""},
+		{"unbalanced original still counts markers", maskSegment(FormatHTML, "</b>only close <i>open"), "⟦2⟧开", "", "丢失了标签或占位符: </b>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.masked.unmask(tt.translated)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("unmask error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("unmask = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestWellNested(t *testing.T) {
+	m := maskedText{tokens: []string{"<p>", "</p>", "<B>", "</b>", "<br>", "<code>x</code>", "<!-- c -->", "{0}"}}
+	tests := []struct {
+		order []int
+		want  bool
+	}{
+		{nil, true},
+		{[]int{0, 2, 3, 1}, true},
+		{[]int{0, 2, 1, 3}, false},
+		{[]int{0, 4, 5, 6, 7, 1}, true},
+		{[]int{0}, false},
+		{[]int{1, 0}, false},
+	}
+	for _, tt := range tests {
+		if got := m.wellNested(tt.order); got != tt.want {
+			t.Errorf("wellNested(%v) = %v, want %v", tt.order, got, tt.want)
+		}
+	}
+}
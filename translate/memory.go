@@ -0,0 +1,286 @@
+package translate
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+const (
+	// defaultMemoryCacheSize 进程内翻译记忆缓存的默认条目数
+	defaultMemoryCacheSize = 10000
+	// defaultMemoryCacheTTL 进程内缓存条目的默认有效期（秒）。多个实例共用数据库时，
+	// 在一个实例上删除的条目最迟在有效期过后从其他实例的缓存中失效
+	defaultMemoryCacheTTL = 300
+)
+
+// Memory 翻译记忆的持久化存储，由store.TranslationMemoryStore实现
+type Memory interface {
+	// LookupTranslations 按键批量查询译文，命中的条目增加命中次数
+	LookupTranslations(keys []string) (map[string]string, error)
+	// TouchTranslations 为进程内缓存命中的条目增加命中次数
+	TouchTranslations(keys []string) error
+	// SaveTranslations 保存译文
+	SaveTranslations(entries []models.TranslationMemoryEntry) error
+}
+
+// memoryCacheSize 读取进程内缓存的条目数，TRANSLATE_MEMORY_CACHE_SIZE为0时不使用进程内缓存
+func memoryCacheSize() int {
+	if os.Getenv("TRANSLATE_MEMORY_CACHE_SIZE") == "0" {
+		return 0
+	}
+	return envInt("TRANSLATE_MEMORY_CACHE_SIZE", defaultMemoryCacheSize)
+}
+
+// memoryCacheTTL 读取进程内缓存条目的有效期，通过TRANSLATE_MEMORY_CACHE_TTL按秒设置
+func memoryCacheTTL() time.Duration {
+	return time.Duration(envInt("TRANSLATE_MEMORY_CACHE_TTL", defaultMemoryCacheTTL)) * time.Second
+}
+
+// MemoryKey 计算翻译记忆的键：规范化后的原文、请求中指定的源语言、目标语言、"服务商/模型"、风格和格式的SHA-256。
+// 同一段原文在不同源语言下可能含义不同，未指定源语言时单独缓存
+func MemoryKey(text, source, target, model, style, format string) string {
+	hash := sha256.New()
+	for _, part := range []string{normalizeSource(text, format), strings.ToLower(strings.TrimSpace(source)), strings.ToLower(strings.TrimSpace(target)), model, style, format} {
+		hash.Write([]byte(part))
+		hash.Write([]byte{0})
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// normalizeSource 规范化原文：去掉首尾空白并合并连续空白。Markdown中的换行有含义，只去掉首尾空白
+func normalizeSource(text, format string) string {
+	if format == FormatMarkdown {
+		return strings.TrimSpace(text)
+	}
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// memoryStyle 将请求的翻译选项和术语表序列化为翻译记忆的风格，不同风格的译文分别缓存
+func memoryStyle(req models.TranslateRequest) string {
+	var style string
+	if options := req.EffectiveOptions(); options != (models.TranslateOptions{}) {
+		if data, err := json.Marshal(options); err == nil {
+			style = string(data)
+		}
+	}
+	if req.Glossary != "" {
+		style += " glossary=" + req.Glossary
+	}
+	return strings.TrimSpace(style)
+}
+
+// memoryEnabled 判断本次请求是否使用翻译记忆
+func (t *Translator) memoryEnabled(req models.TranslateRequest) bool {
+	return !req.SkipMemory && (t.Memory != nil || t.cache != nil)
+}
+
+// recall 查询各目标语言的翻译记忆，先查进程内缓存，未命中的合并为一次数据库查询。candidates为目标语言到要查询的片段，
+// 返回每个目标语言下片段ID对应的键和命中的译文，数据库查询失败时按未命中处理
+func (t *Translator) recall(provider llm.ModelProvider, model string, req models.TranslateRequest, candidates map[string][]models.TranslateSegment) (map[string]map[string]string, map[string]map[string]string) {
+	style := memoryStyle(req)
+	modelName := string(provider) + "/" + model
+
+	keys := make(map[string]map[string]string, len(candidates))
+	hits := make(map[string]map[string]string, len(candidates))
+	var touched, missing []string
+	for target, segments := range candidates {
+		keys[target] = make(map[string]string, len(segments))
+		hits[target] = make(map[string]string)
+		for _, segment := range segments {
+			key := MemoryKey(segment.Text, req.Source, target, modelName, style, req.Format)
+			keys[target][segment.ID] = key
+			if text, ok := t.cache.get(key); ok {
+				hits[target][segment.ID] = text
+				touched = append(touched, key)
+				continue
+			}
+			missing = append(missing, key)
+		}
+	}
+
+	if t.Memory != nil && len(missing) > 0 {
+		stored, err := t.Memory.LookupTranslations(missing)
+		if err != nil {
+			log.Printf("查询翻译记忆失败: %v", err)
+		}
+		for target, segments := range candidates {
+			for _, segment := range segments {
+				key := keys[target][segment.ID]
+				if text, ok := stored[key]; ok {
+					hits[target][segment.ID] = text
+					t.cache.add(key, text)
+				}
+			}
+		}
+	}
+
+	// 进程内缓存命中的条目异步更新命中次数，失败不影响翻译
+	if t.Memory != nil && len(touched) > 0 {
+		go func() {
+			if err := t.Memory.TouchTranslations(touched); err != nil {
+				log.Printf("更新翻译记忆命中次数失败: %v", err)
+			}
+		}()
+	}
+	return keys, hits
+}
+
+// remember 将新翻译的片段写入进程内缓存，并异步保存到数据库。
+// segments只能包含由请求的模型翻译的片段，调用方需排除故障切换到备用模型的译文
+func (t *Translator) remember(req models.TranslateRequest, keys map[string]string, provider llm.ModelProvider, model string, segments []models.TranslateSegment) {
+	if len(segments) == 0 {
+		return
+	}
+
+	sources := make(map[string]string, len(req.Segments))
+	for _, segment := range req.Segments {
+		sources[segment.ID] = segment.Text
+	}
+
+	style := memoryStyle(req)
+	entries := make([]models.TranslationMemoryEntry, 0, len(segments))
+	for _, segment := range segments {
+		key := keys[segment.ID]
+		t.cache.add(key, segment.Text)
+		entries = append(entries, models.TranslationMemoryEntry{
+			Key:         key,
+			SourceText:  sources[segment.ID],
+			Target:      strings.ToLower(strings.TrimSpace(req.Target)),
+			Model:       string(provider) + "/" + model,
+			Style:       style,
+			Format:      req.Format,
+			Translation: segment.Text,
+		})
+	}
+
+	if t.Memory == nil {
+		return
+	}
+	go func() {
+		if err := t.Memory.SaveTranslations(entries); err != nil {
+			log.Printf("保存翻译记忆失败: %v", err)
+		}
+	}()
+}
+
+// ForgetTranslation 从进程内缓存中移除指定键的译文，删除数据库中的条目后调用
+func (t *Translator) ForgetTranslation(key string) {
+	t.cache.remove(key)
+}
+
+// ClearMemoryCache 清空进程内缓存，按条件清理数据库中的条目后调用
+func (t *Translator) ClearMemoryCache() {
+	t.cache.purge()
+}
+
+// lruCache 进程内的翻译记忆缓存，超过容量时淘汰最久未使用的条目，超过有效期的条目按未命中处理。nil表示不使用缓存
+type lruCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	now   func() time.Time
+	items map[string]*list.Element
+	order *list.List
+}
+
+// lruEntry 缓存条目
+type lruEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// newLRUCache 创建容量为size、条目有效期为ttl的缓存，size不大于0时返回nil
+func newLRUCache(size int, ttl time.Duration) *lruCache {
+	if size <= 0 {
+		return nil
+	}
+	return &lruCache{
+		size:  size,
+		ttl:   ttl,
+		now:   time.Now,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+	}
+}
+
+// get 获取缓存的译文
+func (c *lruCache) get(key string) (string, bool) {
+	if c == nil {
+		return "", false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	element, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+	entry := element.Value.(*lruEntry)
+	if !c.now().Before(entry.expires) {
+		c.order.Remove(element)
+		delete(c.items, key)
+		return "", false
+	}
+	c.order.MoveToFront(element)
+	return entry.value, true
+}
+
+// add 缓存译文，超过容量时淘汰最久未使用的条目
+func (c *lruCache) add(key, value string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if element, ok := c.items[key]; ok {
+		entry := element.Value.(*lruEntry)
+		entry.value, entry.expires = value, expires
+		c.order.MoveToFront(element)
+		return
+	}
+	c.items[key] = c.order.PushFront(&lruEntry{key: key, value: value, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*lruEntry).key)
+	}
+}
+
+// remove 移除缓存的译文
+func (c *lruCache) remove(key string) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if element, ok := c.items[key]; ok {
+		c.order.Remove(element)
+		delete(c.items, key)
+	}
+}
+
+// purge 清空缓存
+func (c *lruCache) purge() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.items = make(map[string]*list.Element)
+	c.order.Init()
+}
@@ -0,0 +1,97 @@
+package translate
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+func TestLRUCache(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newLRUCache(2, time.Minute)
+	cache.now = func() time.Time { return now }
+
+	cache.add("a", "1")
+	cache.add("b", "2")
+	cache.get("a")
+	cache.add("c", "3") // 淘汰最久未使用的b
+	if _, ok := cache.get("b"); ok {
+		t.Error("b should have been evicted")
+	}
+	if v, ok := cache.get("a"); !ok || v != "1" {
+		t.Errorf("get(a) = %q, %v", v, ok)
+	}
+
+	// 超过有效期的条目按未命中处理，重新写入后刷新有效期
+	now = now.Add(59 * time.Second)
+	cache.add("c", "3'")
+	now = now.Add(2 * time.Second)
+	if _, ok := cache.get("a"); ok {
+		t.Error("a should have expired")
+	}
+	if v, ok := cache.get("c"); !ok || v != "3'" {
+		t.Errorf("get(c) = %q, %v, want the refreshed entry", v, ok)
+	}
+
+	cache.remove("c")
+	if _, ok := cache.get("c"); ok {
+		t.Error("c should have been removed")
+	}
+	cache.add("d", "4")
+	cache.purge()
+	if _, ok := cache.get("d"); ok {
+		t.Error("purge should clear the cache")
+	}
+
+	// nil表示不使用缓存
+	var disabled *lruCache
+	disabled.add("a", "1")
+	if _, ok := disabled.get("a"); ok || newLRUCache(0, time.Minute) != nil {
+		t.Error("a cache of size 0 should be disabled")
+	}
+}
+
+func TestMemoryKey(t *testing.T) {
+	base := MemoryKey("Hello  world", "", "zh", "qwen/qwen-plus", "", FormatText)
+	if MemoryKey(" Hello world ", "", "ZH", "qwen/qwen-plus", "", FormatText) != base {
+		t.Error("whitespace and target case should not change the key")
+	}
+	for name, key := range map[string]string{
+		"source":   MemoryKey("Hello world", "en", "zh", "qwen/qwen-plus", "", FormatText),
+		"target":   MemoryKey("Hello world", "", "ja", "qwen/qwen-plus", "", FormatText),
+		"model":    MemoryKey("Hello world", "", "zh", "deepseek/deepseek-chat", "", FormatText),
+		"style":    MemoryKey("Hello world", "", "zh", "qwen/qwen-plus", `{"style":"formal"}`, FormatText),
+		"format":   MemoryKey("Hello world", "", "zh", "qwen/qwen-plus", "", FormatHTML),
+		"markdown": MemoryKey("Hello\nworld", "", "zh", "qwen/qwen-plus", "", FormatMarkdown),
+	} {
+		if key == base {
+			t.Errorf("changing the %s should change the key", name)
+		}
+	}
+}
+
+func TestFailoverTranslationsNotRemembered(t *testing.T) {
+	translator, _ := newFailoverTranslator(t)
+
+	req := models.TranslateRequest{
+		Target:   "zh",
+		Segments: []models.TranslateSegment{{ID: "a", Text: "Good morning to all of you"}, {ID: "b", Text: "The weather is nice today"}},
+	}
+	response, err := translator.Translate(context.Background(), "standin", "standin-chat", req)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if len(response.Failed) != 0 || response.Segments[1].Text != "备用译文" {
+		t.Fatalf("Translate = %+v, want b translated by the backup provider", response)
+	}
+
+	style := memoryStyle(req)
+	if _, ok := translator.cache.get(MemoryKey("Good morning to all of you", "", "zh", "standin/standin-chat", style, "")); !ok {
+		t.Error("a was translated by the requested model and should be remembered")
+	}
+	if _, ok := translator.cache.get(MemoryKey("The weather is nice today", "", "zh", "standin/standin-chat", style, "")); ok {
+		t.Error("b was translated by the backup provider and must not be remembered under the requested model")
+	}
+}
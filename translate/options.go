@@ -0,0 +1,115 @@
+package translate
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// maxOptionLength 领域和目标读者的最大长度
+const maxOptionLength = 64
+
+// styleAliases 风格的别名，兼容旧版本extra_args中常见的写法
+var styleAliases = map[string]string{
+	models.StyleFormal:    models.StyleFormal,
+	"正式":                  models.StyleFormal,
+	"书面":                  models.StyleFormal,
+	models.StyleCasual:    models.StyleCasual,
+	"informal":            models.StyleCasual,
+	"口语":                  models.StyleCasual,
+	"口语化":                 models.StyleCasual,
+	models.StyleTechnical: models.StyleTechnical,
+	"技术":                  models.StyleTechnical,
+	"专业":                  models.StyleTechnical,
+}
+
+// styleInstructions 各风格对应的提示词
+var styleInstructions = map[string]string{
+	models.StyleFormal:    "使用正式、书面的语气。",
+	models.StyleCasual:    "使用轻松、口语化的语气。",
+	models.StyleTechnical: "使用准确、专业的技术文档语气，通用的技术术语使用业内惯用译法。",
+}
+
+// NormalizeOptions 校验翻译选项并将风格的别名转换为标准值。领域和目标读者会加入提示词，
+// 只允许文字、数字、空格和少量标点，避免通过选项注入额外的指令
+func NormalizeOptions(options models.TranslateOptions) (models.TranslateOptions, error) {
+	if options.Style != "" {
+		style, ok := styleAliases[strings.ToLower(strings.TrimSpace(options.Style))]
+		if !ok {
+			return options, fmt.Errorf("不支持的风格: %s，可选值为formal、casual、technical", options.Style)
+		}
+		options.Style = style
+	}
+
+	var err error
+	if options.Domain, err = normalizeOptionText("domain", options.Domain); err != nil {
+		return options, err
+	}
+	if options.Audience, err = normalizeOptionText("audience", options.Audience); err != nil {
+		return options, err
+	}
+	return options, nil
+}
+
+// NormalizeLegacyOptions 规范化旧版本extra_args中的翻译选项。旧版本允许任意内容，
+// 不支持的风格和不符合要求的领域、目标读者只记录日志后忽略，不返回错误
+func NormalizeLegacyOptions(options models.TranslateOptions) models.TranslateOptions {
+	if options.Style != "" {
+		style, ok := styleAliases[strings.ToLower(strings.TrimSpace(options.Style))]
+		if !ok {
+			log.Printf("忽略extra_args中不支持的风格: %s", options.Style)
+		}
+		options.Style = style
+	}
+
+	var err error
+	if options.Domain, err = normalizeOptionText("domain", options.Domain); err != nil {
+		log.Printf("忽略extra_args中的domain: %v", err)
+	}
+	if options.Audience, err = normalizeOptionText("audience", options.Audience); err != nil {
+		log.Printf("忽略extra_args中的audience: %v", err)
+	}
+	return options
+}
+
+// normalizeOptionText 合并连续空白并校验长度和字符
+func normalizeOptionText(name, value string) (string, error) {
+	value = strings.Join(strings.Fields(value), " ")
+	if utf8.RuneCountInString(value) > maxOptionLength {
+		return "", fmt.Errorf("%s不能超过%d个字符", name, maxOptionLength)
+	}
+	for _, r := range value {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune(" -_&/.,'·", r) {
+			return "", fmt.Errorf("%s只能包含文字、数字、空格和-_&/.,'·", name)
+		}
+	}
+	return value, nil
+}
+
+// optionsText 按固定的顺序和模板将翻译选项转换为提示词，没有选项时返回空字符串
+func optionsText(options models.TranslateOptions) string {
+	var lines []string
+	if instruction, ok := styleInstructions[options.Style]; ok {
+		lines = append(lines, instruction)
+	}
+	if options.Domain != "" {
+		lines = append(lines, fmt.Sprintf("内容所属领域为「%s」，使用该领域的惯用译法。", options.Domain))
+	}
+	if options.Audience != "" {
+		lines = append(lines, fmt.Sprintf("目标读者为「%s」，译文应适合其阅读。", options.Audience))
+	}
+	if options.PreserveFormatting {
+		lines = append(lines, "保留原文的换行、空格、列表符号和大小写等格式。")
+	}
+	if options.KeepOriginalForUntranslatable {
+		lines = append(lines, "无法翻译或不应翻译的内容（如专有名词、品牌名、代码、网址）保留原文。")
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return "翻译要求:\n- " + strings.Join(lines, "\n- ") + "\n"
+}
@@ -0,0 +1,140 @@
+package translate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// parseResponse 解析大模型返回的翻译结果。兼容代码块标记、JSON前后的说明文字、
+// 多余的尾随逗号，以及直接返回片段数组的情况
+func parseResponse(content string) ([]models.TranslateSegment, error) {
+	var segments []models.TranslateSegment
+	err := parseJSON(content, func(raw string) (err error) {
+		segments, err = decodeSegments(raw)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("解析翻译结果失败: %v, 原始内容: %s", err, content)
+	}
+	return segments, nil
+}
+
+// parseJSON 依次尝试文本中每个完整的JSON对象或数组，删除尾随逗号后交给decode，直到decode成功。
+// 说明文字中也可能出现括号，例如"以下是[2]条译文"，因此不能只取第一个括号
+func parseJSON(content string, decode func(raw string) error) error {
+	var lastErr error
+	for start := 0; start < len(content); start++ {
+		if content[start] != '{' && content[start] != '[' {
+			continue
+		}
+		end := matchBracket(content, start)
+		if end < 0 {
+			continue
+		}
+		err := decode(removeTrailingCommas(content[start : end+1]))
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+	}
+	if lastErr == nil {
+		return errors.New("未找到JSON")
+	}
+	return lastErr
+}
+
+// decodeSegments 将JSON解析为片段列表，只接受包含segments字段的对象或非空的片段数组
+func decodeSegments(raw string) ([]models.TranslateSegment, error) {
+	if strings.HasPrefix(raw, "[") {
+		var segments []models.TranslateSegment
+		if err := json.Unmarshal([]byte(raw), &segments); err != nil {
+			return nil, err
+		}
+		if len(segments) == 0 {
+			return nil, errors.New("片段数组为空")
+		}
+		return segments, nil
+	}
+
+	var translateResp struct {
+		Segments *[]models.TranslateSegment `json:"segments"`
+	}
+	if err := json.Unmarshal([]byte(raw), &translateResp); err != nil {
+		return nil, err
+	}
+	if translateResp.Segments == nil {
+		return nil, errors.New("缺少segments字段")
+	}
+	return *translateResp.Segments, nil
+}
+
+// matchBracket 返回与start处括号匹配的右括号位置，字符串中的括号不计入，找不到时返回-1
+func matchBracket(content string, start int) int {
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(content); i++ {
+		ch := content[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			continue
+		}
+		switch ch {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+		case '}', ']':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// removeTrailingCommas 删除对象和数组末尾多余的逗号，例如 {"a": 1,} 和 [1, 2,]
+func removeTrailingCommas(raw string) string {
+	var b strings.Builder
+	inString, escaped := false, false
+	for i := 0; i < len(raw); i++ {
+		ch := raw[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '"':
+				inString = false
+			}
+			b.WriteByte(ch)
+			continue
+		}
+		if ch == '"' {
+			inString = true
+		}
+		if ch == ',' {
+			next := i + 1
+			for next < len(raw) && strings.ContainsRune(" \t\r\n", rune(raw[next])) {
+				next++
+			}
+			if next < len(raw) && (raw[next] == '}' || raw[next] == ']') {
+				continue
+			}
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
@@ -0,0 +1,247 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// promptSegmentPattern 匹配提示词中的"片段ID x: 文本"行
+var promptSegmentPattern = regexp.MustCompile(`(?m)^片段ID (\S+): (.*)$`)
+
+// promptSegments 从提示词中取出要翻译的片段
+func promptSegments(prompt string) []models.TranslateSegment {
+	var segments []models.TranslateSegment
+	for _, match := range promptSegmentPattern.FindAllStringSubmatch(prompt, -1) {
+		segments = append(segments, models.TranslateSegment{ID: match[1], Text: match[2]})
+	}
+	return segments
+}
+
+// segmentsJSON 把片段序列化为{"segments": [...]}格式的回复
+func segmentsJSON(segments []models.TranslateSegment) string {
+	data, _ := json.Marshal(map[string]interface{}{"segments": segments})
+	return string(data)
+}
+
+// standIn OpenAI兼容的替身服务商，按调用顺序把提示词交给reply生成回复，reply返回空字符串时响应500
+type standIn struct {
+	URL string
+
+	mu      sync.Mutex
+	prompts []string
+	reply   func(call int, prompt string) string
+}
+
+// calls 返回收到的提示词
+func (s *standIn) calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.prompts...)
+}
+
+// newStandIn 启动替身服务商，测试结束时关闭
+func newStandIn(t *testing.T, reply func(call int, prompt string) string) *standIn {
+	t.Helper()
+	stand := &standIn{reply: reply}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req openai.ChatCompletionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		prompt := req.Messages[len(req.Messages)-1].Content
+		stand.mu.Lock()
+		stand.prompts = append(stand.prompts, prompt)
+		call := len(stand.prompts)
+		stand.mu.Unlock()
+
+		content := stand.reply(call, prompt)
+		if content == "" {
+			http.Error(w, `{"error":{"message":"unavailable"}}`, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(openai.ChatCompletionResponse{
+			Model: req.Model,
+			Choices: []openai.ChatCompletionChoice{{
+				Message:      openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: content},
+				FinishReason: openai.FinishReasonStop,
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	stand.URL = server.URL + "/v1"
+	return stand
+}
+
+// newTestTranslatorWith 按config创建连接替身服务商的翻译器，重试不等待
+func newTestTranslatorWith(t *testing.T, config llm.RegistryConfig) *Translator {
+	t.Helper()
+	config.Retry = llm.RetryPolicy{MaxAttempts: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	registry, err := llm.NewRegistry(config)
+	if err != nil {
+		t.Fatalf("NewRegistry: %v", err)
+	}
+	client, err := llm.NewClientWithRegistry(registry)
+	if err != nil {
+		t.Fatalf("NewClientWithRegistry: %v", err)
+	}
+	return NewTranslator(client, nil, nil, nil)
+}
+
+// newTestTranslator 创建连接替身服务商standin的翻译器，默认模型为standin-chat
+func newTestTranslator(t *testing.T, reply func(call int, prompt string) string) (*Translator, *standIn) {
+	t.Helper()
+	stand := newStandIn(t, reply)
+	translator := newTestTranslatorWith(t, llm.RegistryConfig{
+		Providers: []llm.ProviderConfig{{Name: "standin", BaseURL: stand.URL, DefaultModel: "standin-chat"}},
+	})
+	return translator, stand
+}
+
+func TestParseResponse(t *testing.T) {
+	want := []models.TranslateSegment{{ID: "1", Text: "你好"}, {ID: "2", Text: "世界"}}
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{"plain object", `{"target": "zh", "segments": [{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"}]}`},
+		{"code fence", "```json\n{\"segments\": [{\"id\": \"1\", \"text\": \"你好\"}, {\"id\": \"2\", \"text\": \"世界\"}]}\n```"},
+		{"leading and trailing prose", `好的，以下是译文：{"segments": [{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"}]} 希望对你有帮助。`},
+		{"brackets in leading prose", `Here are the [2] translations: {"segments": [{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"}]}`},
+		{"object in leading prose", `Format {id, text}: {"segments": [{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"}]}`},
+		{"unrelated object first", `{"note": "ok"} {"segments": [{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"}]}`},
+		{"trailing commas", "{\"segments\": [\n  {\"id\": \"1\", \"text\": \"你好\",},\n  {\"id\": \"2\", \"text\": \"世界\"},\n],}"},
+		{"bare array", `[{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"}]`},
+		{"bare array after prose", `Result [ok]: [{"id": "1", "text": "你好"}, {"id": "2", "text": "世界"},]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseResponse(tt.content)
+			if err != nil {
+				t.Fatalf("parseResponse: %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("parseResponse = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseResponseErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"no json", "抱歉，我无法翻译。", "未找到JSON"},
+		{"unbalanced", `{"segments": [{"id": "1"`, "未找到JSON"},
+		{"no segments field", `{"translations": "甲"}`, "缺少segments字段"},
+		{"prose brackets only", "Here are the [2] translations", "解析翻译结果失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseResponse(tt.content); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("parseResponse error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveTrailingCommas(t *testing.T) {
+	tests := []struct {
+		raw, want string
+	}{
+		{`{"a": 1,}`, `{"a": 1}`},
+		{"[1, 2 ,\n]", "[1, 2 \n]"},
+		{`{"text": "a,}"}`, `{"text": "a,}"}`},
+		{`{"text": "\",]"}`, `{"text": "\",]"}`},
+	}
+	for _, tt := range tests {
+		if got := removeTrailingCommas(tt.raw); got != tt.want {
+			t.Errorf("removeTrailingCommas(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestTranslateBatchValidatesIDs(t *testing.T) {
+	// 第一次：缺少b、重复c、多出未请求的x、d的译文为空；第二次只重新请求失败的片段
+	translator, stand := newTestTranslator(t, func(call int, prompt string) string {
+		if call == 1 {
+			return `Here are the [4] translations: {"segments": [` +
+				`{"id": "a", "text": "甲"}, {"id": "c", "text": "丙"}, {"id": "c", "text": "丙2"},` +
+				`{"id": "x", "text": "多余"}, {"id": "d", "text": " "}]}`
+		}
+		reply := []models.TranslateSegment{}
+		for _, segment := range promptSegments(prompt) {
+			if segment.ID != "d" {
+				reply = append(reply, models.TranslateSegment{ID: segment.ID, Text: "重试" + segment.ID})
+			}
+		}
+		return segmentsJSON(reply)
+	})
+
+	segments := []models.TranslateSegment{{ID: "a", Text: "A"}, {ID: "b", Text: "B"}, {ID: "c", Text: "C"}, {ID: "d", Text: "D"}}
+	req := models.TranslateRequest{Target: "zh", Segments: segments}
+	result := translator.translateBatch(context.Background(), "standin", "standin-chat", req, batch{segments: segments}, nil)
+
+	wantTranslations := map[string]string{"a": "甲", "b": "重试b", "c": "重试c"}
+	if !reflect.DeepEqual(result.translations, wantTranslations) {
+		t.Errorf("translations = %v, want %v", result.translations, wantTranslations)
+	}
+	if reason := result.failures["d"]; reason != "大模型未返回该片段" {
+		t.Errorf("failures[d] = %q, want the reason from the last attempt", reason)
+	}
+	if len(result.failures) != 1 {
+		t.Errorf("failures = %v, want only d", result.failures)
+	}
+	if result.err == nil || !strings.HasSuffix(result.err.Error(), "标签不一致: d") {
+		t.Errorf("err = %v, want the failed segment IDs", result.err)
+	}
+
+	calls := stand.calls()
+	if len(calls) != maxBatchAttempts {
+		t.Fatalf("calls = %d, want %d", len(calls), maxBatchAttempts)
+	}
+	retried := promptSegments(calls[1])
+	var ids []string
+	for _, segment := range retried {
+		ids = append(ids, segment.ID)
+	}
+	if fmt.Sprint(ids) != "[b c d]" {
+		t.Errorf("second attempt sent %v, want only the failed segments", ids)
+	}
+}
+
+func TestTranslateBatchRetriesUnparsableReply(t *testing.T) {
+	translator, stand := newTestTranslator(t, func(call int, prompt string) string {
+		if call == 1 {
+			return "Sorry, [2] is not valid JSON"
+		}
+		return segmentsJSON([]models.TranslateSegment{{ID: "a", Text: "甲"}})
+	})
+
+	segments := []models.TranslateSegment{{ID: "a", Text: "A"}}
+	req := models.TranslateRequest{Target: "zh", Segments: segments}
+	result := translator.translateBatch(context.Background(), "standin", "standin-chat", req, batch{segments: segments}, nil)
+	if result.err != nil || result.translations["a"] != "甲" {
+		t.Errorf("translateBatch = %+v, want a translated on the second attempt", result)
+	}
+	if len(stand.calls()) != 2 {
+		t.Errorf("calls = %d, want 2", len(stand.calls()))
+	}
+}
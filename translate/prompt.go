@@ -0,0 +1,43 @@
+package translate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// buildPrompt 构造翻译一组片段的提示词，terms为这组片段中出现的术语
+func buildPrompt(req models.TranslateRequest, segments []models.TranslateSegment, terms []glossaryTerm) string {
+	prompt := fmt.Sprintf("请将以下内容翻译为%s语言:\n", req.Target)
+	if req.Source != "" {
+		prompt = fmt.Sprintf("请将以下%s语言的内容翻译为%s语言:\n", req.Source, req.Target)
+	}
+	for _, segment := range segments {
+		prompt += fmt.Sprintf("片段ID %s: %s\n", segment.ID, segment.Text)
+	}
+
+	for _, segment := range segments {
+		if strings.Contains(segment.Text, "⟦") {
+			prompt += "文本中形如⟦1⟧的标记代表格式标签、代码或占位符，必须原样保留在译文中的对应位置，不要翻译、修改、合并或删除。\n"
+			break
+		}
+	}
+
+	if len(terms) > 0 {
+		prompt += "术语表（原文中出现以下术语时必须使用指定的译法）:\n"
+		for _, term := range terms {
+			prompt += fmt.Sprintf("- %s → %s", term.SourceTerm, term.TargetTerm)
+			if term.Note != "" {
+				prompt += fmt.Sprintf("（%s）", term.Note)
+			}
+			prompt += "\n"
+		}
+	}
+
+	prompt += optionsText(req.EffectiveOptions())
+
+	prompt += "请按照以下JSON格式返回结果，只返回JSON，不要包含其他内容:\n"
+	prompt += "{\n  \"target\": \"目标语言\",\n  \"segments\": [\n    {\"id\": \"片段ID\", \"text\": \"翻译后的文本\"}\n  ]\n}"
+	return prompt
+}
@@ -0,0 +1,460 @@
+package translate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// backTranslationWeight 同时使用回译和大模型打分时回译相似度所占的权重
+	backTranslationWeight = 0.3
+	// backTranslationThreshold 只使用回译时相似度低于该分数标记为意思错误
+	backTranslationThreshold = 60
+	// untranslatedMaxScore 译文与原文相同时的最高分
+	untranslatedMaxScore = 20
+)
+
+// QualityStore 质量分数的持久化存储，由store.QualityStore实现
+type QualityStore interface {
+	// SaveQualityScores 批量保存片段的质量分数
+	SaveQualityScores(scores []models.QualityScore) error
+}
+
+// ValidQualityMethod 判断是否为支持的质量评估方式，为空表示默认的judge
+func ValidQualityMethod(method string) bool {
+	switch method {
+	case "", models.QualityJudge, models.QualityBackTranslation, models.QualityBoth:
+		return true
+	}
+	return false
+}
+
+// qualityItem 一个待评估的片段
+type qualityItem struct {
+	ID              string
+	Source          string // 原文
+	SourceLang      string // 回译的目标语言，为空时无法回译
+	Translation     string // 译文
+	BackTranslation string // 回译结果
+}
+
+// judgement 大模型对一个片段的评分
+type judgement struct {
+	ID     string                `json:"id"`
+	Score  float64               `json:"score"`
+	Issues []models.QualityIssue `json:"issues"`
+}
+
+// assess 评估翻译成功的片段的译文质量，结果写入片段的Quality和响应的Quality，并异步保存分数。
+// 评估失败的原因记录在响应的Errors中，不影响翻译结果
+func (t *Translator) assess(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, response *models.TranslateResponse) {
+	method := req.QualityMethod
+	if method == "" {
+		method = models.QualityJudge
+	}
+	judgeProvider, judgeModel := provider, model
+	if req.QualityModel != "" {
+		var err error
+		if judgeProvider, judgeModel, err = t.LLM.ParseModel(req.QualityModel); err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("质量评估: %v", err))
+			return
+		}
+	}
+
+	sources := make(map[string]string, len(req.Segments))
+	for _, segment := range req.Segments {
+		sources[segment.ID] = segment.Text
+	}
+	var items []*qualityItem
+	for _, segment := range response.Segments {
+		if segment.Status != models.SegmentTranslated {
+			continue
+		}
+		sourceLang := req.Source
+		if sourceLang == "" {
+			sourceLang = segment.DetectedLang
+		}
+		items = append(items, &qualityItem{ID: segment.ID, Source: sources[segment.ID], SourceLang: sourceLang, Translation: segment.Text})
+	}
+	if len(items) == 0 {
+		return
+	}
+
+	var errs []string
+	if method == models.QualityBackTranslation || method == models.QualityBoth {
+		errs = append(errs, t.backTranslate(ctx, judgeProvider, judgeModel, req, items)...)
+	}
+	judgements := map[string]judgement{}
+	judge := ""
+	if method == models.QualityJudge || method == models.QualityBoth {
+		var judgeErrs []string
+		judgements, judge, judgeErrs = t.judge(ctx, judgeProvider, judgeModel, req, items, method == models.QualityBoth)
+		errs = append(errs, judgeErrs...)
+	}
+	for _, err := range errs {
+		response.Errors = append(response.Errors, "质量评估: "+err)
+	}
+
+	// 合并回译相似度和大模型评分
+	results := make(map[string]*models.SegmentQuality, len(items))
+	for _, item := range items {
+		quality := &models.SegmentQuality{BackTranslation: item.BackTranslation}
+		similarity := -1
+		if item.BackTranslation != "" {
+			similarity = textSimilarity(maskSegment(req.Format, item.Source).text, maskSegment(req.Format, item.BackTranslation).text)
+		}
+		result, judged := judgements[item.ID]
+		switch method {
+		case models.QualityJudge:
+			if !judged {
+				continue
+			}
+			quality.Score = clampScore(result.Score)
+		case models.QualityBackTranslation:
+			if similarity < 0 {
+				continue
+			}
+			quality.Score = similarity
+			if similarity < backTranslationThreshold {
+				quality.Issues = append(quality.Issues, models.QualityIssue{Type: models.IssueMistranslation, Detail: "回译与原文差异较大"})
+			}
+		case models.QualityBoth:
+			switch {
+			case judged && similarity >= 0:
+				quality.Score = int(math.Round((1-backTranslationWeight)*float64(clampScore(result.Score)) + backTranslationWeight*float64(similarity)))
+			case judged:
+				quality.Score = clampScore(result.Score)
+			case similarity >= 0:
+				quality.Score = similarity
+			default:
+				continue
+			}
+		}
+		quality.Issues = append(quality.Issues, result.Issues...)
+
+		// 译文与原文相同说明没有翻译，回译相似度在这种情况下没有意义
+		if hasLetters(item.Source) && normalizeSource(item.Source, "") == normalizeSource(item.Translation, "") {
+			quality.Issues = append(quality.Issues, models.QualityIssue{Type: models.IssueUntranslated, Detail: "译文与原文相同"})
+			if quality.Score > untranslatedMaxScore {
+				quality.Score = untranslatedMaxScore
+			}
+		}
+		results[item.ID] = quality
+	}
+
+	report := &models.QualityReport{Method: method, Judge: judge, Issues: map[string]int{}}
+	var scores []models.QualityScore
+	total := 0
+	now := time.Now()
+	for i, segment := range response.Segments {
+		quality, ok := results[segment.ID]
+		if !ok {
+			continue
+		}
+		response.Segments[i].Quality = quality
+		report.Scored++
+		total += quality.Score
+		for _, issue := range quality.Issues {
+			report.Issues[issue.Type]++
+		}
+		source := req.Source
+		if source == "" {
+			source = segment.DetectedLang
+		}
+		// 分数记在实际翻译该片段的服务商和模型上，同一次请求的片段可能由不同的模型翻译
+		scores = append(scores, models.QualityScore{
+			Provider:  segment.Provider,
+			Model:     segment.Model,
+			Judge:     judge,
+			Method:    method,
+			Source:    strings.ToLower(source),
+			Target:    strings.ToLower(strings.TrimSpace(req.Target)),
+			Score:     quality.Score,
+			Issues:    quality.Issues,
+			CreatedAt: now,
+		})
+	}
+	if report.Scored > 0 {
+		report.Average = math.Round(float64(total)/float64(report.Scored)*10) / 10
+	}
+	response.Quality = report
+
+	if t.Quality == nil || len(scores) == 0 {
+		return
+	}
+	go func() {
+		if err := t.Quality.SaveQualityScores(scores); err != nil {
+			log.Printf("保存翻译质量分数失败: %v", err)
+		}
+	}()
+}
+
+// qualityModelName 请求中指定的评估模型，未指定时为翻译的模型。指定了模型时评估不切换到备用服务商
+func qualityModelName(req models.TranslateRequest) string {
+	if req.QualityModel != "" {
+		return req.QualityModel
+	}
+	return req.Model
+}
+
+// backTranslate 将译文回译为源语言，无法确定源语言的片段不回译。返回失败的原因
+func (t *Translator) backTranslate(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, items []*qualityItem) []string {
+	byID := make(map[string]*qualityItem, len(items))
+	groups := make(map[string][]models.TranslateSegment)
+	var langs []string
+	masks := make(map[string]maskedText, len(items))
+	for _, item := range items {
+		if item.SourceLang == "" {
+			continue
+		}
+		byID[item.ID] = item
+		masks[item.ID] = maskSegment(req.Format, item.Translation)
+		if _, ok := groups[item.SourceLang]; !ok {
+			langs = append(langs, item.SourceLang)
+		}
+		groups[item.SourceLang] = append(groups[item.SourceLang], models.TranslateSegment{ID: item.ID, Text: masks[item.ID].text})
+	}
+
+	var mu sync.Mutex
+	var errs []string
+	var batches []batch
+	requests := make(map[int]models.TranslateRequest)
+	for _, lang := range langs {
+		backReq := models.TranslateRequest{Target: lang, Source: req.Target, Format: req.Format, Model: qualityModelName(req)}
+		for _, b := range splitBatches(groups[lang], t.batchBudget(provider, model, req), maxBatchSegments) {
+			b.index = len(batches)
+			requests[b.index] = backReq
+			batches = append(batches, b)
+		}
+	}
+	t.runBatches(batches, func(b batch) {
+		translated, _, err := t.complete(ctx, provider, model, requests[b.index], b.segments, nil)
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("回译失败: %v", err))
+			return
+		}
+		for _, segment := range translated {
+			item, ok := byID[segment.ID]
+			if !ok {
+				continue
+			}
+			// 回译结果的标记无法还原时保留标记，不影响相似度计算
+			text, err := masks[segment.ID].unmask(segment.Text)
+			if err != nil {
+				text = segment.Text
+			}
+			item.BackTranslation = text
+		}
+	})
+	return errs
+}
+
+// judge 由大模型按评分标准为每个片段打分，withBackTranslation为true时把回译结果一并提供给大模型。
+// 返回片段ID到评分、实际打分的"服务商/模型"和失败的原因
+func (t *Translator) judge(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, items []*qualityItem, withBackTranslation bool) (map[string]judgement, string, []string) {
+	byID := make(map[string]*qualityItem, len(items))
+	pairs := make([]models.TranslateSegment, 0, len(items))
+	for _, item := range items {
+		byID[item.ID] = item
+		// 按原文和译文的总长度分批
+		pairs = append(pairs, models.TranslateSegment{ID: item.ID, Text: item.Source + "\n" + item.Translation})
+	}
+
+	var mu sync.Mutex
+	var errs []string
+	judge := ""
+	results := make(map[string]judgement, len(items))
+	t.runBatches(splitBatches(pairs, t.batchBudget(provider, model, req), maxBatchSegments), func(b batch) {
+		batchItems := make([]*qualityItem, len(b.segments))
+		for i, segment := range b.segments {
+			batchItems[i] = byID[segment.ID]
+		}
+		reqBody := openai.ChatCompletionRequest{
+			Messages: []openai.ChatCompletionMessage{
+				{Role: openai.ChatMessageRoleUser, Content: judgePrompt(req, batchItems, withBackTranslation)},
+			},
+		}
+		// 评分使用为0的采样温度，同样的输入尽量得到同样的分数
+		temperature := float32(0)
+		judgeCtx := llm.WithSampling(ctx, llm.Sampling{Temperature: &temperature})
+		resp, served, err := t.createCompletion(judgeCtx, provider, model, qualityModelName(req) != "", reqBody)
+		var parsed []judgement
+		if err == nil {
+			if len(resp.Choices) == 0 {
+				err = fmt.Errorf("大模型未返回有效内容")
+			} else {
+				parsed, err = parseJudgement(resp.Choices[0].Message.Content)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("评分失败: %v", err))
+			return
+		}
+		if judge == "" {
+			judge = string(served.Provider) + "/" + served.Model
+		}
+		for _, result := range parsed {
+			if _, ok := byID[result.ID]; ok {
+				results[result.ID] = result
+			}
+		}
+	})
+	return results, judge, errs
+}
+
+// runBatches 有界的并发处理各批次
+func (t *Translator) runBatches(batches []batch, process func(batch)) {
+	sem := make(chan struct{}, t.Concurrency)
+	var wg sync.WaitGroup
+	for _, b := range batches {
+		wg.Add(1)
+		go func(b batch) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			process(b)
+		}(b)
+	}
+	wg.Wait()
+}
+
+// judgePrompt 构造评分的提示词
+func judgePrompt(req models.TranslateRequest, items []*qualityItem, withBackTranslation bool) string {
+	source := "原文的语言"
+	if req.Source != "" {
+		source = req.Source
+	}
+	prompt := fmt.Sprintf("你是专业的翻译质量评审。请逐条评估以下从%s到%s的译文，按0-100打分:\n", source, req.Target)
+	prompt += "- 90-100: 准确、完整、通顺\n- 70-89: 意思正确，有轻微的措辞或语法问题\n- 40-69: 有明显的错误或遗漏\n- 0-39: 严重错误或基本没有翻译\n"
+	prompt += "发现的问题类型只能是: omission（遗漏原文内容）、mistranslation（意思错误）、untranslated（应翻译的内容没有翻译）。\n"
+	if withBackTranslation {
+		prompt += "回译是把译文重新翻译回原文语言的结果，可以作为判断意思是否一致的参考。\n"
+	}
+	for _, item := range items {
+		prompt += fmt.Sprintf("片段ID %s:\n原文: %s\n译文: %s\n", item.ID, item.Source, item.Translation)
+		if withBackTranslation && item.BackTranslation != "" {
+			prompt += fmt.Sprintf("回译: %s\n", item.BackTranslation)
+		}
+	}
+	prompt += "请按照以下JSON格式返回结果，只返回JSON，不要包含其他内容:\n"
+	prompt += "{\n  \"segments\": [\n    {\"id\": \"片段ID\", \"score\": 85, \"issues\": [{\"type\": \"omission\", \"detail\": \"简要说明\"}]}\n  ]\n}"
+	return prompt
+}
+
+// parseJudgement 解析大模型返回的评分，兼容代码块标记、说明文字和直接返回数组的情况，忽略未知的问题类型
+func parseJudgement(content string) ([]judgement, error) {
+	var results []judgement
+	err := parseJSON(content, func(raw string) error {
+		if strings.HasPrefix(raw, "[") {
+			if err := json.Unmarshal([]byte(raw), &results); err != nil {
+				return err
+			}
+			if len(results) == 0 {
+				return errors.New("评分数组为空")
+			}
+			return nil
+		}
+		var judgeResp struct {
+			Segments *[]judgement `json:"segments"`
+		}
+		if err := json.Unmarshal([]byte(raw), &judgeResp); err != nil {
+			return err
+		}
+		if judgeResp.Segments == nil {
+			return errors.New("缺少segments字段")
+		}
+		results = *judgeResp.Segments
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("解析评分结果失败: %v, 原始内容: %s", err, content)
+	}
+
+	for i, result := range results {
+		issues := result.Issues[:0]
+		for _, issue := range result.Issues {
+			switch issue.Type {
+			case models.IssueOmission, models.IssueMistranslation, models.IssueUntranslated:
+				issues = append(issues, issue)
+			}
+		}
+		results[i].Issues = issues
+	}
+	return results, nil
+}
+
+// clampScore 将分数限制在0-100之间并取整
+func clampScore(score float64) int {
+	return int(math.Round(math.Max(0, math.Min(100, score))))
+}
+
+// textSimilarity 按字符二元组计算两段文本的相似度（Dice系数），返回0-100。只比较字母和数字，忽略保护标记、大小写和标点
+func textSimilarity(a, b string) int {
+	x, y := bigrams(a), bigrams(b)
+	total := 0
+	for _, n := range x {
+		total += n
+	}
+	for _, n := range y {
+		total += n
+	}
+	if total == 0 {
+		return 100
+	}
+	common := 0
+	for gram, n := range x {
+		if m := y[gram]; m < n {
+			common += m
+		} else {
+			common += n
+		}
+	}
+	return int(math.Round(200 * float64(common) / float64(total)))
+}
+
+// bigrams 统计文本的字符二元组
+func bigrams(text string) map[string]int {
+	text = markerPattern.ReplaceAllString(text, " ")
+	var runes []rune
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			runes = append(runes, r)
+		}
+	}
+	grams := make(map[string]int)
+	for i := 0; i+1 < len(runes); i++ {
+		grams[string(runes[i:i+2])]++
+	}
+	if len(runes) == 1 {
+		grams[string(runes)]++
+	}
+	return grams
+}
+
+// hasLetters 判断文本中是否有字母
+func hasLetters(text string) bool {
+	for _, r := range text {
+		if unicode.IsLetter(r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package translate
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// recordingQualityStore 记录保存的质量分数
+type recordingQualityStore chan []models.QualityScore
+
+func (s recordingQualityStore) SaveQualityScores(scores []models.QualityScore) error {
+	s <- scores
+	return nil
+}
+
+func TestQualityScoresRecordServedModel(t *testing.T) {
+	// 主服务商翻译a后不可用，b由备用服务商翻译；评分由主服务商完成
+	primary := newStandIn(t, func(call int, prompt string) string {
+		switch {
+		case strings.Contains(prompt, "翻译质量评审"):
+			return `{"segments": [{"id": "a", "score": 90, "issues": []}, {"id": "b", "score": 70, "issues": []}]}`
+		case call == 1:
+			return segmentsJSON([]models.TranslateSegment{{ID: "a", Text: "早上好"}})
+		}
+		return ""
+	})
+	backup := newStandIn(t, func(call int, prompt string) string {
+		return segmentsJSON([]models.TranslateSegment{{ID: "b", Text: "今天天气很好"}})
+	})
+	translator := newTestTranslatorWith(t, llm.RegistryConfig{
+		Default:  "standin",
+		Fallback: []string{"standin", "backup"},
+		Providers: []llm.ProviderConfig{
+			{Name: "standin", BaseURL: primary.URL, DefaultModel: "standin-chat"},
+			{Name: "backup", BaseURL: backup.URL, DefaultModel: "backup-chat"},
+		},
+	})
+	store := make(recordingQualityStore, 1)
+	translator.Quality = store
+
+	req := models.TranslateRequest{
+		Target:     "zh",
+		Quality:    true,
+		SkipMemory: true,
+		Segments:   []models.TranslateSegment{{ID: "a", Text: "Good morning to all of you"}, {ID: "b", Text: "The weather is nice today"}},
+	}
+	response, err := translator.Translate(context.Background(), "standin", "standin-chat", req)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+	if response.Quality == nil || response.Quality.Scored != 2 {
+		t.Fatalf("Quality = %+v, errors = %v", response.Quality, response.Errors)
+	}
+
+	select {
+	case scores := <-store:
+		want := map[int][2]string{90: {"standin", "standin-chat"}, 70: {"backup", "backup-chat"}}
+		if len(scores) != 2 {
+			t.Fatalf("saved %d scores, want 2", len(scores))
+		}
+		for _, score := range scores {
+			if got := [2]string{score.Provider, score.Model}; got != want[score.Score] {
+				t.Errorf("score %d recorded for %v, want %v", score.Score, got, want[score.Score])
+			}
+			if score.Judge != "standin/standin-chat" {
+				t.Errorf("Judge = %q", score.Judge)
+			}
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("quality scores were not saved")
+	}
+}
@@ -0,0 +1,478 @@
+package translate
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+const (
+	// defaultBatchTokens 每批片段的默认token预算
+	defaultBatchTokens = 1500
+	// minBatchTokens 每批片段的最小token预算
+	minBatchTokens = 100
+	// defaultConcurrency 默认同时翻译的批次数
+	defaultConcurrency = 4
+	// maxBatchSegments 每批最多包含的片段数
+	maxBatchSegments = 50
+	// maxBatchAttempts 每批最多调用大模型的次数，重试时只发送失败或缺失的片段
+	maxBatchAttempts = 3
+)
+
+// Translator 翻译器，先查询翻译记忆，未命中的片段按token预算分批并发翻译后按片段ID合并结果
+type Translator struct {
+	LLM         *llm.Client
+	Memory      Memory       // 翻译记忆的持久化存储，为nil时只使用进程内缓存
+	Glossary    Glossary     // 术语表存储，为nil时忽略请求中的术语表
+	Quality     QualityStore // 质量分数存储，为nil时不保存分数
+	BatchTokens int          // 每批片段的token预算
+	Concurrency int          // 同时翻译的批次数
+
+	cache *lruCache // 进程内的翻译记忆缓存
+}
+
+// NewTranslator 创建新的翻译器，批次大小和并发数可以通过环境变量TRANSLATE_BATCH_TOKENS和TRANSLATE_CONCURRENCY调整，
+// 进程内翻译记忆缓存的条目数和有效期可以通过环境变量TRANSLATE_MEMORY_CACHE_SIZE和TRANSLATE_MEMORY_CACHE_TTL调整
+func NewTranslator(client *llm.Client, memory Memory, glossary Glossary, quality QualityStore) *Translator {
+	return &Translator{
+		LLM:         client,
+		Memory:      memory,
+		Glossary:    glossary,
+		Quality:     quality,
+		BatchTokens: envInt("TRANSLATE_BATCH_TOKENS", defaultBatchTokens),
+		Concurrency: envInt("TRANSLATE_CONCURRENCY", defaultConcurrency),
+		cache:       newLRUCache(memoryCacheSize(), memoryCacheTTL()),
+	}
+}
+
+// envInt 读取正整数环境变量，未设置或无效时使用默认值
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// batchResult 一批片段的翻译结果
+type batchResult struct {
+	translations map[string]string       // 片段ID到译文
+	failures     map[string]string       // 未通过校验的片段ID及原因
+	misses       map[string][]string     // 片段ID到译文中未使用要求译法的术语
+	served       map[string]llm.ServedBy // 片段ID到翻译该片段的那次调用实际使用的服务商和模型
+	err          error
+}
+
+// segment 按批次结果构造片段，未翻译的片段保留原文并标记为失败
+func (r batchResult) segment(source models.TranslateSegment) models.TranslateSegment {
+	if text, ok := r.translations[source.ID]; ok {
+		served := r.served[source.ID]
+		return models.TranslateSegment{ID: source.ID, Text: text, Status: models.SegmentTranslated, GlossaryMisses: r.misses[source.ID], Provider: string(served.Provider), Model: served.Model}
+	}
+	reason := r.failures[source.ID]
+	if reason == "" && r.err != nil {
+		reason = r.err.Error()
+	}
+	return models.TranslateSegment{ID: source.ID, Text: source.Text, Status: models.SegmentFailed, Error: reason}
+}
+
+// Translate 翻译请求中的全部片段。部分批次失败时返回已翻译的片段，并在Failed和Errors中说明；
+// 全部片段都翻译失败时返回错误
+func (t *Translator) Translate(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest) (*models.TranslateResponse, error) {
+	response := t.TranslateEach(ctx, provider, model, req, nil)
+	if len(response.Failed) > 0 && len(response.Failed) == len(req.Segments) {
+		return nil, fmt.Errorf("全部片段翻译失败: %s", strings.Join(response.Errors, "; "))
+	}
+	return response, nil
+}
+
+// TranslateEach 翻译请求中的全部片段，先推送跳过翻译和翻译记忆命中的片段，其余片段每批完成后按顺序调用onSegment。
+// onSegment的调用是串行的，可以直接写入响应；失败的片段保留原文，Status为failed，同时记录在返回结果的Failed中。
+// 请求设置quality时，全部片段完成后评估译文质量，评估结果只在返回结果中提供
+func (t *Translator) TranslateEach(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, onSegment func(models.TranslateSegment)) *models.TranslateResponse {
+	l := t.prepare(provider, model, req, []string{req.Target})[req.Target]
+	return t.translatePrepared(ctx, provider, model, req, l, make(chan struct{}, t.Concurrency), onSegment)
+}
+
+// TranslateTargets 将请求中的片段同时翻译为targets中的每种目标语言，返回按目标语言组织的结果。
+// 各目标语言共用一次语言识别、术语表查询和翻译记忆查询，全部目标语言的批次共用同一个并发限制；
+// 某个目标语言的全部片段都翻译失败时记录在返回结果的Failed中
+func (t *Translator) TranslateTargets(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest) *models.MultiTranslateResponse {
+	lookups := t.prepare(provider, model, req, req.Targets)
+	response := &models.MultiTranslateResponse{
+		Source:       req.Source,
+		Targets:      req.Targets,
+		Translations: make(map[string]*models.TranslateResponse, len(req.Targets)),
+	}
+
+	sem := make(chan struct{}, t.Concurrency)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	for _, target := range req.Targets {
+		wg.Add(1)
+		go func(target string) {
+			defer wg.Done()
+			single := req
+			single.Target, single.Targets = target, nil
+			result := t.translatePrepared(ctx, provider, model, single, lookups[target], sem, nil)
+
+			mu.Lock()
+			defer mu.Unlock()
+			response.Translations[target] = result
+		}(target)
+	}
+	wg.Wait()
+
+	for _, target := range req.Targets {
+		if failed := response.Translations[target].Failed; len(failed) > 0 && len(failed) == len(req.Segments) {
+			response.Failed = append(response.Failed, target)
+		}
+	}
+	return response
+}
+
+// lookup 翻译为一种目标语言之前的查询结果
+type lookup struct {
+	detected   map[string]detection      // 片段ID到语言识别结果
+	candidates []models.TranslateSegment // 需要翻译的片段，不包含跳过的片段
+	terms      []glossaryTerm            // 适用于该目标语言的术语
+	errors     []string                  // 查询失败的原因，查询失败时继续翻译
+	useMemory  bool                      // 是否使用翻译记忆
+	keys, hits map[string]string         // 片段ID到翻译记忆的键和命中的译文
+}
+
+// prepare 为各目标语言识别片段语言、加载术语表并查询翻译记忆，每种查询只进行一次。
+// 术语表修改后不符合要求的译文按未命中处理
+func (t *Translator) prepare(provider llm.ModelProvider, model string, req models.TranslateRequest, targets []string) map[string]*lookup {
+	languages := t.detect(req)
+	terms, err := t.loadGlossaries(req, targets)
+	useMemory := t.memoryEnabled(req)
+
+	lookups := make(map[string]*lookup, len(targets))
+	candidates := make(map[string][]models.TranslateSegment)
+	for _, target := range targets {
+		l := &lookup{
+			detected:  skipTarget(languages, target, req.TranslateAll),
+			terms:     terms[strings.ToLower(target)],
+			useMemory: useMemory,
+			keys:      map[string]string{},
+			hits:      map[string]string{},
+		}
+		if err != nil {
+			l.errors = append(l.errors, fmt.Sprintf("加载术语表失败: %v", err))
+		}
+		for _, segment := range req.Segments {
+			if l.detected[segment.ID].skip == "" {
+				l.candidates = append(l.candidates, segment)
+			}
+		}
+		if useMemory && len(l.candidates) > 0 {
+			candidates[target] = l.candidates
+		}
+		lookups[target] = l
+	}
+	if len(candidates) == 0 {
+		return lookups
+	}
+
+	keys, hits := t.recall(provider, model, req, candidates)
+	for target := range candidates {
+		l := lookups[target]
+		l.keys, l.hits = keys[target], hits[target]
+		for _, segment := range l.candidates {
+			if text, ok := l.hits[segment.ID]; ok && len(glossaryMisses(req.Format, l.terms, segment.Text, text)) > 0 {
+				delete(l.hits, segment.ID)
+			}
+		}
+	}
+	return lookups
+}
+
+// translatePrepared 按查询结果翻译为req.Target，sem限制同时翻译的批次数
+func (t *Translator) translatePrepared(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, l *lookup, sem chan struct{}, onSegment func(models.TranslateSegment)) *models.TranslateResponse {
+	response := &models.TranslateResponse{
+		Target:   req.Target,
+		Source:   req.Source,
+		Segments: []models.TranslateSegment{},
+		Errors:   l.errors,
+	}
+	done := make(map[string]models.TranslateSegment, len(req.Segments))
+	emit := func(segment models.TranslateSegment) {
+		done[segment.ID] = segment
+		if onSegment != nil {
+			onSegment(segment)
+		}
+	}
+
+	// 已经是目标语言或不需要翻译的片段直接返回原文
+	detected := l.detected
+	for _, segment := range req.Segments {
+		if skip := detected[segment.ID].skip; skip != "" {
+			emit(models.TranslateSegment{ID: segment.ID, Text: segment.Text, Status: models.SegmentSkipped, DetectedLang: detected[segment.ID].lang, SkipReason: skip})
+		}
+	}
+
+	// 翻译记忆命中的片段直接返回，只有未命中的片段调用大模型翻译
+	var misses []models.TranslateSegment
+	for _, segment := range l.candidates {
+		if text, ok := l.hits[segment.ID]; ok {
+			emit(models.TranslateSegment{ID: segment.ID, Text: text, Status: models.SegmentTranslated, Cached: true, DetectedLang: detected[segment.ID].lang, Provider: string(provider), Model: model})
+			continue
+		}
+		misses = append(misses, segment)
+	}
+	if l.useMemory {
+		response.Memory = &models.TranslationMemoryStats{Hits: len(l.hits), Misses: len(misses)}
+	}
+
+	batches := splitBatches(misses, t.batchBudget(provider, model, req), maxBatchSegments)
+
+	// 有界的并发翻译各批次
+	results := make([]batchResult, len(batches))
+	var wg sync.WaitGroup
+	var emitMu sync.Mutex
+	for _, b := range batches {
+		wg.Add(1)
+		go func(b batch) {
+			defer wg.Done()
+			sem <- struct{}{}
+			result := t.translateBatch(ctx, provider, model, req, b, l.terms)
+			<-sem
+			results[b.index] = result
+
+			emitMu.Lock()
+			defer emitMu.Unlock()
+			for _, source := range b.segments {
+				segment := result.segment(source)
+				segment.DetectedLang = detected[source.ID].lang
+				emit(segment)
+			}
+		}(b)
+	}
+	wg.Wait()
+
+	// 汇总各批次的结果，翻译成功的片段写入翻译记忆
+	for i, b := range batches {
+		result := results[i]
+		for _, source := range b.segments {
+			if served, ok := result.served[source.ID]; ok && response.Provider == "" {
+				response.Provider = string(served.Provider)
+				response.Model = served.Model
+			}
+		}
+		if result.err != nil {
+			response.Errors = append(response.Errors, fmt.Sprintf("第%d批: %v", b.index+1, result.err))
+		}
+		if !l.useMemory {
+			continue
+		}
+		// 故障切换到备用模型翻译的片段不计入请求模型的翻译记忆
+		var remembered []models.TranslateSegment
+		for _, source := range b.segments {
+			segment := done[source.ID]
+			if segment.Status == models.SegmentTranslated && len(segment.GlossaryMisses) == 0 && !result.served[source.ID].Failover {
+				remembered = append(remembered, segment)
+			}
+		}
+		t.remember(req, l.keys, provider, model, remembered)
+	}
+
+	// 按片段ID合并结果，保持请求中的顺序
+	for _, source := range req.Segments {
+		segment := done[source.ID]
+		switch segment.Status {
+		case models.SegmentFailed:
+			response.Failed = append(response.Failed, segment.ID)
+		case models.SegmentSkipped:
+			response.Skipped = append(response.Skipped, segment.ID)
+		}
+		if lang := segment.DetectedLang; lang != "" {
+			if response.Detected == nil {
+				response.Detected = make(map[string]int)
+			}
+			response.Detected[lang]++
+		}
+		response.Segments = append(response.Segments, segment)
+	}
+	if response.Provider == "" && len(l.hits) > 0 {
+		response.Provider = string(provider)
+		response.Model = model
+	}
+
+	// 全部片段处理完成后评估译文质量
+	if req.Quality {
+		t.assess(ctx, provider, model, req, response)
+	}
+
+	return response
+}
+
+// detect 识别每个片段的语言，各目标语言共用识别结果
+func (t *Translator) detect(req models.TranslateRequest) map[string]detection {
+	detected := make(map[string]detection, len(req.Segments))
+	for _, segment := range req.Segments {
+		detected[segment.ID] = detectLanguage(maskSegment(req.Format, segment.Text).text)
+	}
+	return detected
+}
+
+// skipTarget 标记已经是目标语言的片段。请求设置了translate_all时只标注语言，不跳过任何片段
+func skipTarget(languages map[string]detection, target string, translateAll bool) map[string]detection {
+	lang, variant := parseTarget(target)
+	detected := make(map[string]detection, len(languages))
+	for id, d := range languages {
+		if d.skip == "" && d.isTarget(lang, variant) {
+			d.skip = SkipTargetLanguage
+		}
+		if translateAll {
+			d.skip = ""
+		}
+		detected[id] = d
+	}
+	return detected
+}
+
+// batchBudget 计算每批片段的token预算：不超过模型上下文窗口的四分之一，
+// 指定了max_tokens时不超过其一半，为译文长度增长留出空间
+func (t *Translator) batchBudget(provider llm.ModelProvider, model string, req models.TranslateRequest) int {
+	budget := t.BatchTokens
+	if config, ok := t.LLM.Registry().Lookup(string(provider)); ok {
+		if info, ok := config.FindModel(model); ok && info.ContextWindow > 0 && info.ContextWindow/4 < budget {
+			budget = info.ContextWindow / 4
+		}
+	}
+	if req.MaxTokens > 0 && req.MaxTokens/2 < budget {
+		budget = req.MaxTokens / 2
+	}
+	if budget < minBatchTokens {
+		budget = minBatchTokens
+	}
+	return budget
+}
+
+// translateBatch 翻译一批片段，并校验每个片段ID在结果中恰好出现一次、译文不为空且标签和占位符与原文一致。
+// 调用失败时重试全部未完成的片段，未通过校验的片段单独重新请求。译文未使用术语表要求的译法时只标记，不重新请求
+func (t *Translator) translateBatch(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, b batch, terms []glossaryTerm) batchResult {
+	result := batchResult{
+		translations: make(map[string]string),
+		failures:     make(map[string]string),
+		misses:       make(map[string][]string),
+		served:       make(map[string]llm.ServedBy),
+	}
+
+	// 按格式保护标签和占位符，发送给大模型的是替换为标记后的文本
+	masks := make(map[string]maskedText, len(b.segments))
+	pending := make([]models.TranslateSegment, len(b.segments))
+	for i, segment := range b.segments {
+		masks[segment.ID] = maskSegment(req.Format, segment.Text)
+		pending[i] = models.TranslateSegment{ID: segment.ID, Text: masks[segment.ID].text}
+	}
+
+	for attempt := 1; attempt <= maxBatchAttempts && len(pending) > 0; attempt++ {
+		translated, served, err := t.complete(ctx, provider, model, req, pending, batchTerms(terms, pending))
+		if err != nil {
+			result.err = err
+			if ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+
+		// 按片段ID统计返回结果，请求之外的片段ID直接忽略
+		returned := make(map[string][]string, len(translated))
+		for _, segment := range translated {
+			returned[segment.ID] = append(returned[segment.ID], segment.Text)
+		}
+
+		var retry []models.TranslateSegment
+		var invalid []string
+		for _, segment := range pending {
+			texts := returned[segment.ID]
+			switch {
+			case len(texts) == 0:
+				result.failures[segment.ID] = "大模型未返回该片段"
+			case len(texts) > 1:
+				result.failures[segment.ID] = fmt.Sprintf("大模型返回了%d次该片段", len(texts))
+			case strings.TrimSpace(texts[0]) == "" && strings.TrimSpace(segment.Text) != "":
+				result.failures[segment.ID] = "译文为空"
+			default:
+				text, err := masks[segment.ID].unmask(texts[0])
+				if err != nil {
+					result.failures[segment.ID] = err.Error()
+					break
+				}
+				result.translations[segment.ID] = text
+				// 重试可能由备用服务商完成，按片段记录每次调用实际使用的服务商
+				result.served[segment.ID] = served
+				if missing := missingTerms(terms, segment.Text, texts[0]); len(missing) > 0 {
+					result.misses[segment.ID] = missing
+				}
+				delete(result.failures, segment.ID)
+				continue
+			}
+			retry = append(retry, segment)
+			invalid = append(invalid, segment.ID)
+		}
+		pending = retry
+		result.err = nil
+		if len(invalid) > 0 {
+			result.err = fmt.Errorf("片段缺失、重复、译文为空或标签不一致: %s", strings.Join(invalid, ", "))
+		}
+	}
+	return result
+}
+
+// complete 调用大模型翻译一组片段，返回解析出的片段
+func (t *Translator) complete(ctx context.Context, provider llm.ModelProvider, model string, req models.TranslateRequest, segments []models.TranslateSegment, terms []glossaryTerm) ([]models.TranslateSegment, llm.ServedBy, error) {
+	messages := []openai.ChatCompletionMessage{
+		{
+			Role:    openai.ChatMessageRoleUser,
+			Content: buildPrompt(req, segments, terms),
+		},
+	}
+
+	// 获取响应，请求未指定模型时服务商不可用会自动切换到备用服务商
+	ctx = llm.WithSampling(ctx, llm.Sampling{Temperature: req.Temperature, TopP: req.TopP})
+	resp, served, err := t.createCompletion(ctx, provider, model, req.Model != "", completionRequest(req, messages))
+	if err != nil {
+		return nil, served, fmt.Errorf("调用大模型API失败: %v", err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, served, fmt.Errorf("大模型未返回有效内容")
+	}
+
+	translated, err := parseResponse(resp.Choices[0].Message.Content)
+	if err != nil {
+		return nil, served, err
+	}
+	return translated, served, nil
+}
+
+// createCompletion 调用大模型，pinned为true时只使用指定的服务商和模型，不切换到备用服务商。
+// 请求明确指定了模型时，备用模型的译文不符合调用方的要求
+func (t *Translator) createCompletion(ctx context.Context, provider llm.ModelProvider, model string, pinned bool, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, llm.ServedBy, error) {
+	if pinned {
+		return t.LLM.CreateChatCompletionPinned(ctx, provider, model, req)
+	}
+	return t.LLM.CreateChatCompletion(ctx, provider, model, req)
+}
+
+// completionRequest 构造翻译使用的聊天完成请求，max_tokens为0时使用服务商默认值。
+// temperature和top_p通过llm.WithSampling原样发送，未指定时使用服务商默认值
+func completionRequest(req models.TranslateRequest, messages []openai.ChatCompletionMessage) openai.ChatCompletionRequest {
+	reqBody := openai.ChatCompletionRequest{
+		Messages: messages,
+	}
+	if req.MaxTokens > 0 {
+		reqBody.MaxTokens = req.MaxTokens
+	}
+	return reqBody
+}
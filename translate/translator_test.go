@@ -0,0 +1,79 @@
+package translate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aimmetal-tech/wistrans-backend/llm"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// newFailoverTranslator 主服务商第一次只返回a、之后都返回500，备用服务商翻译收到的全部片段
+func newFailoverTranslator(t *testing.T) (*Translator, *standIn) {
+	primary := newStandIn(t, func(call int, prompt string) string {
+		if call == 1 {
+			return segmentsJSON([]models.TranslateSegment{{ID: "a", Text: "早上好"}})
+		}
+		return ""
+	})
+	backup := newStandIn(t, func(call int, prompt string) string {
+		var reply []models.TranslateSegment
+		for _, segment := range promptSegments(prompt) {
+			reply = append(reply, models.TranslateSegment{ID: segment.ID, Text: "备用译文"})
+		}
+		return segmentsJSON(reply)
+	})
+	translator := newTestTranslatorWith(t, llm.RegistryConfig{
+		Default:  "standin",
+		Fallback: []string{"standin", "backup"},
+		Providers: []llm.ProviderConfig{
+			{Name: "standin", BaseURL: primary.URL, DefaultModel: "standin-chat"},
+			{Name: "backup", BaseURL: backup.URL, DefaultModel: "backup-chat"},
+		},
+	})
+	return translator, backup
+}
+
+func TestTranslateReportsServedPerSegment(t *testing.T) {
+	translator, _ := newFailoverTranslator(t)
+	req := models.TranslateRequest{
+		Target:     "zh",
+		SkipMemory: true,
+		Segments:   []models.TranslateSegment{{ID: "a", Text: "Good morning to all of you"}, {ID: "b", Text: "The weather is nice today"}},
+	}
+	response, err := translator.Translate(context.Background(), "standin", "standin-chat", req)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+
+	want := map[string][2]string{"a": {"standin", "standin-chat"}, "b": {"backup", "backup-chat"}}
+	for _, segment := range response.Segments {
+		if got := [2]string{segment.Provider, segment.Model}; got != want[segment.ID] {
+			t.Errorf("segment %s served by %v, want %v", segment.ID, got, want[segment.ID])
+		}
+	}
+}
+
+func TestTranslateNamedModelDoesNotFailOver(t *testing.T) {
+	translator, backup := newFailoverTranslator(t)
+	req := models.TranslateRequest{
+		Target:     "zh",
+		Model:      "standin/standin-chat",
+		SkipMemory: true,
+		Segments:   []models.TranslateSegment{{ID: "a", Text: "Good morning to all of you"}, {ID: "b", Text: "The weather is nice today"}},
+	}
+	response, err := translator.Translate(context.Background(), "standin", "standin-chat", req)
+	if err != nil {
+		t.Fatalf("Translate: %v", err)
+	}
+
+	if len(backup.calls()) != 0 {
+		t.Errorf("backup provider called %d times, want 0 for a named model", len(backup.calls()))
+	}
+	if len(response.Failed) != 1 || response.Failed[0] != "b" {
+		t.Errorf("Failed = %v, want [b]", response.Failed)
+	}
+	if segment := response.Segments[0]; segment.Provider != "standin" || segment.Model != "standin-chat" {
+		t.Errorf("segment a served by %s/%s, want standin/standin-chat", segment.Provider, segment.Model)
+	}
+}
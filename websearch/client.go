@@ -0,0 +1,210 @@
+package websearch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aimmetal-tech/wistrans-backend/mcp"
+	"github.com/aimmetal-tech/wistrans-backend/models"
+)
+
+// DefaultServerName 默认使用的联网搜索MCP服务器
+const DefaultServerName = "阿里云百炼_联网搜索"
+
+// Client 联网搜索客户端，通过MCP服务器的tools/call执行搜索
+type Client struct {
+	Manager    *mcp.Manager
+	ServerName string
+	ToolName   string // 为空时自动选择名称中包含search的工具
+}
+
+// NewClient 创建新的联网搜索客户端
+func NewClient(manager *mcp.Manager) (*Client, error) {
+	// 阿里云百炼联网搜索需要QWEN_API_KEY进行鉴权
+	if os.Getenv("QWEN_API_KEY") == "" {
+		return nil, fmt.Errorf("未配置QWEN_API_KEY环境变量")
+	}
+
+	return &Client{
+		Manager:    manager,
+		ServerName: DefaultServerName,
+		ToolName:   os.Getenv("WEB_SEARCH_TOOL"),
+	}, nil
+}
+
+// Search 执行联网搜索
+func (c *Client) Search(ctx context.Context, req models.WebSearchRequest) (*models.WebSearchResponse, error) {
+	tools, err := c.Manager.Tools(ctx, c.ServerName)
+	if err != nil {
+		return nil, err
+	}
+
+	tool, err := c.selectTool(tools)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.Manager.CallTool(ctx, c.ServerName, tool.Name, buildArguments(tool, req))
+	if err != nil {
+		return nil, fmt.Errorf("调用搜索工具%s失败: %v", tool.Name, err)
+	}
+	if result.IsError {
+		return nil, fmt.Errorf("搜索工具返回错误: %s", result.Text())
+	}
+
+	response := &models.WebSearchResponse{
+		Query:      req.Query,
+		SearchTime: time.Now(),
+		Status:     "success",
+	}
+
+	text := result.Text()
+	results, total, ok := parseResults(text)
+	if !ok {
+		response.RawText = text
+		return response, nil
+	}
+	if req.MaxResults > 0 && len(results) > req.MaxResults {
+		results = results[:req.MaxResults]
+	}
+	response.Results = results
+	response.TotalCount = total
+	if response.TotalCount == 0 {
+		response.TotalCount = len(results)
+	}
+	return response, nil
+}
+
+// selectTool 选择用于搜索的工具
+func (c *Client) selectTool(tools []mcp.Tool) (mcp.Tool, error) {
+	for _, tool := range tools {
+		if c.ToolName != "" && tool.Name == c.ToolName {
+			return tool, nil
+		}
+	}
+	if c.ToolName != "" {
+		return mcp.Tool{}, fmt.Errorf("MCP服务器%s不提供工具%s", c.ServerName, c.ToolName)
+	}
+	for _, tool := range tools {
+		if strings.Contains(strings.ToLower(tool.Name), "search") {
+			return tool, nil
+		}
+	}
+	return mcp.Tool{}, fmt.Errorf("MCP服务器%s没有可用的搜索工具", c.ServerName)
+}
+
+// buildArguments 根据工具的输入Schema构造调用参数，只传递Schema中声明的字段
+func buildArguments(tool mcp.Tool, req models.WebSearchRequest) map[string]interface{} {
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	_ = json.Unmarshal(tool.InputSchema, &schema)
+	has := func(name string) bool {
+		_, ok := schema.Properties[name]
+		return ok
+	}
+
+	args := map[string]interface{}{}
+	queryKey := "query"
+	for _, key := range []string{"query", "q", "keyword", "keywords"} {
+		if has(key) {
+			queryKey = key
+			break
+		}
+	}
+	args[queryKey] = req.Query
+
+	if req.MaxResults > 0 {
+		for _, key := range []string{"count", "max_results", "num", "top_k", "limit"} {
+			if has(key) {
+				args[key] = req.MaxResults
+				break
+			}
+		}
+	}
+
+	optional := map[string]string{
+		"language":   req.Language,
+		"region":     req.Region,
+		"time_range": req.TimeRange,
+	}
+	for key, value := range optional {
+		if value != "" && has(key) {
+			args[key] = value
+		}
+	}
+	for key, value := range req.ExtraParams {
+		if has(key) {
+			args[key] = value
+		}
+	}
+	return args
+}
+
+// parseResults 解析工具返回的JSON文本，兼容pages/results/items等常见结构
+func parseResults(text string) ([]models.WebSearchResult, int, bool) {
+	var data interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(text)), &data); err != nil {
+		return nil, 0, false
+	}
+
+	var items []interface{}
+	total := 0
+	switch v := data.(type) {
+	case []interface{}:
+		items = v
+	case map[string]interface{}:
+		for _, key := range []string{"pages", "results", "items", "data", "webPages"} {
+			if list, ok := v[key].([]interface{}); ok {
+				items = list
+				break
+			}
+			if nested, ok := v[key].(map[string]interface{}); ok {
+				if list, ok := nested["value"].([]interface{}); ok {
+					items = list
+					break
+				}
+			}
+		}
+		for _, key := range []string{"total_count", "total", "totalEstimatedMatches"} {
+			if count, ok := v[key].(float64); ok {
+				total = int(count)
+				break
+			}
+		}
+	}
+	if items == nil {
+		return nil, 0, false
+	}
+
+	results := make([]models.WebSearchResult, 0, len(items))
+	for _, item := range items {
+		itemMap, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		results = append(results, models.WebSearchResult{
+			Title:       firstString(itemMap, "title", "name"),
+			URL:         firstString(itemMap, "url", "link", "href"),
+			Snippet:     firstString(itemMap, "snippet", "summary", "content", "description"),
+			Source:      firstString(itemMap, "source", "hostname", "site_name", "siteName"),
+			PublishedAt: firstString(itemMap, "published_at", "publish_time", "date", "time", "datePublished"),
+			Language:    firstString(itemMap, "language", "lang"),
+		})
+	}
+	return results, total, true
+}
+
+// firstString 按顺序获取第一个非空字符串字段
+func firstString(item map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := item[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}